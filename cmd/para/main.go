@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/user"
 	"path"
@@ -8,6 +9,9 @@ import (
 	"runtime/debug"
 
 	"github.com/lembata/para/internal/api"
+	"github.com/lembata/para/internal/classifier"
+	"github.com/lembata/para/internal/scheduler"
+	"github.com/lembata/para/pkg/attachments"
 	"github.com/lembata/para/pkg/database"
 	"github.com/lembata/para/pkg/logger"
 )
@@ -43,7 +47,32 @@ func main() {
 		}
 	}(db)
 
-	server, err := api.Init()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(os.Args) > 1 && os.Args[1] == "purge-accounts" {
+		if err := purgeAccounts(ctx, db); err != nil {
+			logger.Errorf("failed to purge accounts: %v", err)
+			exitCode = 1
+		}
+
+		return
+	}
+
+	transactionScheduler := scheduler.New(db)
+	transactionScheduler.Start(ctx)
+
+	categoryClassifier := classifier.New(db)
+
+	attachmentStore, err := attachments.NewStore(filepath.Join(configDir, "attachments"))
+
+	if err != nil {
+		logger.Errorf("failed to open attachment store: %v", err)
+		exitCode = 1
+		return
+	}
+
+	server, err := api.Init(transactionScheduler, categoryClassifier, attachmentStore)
 
 	if err != nil {
 		logger.Errorf("failed to init server: %v", err)
@@ -51,6 +80,10 @@ func main() {
 		return
 	}
 
+	if _, err := server.AttachmentService.RemoveOrphans(ctx); err != nil {
+		logger.Warnf("failed to clean up attachments: %v", err)
+	}
+
 	err = server.Start()
 
 	if err != nil {
@@ -81,6 +114,25 @@ func initDatabase(configDir string) (*database.Database, error) {
 	return dbInst, err
 }
 
+// purgeAccounts permanently removes the deleted accounts nothing refers to
+// any more.
+func purgeAccounts(ctx context.Context, db *database.Database) error {
+	var purged []int64
+
+	err := db.WithTxn(ctx, false, func(ctx context.Context) error {
+		var err error
+		purged, err = db.PurgeAccounts(ctx)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Purged %d deleted accounts: %v", len(purged), purged)
+	return nil
+}
+
 func recoverPanic() {
 	if err := recover(); err != nil {
 		exitCode = 1
@@ -3,17 +3,19 @@ module github.com/lembata/para
 go 1.21.3
 
 require (
+	github.com/Masterminds/squirrel v1.5.4
 	github.com/go-chi/chi/v5 v5.0.12
+	github.com/go-chi/cors v1.2.1
+	github.com/go-chi/httplog v0.3.2
+	github.com/golang-migrate/migrate/v4 v4.17.1
 	github.com/gorilla/sessions v1.2.2
 	github.com/jmoiron/sqlx v1.3.5
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/rs/zerolog v1.32.0
+	github.com/vearutop/statigz v1.4.0
 )
 
 require (
-	github.com/Masterminds/squirrel v1.5.4 // indirect
-	github.com/go-chi/cors v1.2.1 // indirect
-	github.com/go-chi/httplog v0.3.2 // indirect
-	github.com/golang-migrate/migrate/v4 v4.17.1 // indirect
 	github.com/gorilla/securecookie v1.1.2 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
@@ -22,8 +24,6 @@ require (
 	github.com/lib/pq v1.10.9 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	github.com/mattn/go-sqlite3 v1.14.22 // indirect
-	github.com/vearutop/statigz v1.4.0 // indirect
 	go.uber.org/atomic v1.7.0 // indirect
 	golang.org/x/sys v0.19.0 // indirect
 )
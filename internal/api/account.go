@@ -3,14 +3,19 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/internal/reports"
+	"github.com/lembata/para/internal/valuations"
 	"github.com/lembata/para/pkg/currency"
 	"github.com/lembata/para/pkg/database"
+	"github.com/lembata/para/pkg/recurrence"
 )
 
 type AccountService struct {
@@ -18,16 +23,54 @@ type AccountService struct {
 }
 
 type AccountData struct {
-	Id                 int     `json:"id"`
-	AccountName        string  `json:"accountName"`
-	Currency           string  `json:"currency"`
-	IBAN               string  `json:"iban"`
-	BIC                string  `json:"bic"`
-	AccountNumber      string  `json:"accountNumber"`
-	OpeningBalance     float64 `json:"openingBalance"`
-	OpeningBalanceDate string  `json:"openiningBalanceDate"`
-	Notes              string  `json:"notes"`
-	IncludeInNetWorth  bool    `json:"includeInNetWorth"`
+	Id                 int                  `json:"id"`
+	AccountName        string               `json:"accountName"`
+	Currency           string               `json:"currency"`
+	IBAN               string               `json:"iban"`
+	BIC                string               `json:"bic"`
+	AccountNumber      string               `json:"accountNumber"`
+	OpeningBalance     float64              `json:"openingBalance"`
+	OpeningBalanceDate string               `json:"openiningBalanceDate"`
+	Notes              string               `json:"notes"`
+	IncludeInNetWorth  bool                 `json:"includeInNetWorth"`
+	AccountType        entities.AccountType `json:"accountType"`
+	// StatementClosingDay and PaymentDueDay are only used by credit cards.
+	StatementClosingDay int `json:"statementClosingDay"`
+	PaymentDueDay       int `json:"paymentDueDay"`
+	// NextStatementDate and NextPaymentDate are derived for credit cards,
+	// they are ignored when saving.
+	NextStatementDate string `json:"nextStatementDate"`
+	NextPaymentDate   string `json:"nextPaymentDate"`
+	// Archived and Deleted are changed through their own endpoints, they
+	// are ignored when saving.
+	Archived bool `json:"archived"`
+	Deleted  bool `json:"deleted"`
+	// GroupId places a new account in a group, accounts are moved between
+	// groups by reordering them.
+	GroupId int `json:"groupId"`
+	// Version is the version the account was read at, edits of an older
+	// version are refused.
+	Version int `json:"version"`
+}
+
+type AccountGroupData struct {
+	Id      int    `json:"id"`
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+// ReorderRequest lists ids in their new order. When accounts are reordered
+// they are also moved into GroupId, 0 keeps them outside any group.
+type ReorderRequest struct {
+	GroupId int   `json:"groupId"`
+	Ids     []int `json:"ids"`
+}
+
+// accountColumns are the columns the account list can be sorted by, it is
+// in the user's order otherwise.
+var accountColumns = map[string]string{
+	"name": "a.name",
+	"type": "a.account_type",
 }
 
 type AccountShort struct {
@@ -37,6 +80,48 @@ type AccountShort struct {
 	LastActivity   string  `json:"lastActivity"`
 }
 
+func (d *AccountData) toEntity() (entities.AccountEntity, string) {
+	if d.AccountName == "" {
+		return entities.AccountEntity{}, "account name is required"
+	}
+
+	if len(d.Currency) != 3 {
+		return entities.AccountEntity{}, "currency is invalid"
+	}
+
+	if d.AccountType < entities.AccountChecking || d.AccountType > entities.AccountLiability {
+		return entities.AccountEntity{}, "account type is invalid"
+	}
+
+	if d.AccountType != entities.AccountCreditCard && (d.StatementClosingDay != 0 || d.PaymentDueDay != 0) {
+		return entities.AccountEntity{}, "only credit cards have statement closing and payment due days"
+	}
+
+	for _, day := range []int{d.StatementClosingDay, d.PaymentDueDay} {
+		if day < 0 || day > 31 {
+			return entities.AccountEntity{}, "day of month is invalid"
+		}
+	}
+
+	return entities.AccountEntity{
+		Id:                  int64(d.Id),
+		Name:                d.AccountName,
+		Currency:            d.Currency,
+		OpeningBalance:      currency.ToCoins(d.OpeningBalance),
+		OpeningBalanceDate:  d.OpeningBalanceDate,
+		IBAN:                d.IBAN,
+		BIC:                 d.BIC,
+		AccountNumber:       d.AccountNumber,
+		Notes:               d.Notes,
+		IncludeInNetWorth:   d.IncludeInNetWorth,
+		Type:                d.AccountType,
+		StatementClosingDay: d.StatementClosingDay,
+		PaymentDueDay:       d.PaymentDueDay,
+		GroupId:             optionalId(d.GroupId),
+		Version:             d.Version,
+	}, ""
+}
+
 func (s *AccountService) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	var account AccountData
 	err := json.NewDecoder(r.Body).Decode(&account)
@@ -48,31 +133,16 @@ func (s *AccountService) CreateAccount(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debugf("Creating account: %v", account)
 
-	if account.AccountName == "" {
-		WriteFailure(w, "account name is required", http.StatusBadRequest)
-		return
-	}
+	newAccount, msg := account.toEntity()
 
-	if len(account.Currency) != 3 {
-		WriteFailure(w, "currency is invalid", http.StatusBadRequest)
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
 		return
 	}
 
-	newAccount := entities.AccountEntity{
-		//_ = entities.AccountEntity{
-		Id:                 0,
-		Name:               account.AccountName,
-		CreateAt:           time.Now(),
-		UpdateAt:           time.Now(),
-		Currency:           account.Currency,
-		OpeningBalance:     currency.ToCoins(account.OpeningBalance),
-		OpeningBalanceDate: account.OpeningBalanceDate,
-		IBAN:               account.IBAN,
-		BIC:                account.BIC,
-		AccountNumber:      account.AccountNumber,
-		Notes:              account.Notes,
-		IncludeInNetWorth:  account.IncludeInNetWorth,
-	}
+	newAccount.Id = 0
+	newAccount.CreateAt = time.Now()
+	newAccount.UpdateAt = time.Now()
 
 	db := database.GetInstance()
 	ctx, err := db.Begin(r.Context(), false)
@@ -111,52 +181,42 @@ func (s *AccountService) EditAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logger.Debugf("Creating account: %v", account)
+	logger.Debugf("Editing account: %v", account)
 
-	if account.AccountName == "" {
-		WriteFailure(w, "account name is required", http.StatusBadRequest)
-		return
-	}
+	editedAccount, msg := account.toEntity()
 
-	if len(account.Currency) != 3 {
-		WriteFailure(w, "currency is invalid", http.StatusBadRequest)
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
 		return
 	}
 
-	newAccount := entities.AccountEntity{
-		//_ = entities.AccountEntity{
-		Id:                 int64(account.Id),
-		Name:               account.AccountName,
-		UpdateAt:           time.Now(),
-		Currency:           account.Currency,
-		OpeningBalance:     currency.ToCoins(account.OpeningBalance),
-		OpeningBalanceDate: account.OpeningBalanceDate,
-		IBAN:               account.IBAN,
-		BIC:                account.BIC,
-		AccountNumber:      account.AccountNumber,
-		Notes:              account.Notes,
-		IncludeInNetWorth:  account.IncludeInNetWorth,
-	}
+	editedAccount.UpdateAt = time.Now()
 
 	db := database.GetInstance()
-	ctx, err := db.Begin(r.Context(), false)
+	var current AccountData
 
-	if err != nil {
-		logger.Errorf("failed to begin transaction: %v", err)
-		WriteFailure(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		_, err := db.EditAccount(ctx, editedAccount)
 
-	if _, err = db.EditAccount(ctx, newAccount); err != nil {
-		logger.Errorf("failed to edit account: %v", err)
-		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		if err == database.ErrorConflict {
+			var existing entities.AccountEntity
+
+			if existing, err = db.GetAccountById(ctx, editedAccount.Id); err == nil {
+				current = accountData(existing)
+				return database.ErrorConflict
+			}
+		}
+
+		return err
+	})
+
+	if err == database.ErrorConflict {
+		WriteConflict(w, current)
 		return
 	}
 
-	err = db.Commit(ctx)
-
 	if err != nil {
-		logger.Errorf("failed to create account: %v", err)
+		logger.Errorf("failed to edit account: %v", err)
 		WriteFailure(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -164,6 +224,8 @@ func (s *AccountService) EditAccount(w http.ResponseWriter, r *http.Request) {
 	_, _ = WriteSuccess(w)
 }
 
+// All returns a page of the accounts in use with the subtotals of their
+// groups.
 func (s *AccountService) All(w http.ResponseWriter, r *http.Request) {
 	var tableRequest TableRequest
 	err := json.NewDecoder(r.Body).Decode(&tableRequest)
@@ -174,32 +236,56 @@ func (s *AccountService) All(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db := database.GetInstance()
-
-	//ctx, err := db.Begin(r.Context(), false)
-	var ctx context.Context
-
-	if ctx, err = db.Begin(r.Context(), false); err != nil {
+	if err = tableRequest.Validate(); err != nil {
 		WriteFailure(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	var accounts []entities.AccountRow
+	tableRequest, msg := withView(r, tableRequest, accountsList)
 
-	if accounts, err = db.GetAccounts(ctx, 0, 10, "id"); err != nil {
-		logger.Errorf("failed to get account: %v", err)
-		WriteFailure(w, err.Error(), http.StatusBadRequest)
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
 		return
 	}
 
-	if err = db.Commit(ctx); err != nil {
+	db := database.GetInstance()
+	list := entities.AccountList{Groups: []entities.AccountGroupTotal{}}
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		var err error
+		list.Accounts, err = db.GetAccounts(ctx, uint64(tableRequest.Offset), uint64(tableRequest.Limit),
+			tableRequest.OrderClause(accountColumns, "a.order_index, a.id"))
+
+		if err != nil {
+			return err
+		}
+
+		groups, err := activeGroups(ctx, db)
+
+		if err != nil {
+			return err
+		}
+
+		for _, group := range groups {
+			list.Groups = append(list.Groups, entities.AccountGroupTotal{
+				Id:        group.Id,
+				Name:      group.Name,
+				Subtotals: group.Subtotals,
+			})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get accounts: %v", err)
 		WriteFailure(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	logger.Debugf("Got accounts: %v", accounts)
+	logger.Debugf("Got accounts: %v", list.Accounts)
 
-	_, _ = WriteData(w, accounts)
+	_, _ = WriteData(w, list)
 }
 
 func (s *AccountService) GetAccount(w http.ResponseWriter, r *http.Request) {
@@ -235,18 +321,382 @@ func (s *AccountService) GetAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accountData := AccountData{
-		Id:                 int(account.Id),
-		AccountName:        account.Name,
-		Currency:           account.Currency,
-		IBAN:               account.IBAN,
-		BIC:                account.BIC,
-		AccountNumber:      account.AccountNumber,
-		OpeningBalance:     currency.FromCoins(account.OpeningBalance),
-		OpeningBalanceDate: account.OpeningBalanceDate,
-		Notes:              account.Notes,
-		IncludeInNetWorth:  account.IncludeInNetWorth,
+	_, _ = WriteData(w, accountData(account))
+}
+
+// DeleteAccount moves an account to the trash, it can be restored until it
+// is purged.
+func (s *AccountService) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	s.changeAccount(w, r, "delete", func(ctx context.Context, db *database.Database, id int64) error {
+		return db.DeleteAccount(ctx, id)
+	})
+}
+
+// ArchiveAccount hides an account that is no longer used, it stays in
+// reports.
+func (s *AccountService) ArchiveAccount(w http.ResponseWriter, r *http.Request) {
+	s.changeAccount(w, r, "archive", func(ctx context.Context, db *database.Database, id int64) error {
+		return db.ArchiveAccount(ctx, id)
+	})
+}
+
+func (s *AccountService) RestoreAccount(w http.ResponseWriter, r *http.Request) {
+	s.changeAccount(w, r, "restore", func(ctx context.Context, db *database.Database, id int64) error {
+		return db.RestoreAccount(ctx, id)
+	})
+}
+
+// PurgeAccount permanently removes a deleted account, accounts that
+// transactions or other history refer to are refused.
+func (s *AccountService) PurgeAccount(w http.ResponseWriter, r *http.Request) {
+	s.changeAccount(w, r, "purge", func(ctx context.Context, db *database.Database, id int64) error {
+		return db.PurgeAccount(ctx, id)
+	})
+}
+
+// Archived returns the archived and deleted accounts.
+func (s *AccountService) Archived(w http.ResponseWriter, r *http.Request) {
+	db := database.GetInstance()
+	accounts := []entities.AccountRow{}
+
+	err := db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		archived, err := db.GetArchivedAccounts(ctx)
+		accounts = append(accounts, archived...)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get archived accounts: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, accounts)
+}
+
+// Grouped returns the accounts in use by group with the subtotals of each
+// group, for the sidebar and the dashboard.
+func (s *AccountService) Grouped(w http.ResponseWriter, r *http.Request) {
+	db := database.GetInstance()
+	var groups []entities.AccountGroupRow
+
+	err := db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		var err error
+		groups, err = activeGroups(ctx, db)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get account groups: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, groups)
+}
+
+// activeGroups lists the accounts in use by group with their subtotals.
+func activeGroups(ctx context.Context, db *database.Database) ([]entities.AccountGroupRow, error) {
+	groups, err := db.GetAccountGroups(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := currentBalances(ctx, db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var active []entities.AccountRow
+
+	for _, account := range accounts {
+		if !account.Archived {
+			active = append(active, account)
+		}
+	}
+
+	return reports.GroupAccounts(groups, active), nil
+}
+
+// Reorder stores the order of the accounts of a group after one was dragged,
+// the dragged account joins the group.
+func (s *AccountService) Reorder(w http.ResponseWriter, r *http.Request) {
+	var request ReorderRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.MoveAccounts(ctx, optionalId(request.GroupId), toIds(request.Ids))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to reorder accounts: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *AccountService) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var data AccountGroupData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if data.Name == "" {
+		WriteFailure(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	group := entities.AccountGroupEntity{
+		Name:     data.Name,
+		CreateAt: time.Now(),
+		UpdateAt: time.Now(),
+	}
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		if err := checkGroupName(ctx, db, group); err != nil {
+			return err
+		}
+
+		id, err = db.CreateAccountGroup(ctx, group)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to create account group: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+func (s *AccountService) EditGroup(w http.ResponseWriter, r *http.Request) {
+	var data AccountGroupData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if data.Name == "" {
+		WriteFailure(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	group := entities.AccountGroupEntity{
+		Id:       int64(data.Id),
+		Name:     data.Name,
+		Version:  data.Version,
+		UpdateAt: time.Now(),
+	}
+
+	db := database.GetInstance()
+	var current entities.AccountGroupEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		if err := checkGroupName(ctx, db, group); err != nil {
+			return err
+		}
+
+		_, err := db.EditAccountGroup(ctx, group)
+
+		if err == database.ErrorConflict {
+			current, err = db.GetAccountGroupById(ctx, group.Id)
+
+			if err == nil {
+				return database.ErrorConflict
+			}
+		}
+
+		return err
+	})
+
+	if err == database.ErrorConflict {
+		WriteConflict(w, current)
+		return
+	}
+
+	if err != nil {
+		logger.Errorf("failed to edit account group: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// DeleteGroup deletes a group, its accounts are kept outside any group.
+func (s *AccountService) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid account group id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.DeleteAccountGroup(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete account group: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *AccountService) ReorderGroups(w http.ResponseWriter, r *http.Request) {
+	var request ReorderRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.ReorderAccountGroups(ctx, toIds(request.Ids))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to reorder account groups: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// checkGroupName refuses a name another group already has, names are case
+// insensitive.
+func checkGroupName(ctx context.Context, db *database.Database, group entities.AccountGroupEntity) error {
+	groups, err := db.GetAccountGroups(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	for _, other := range groups {
+		if other.Id != group.Id && strings.EqualFold(other.Name, group.Name) {
+			return fmt.Errorf("account group %q already exists", other.Name)
+		}
+	}
+
+	return nil
+}
+
+// currentBalances returns the balance of the accounts in use today, asset
+// accounts at their latest valuation.
+func currentBalances(ctx context.Context, db *database.Database) ([]entities.AccountRow, error) {
+	today := time.Now().Format(recurrence.DateLayout)
+	accounts, err := db.GetAccountBalances(ctx, today)
+
+	if err != nil {
+		return nil, err
+	}
+
+	valuationList, err := db.GetValuations(ctx, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return valuations.Apply(accounts, valuationList, today), nil
+}
+
+func (s *AccountService) changeAccount(w http.ResponseWriter, r *http.Request, action string,
+	change func(ctx context.Context, db *database.Database, id int64) error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid account id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return change(ctx, db, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to %s account: %v", action, err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// accountData returns an account as the client reads and edits it.
+func accountData(account entities.AccountEntity) AccountData {
+	data := AccountData{
+		Id:                  int(account.Id),
+		AccountName:         account.Name,
+		Currency:            account.Currency,
+		IBAN:                account.IBAN,
+		BIC:                 account.BIC,
+		AccountNumber:       account.AccountNumber,
+		OpeningBalance:      currency.FromCoins(account.OpeningBalance),
+		OpeningBalanceDate:  account.OpeningBalanceDate,
+		Notes:               account.Notes,
+		IncludeInNetWorth:   account.IncludeInNetWorth,
+		AccountType:         account.Type,
+		StatementClosingDay: account.StatementClosingDay,
+		PaymentDueDay:       account.PaymentDueDay,
+		Archived:            account.Archived,
+		Deleted:             account.Deleted,
+		GroupId:             idValue(account.GroupId),
+		Version:             account.Version,
+	}
+
+	if account.Type == entities.AccountCreditCard {
+		today := time.Now()
+		data.NextStatementDate = nextDayOfMonth(today, account.StatementClosingDay)
+		data.NextPaymentDate = nextDayOfMonth(today, account.PaymentDueDay)
+	}
+
+	return data
+}
+
+// nextDayOfMonth returns the next date from today falling on the day of the
+// month, or the last day of shorter months. It is empty when day is not set.
+func nextDayOfMonth(today time.Time, day int) string {
+	if day == 0 {
+		return ""
+	}
+
+	rule := recurrence.Rule{
+		Frequency:  recurrence.Monthly,
+		Interval:   1,
+		DayOfMonth: day,
+		Start:      today,
 	}
 
-	_, _ = WriteData(w, accountData)
+	date, _ := rule.Occurrence(0)
+	return date.Format(recurrence.DateLayout)
 }
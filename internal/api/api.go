@@ -5,11 +5,15 @@ import (
 	"encoding/json"
 	"io/fs"
 	"net/http"
+	"os"
 	"path"
 	"time"
 
+	"github.com/lembata/para/internal/classifier"
+	"github.com/lembata/para/internal/scheduler"
+	"github.com/lembata/para/pkg/attachments"
+	"github.com/lembata/para/pkg/database"
 	log "github.com/lembata/para/pkg/logger"
-	//"github.com/lembata/para/pkg/database"
 	"github.com/lembata/para/ui"
 
 	"github.com/go-chi/chi/v5"
@@ -26,6 +30,26 @@ type Server struct {
 	DashboardService
 	LoginService
 	AccountService
+	BudgetService
+	EnvelopeService
+	ScheduledService
+	ReportService
+	TransactionService
+	PayeeService
+	RuleService
+	TagService
+	AttachmentService
+	ReconciliationService
+	DuplicateService
+	LoanService
+	SecurityService
+	InvestmentService
+	ValuationService
+	GoalService
+	AuditService
+	HistoryService
+	SearchService
+	ViewService
 }
 
 type ApiResponse struct {
@@ -35,19 +59,27 @@ type ApiResponse struct {
 	ErrorCode int    `json:"errorCode"`
 }
 
-func Init() (*Server, error) {
+func Init(transactionScheduler *scheduler.Scheduler, categoryClassifier *classifier.Classifier, attachmentStore *attachments.Store) (*Server, error) {
 	logger.Debug("Initializing API...")
 
 	address := "localhost:8080"
 	router := chi.NewRouter()
+	trustedProxies, err := parseTrustedProxies(os.Getenv(trustedProxiesEnv))
+
+	if err != nil {
+		return nil, err
+	}
 
 	server := Server{
 		Server: http.Server{
 			Addr:    address,
 			Handler: router,
 		},
-		DashboardService: DashboardService{},
-		LoginService:     LoginService{},
+		DashboardService:   DashboardService{},
+		LoginService:       LoginService{},
+		ScheduledService:   ScheduledService{scheduler: transactionScheduler},
+		TransactionService: TransactionService{classifier: categoryClassifier},
+		AttachmentService:  AttachmentService{store: attachmentStore},
 	}
 
 	router.Use(cors.Handler(cors.Options{
@@ -61,10 +93,11 @@ func Init() (*Server, error) {
 
 	router.Use(middleware.Heartbeat("/healthz"))
 	router.Use(middleware.RequestID)
-	router.Use(middleware.RealIP)
+	router.Use(proxyHandler(trustedProxies))
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
 	router.Use(authenticateHandler())
+	router.Use(auditHandler())
 
 	httpLogger := log.NewHttpLogger()
 
@@ -73,6 +106,26 @@ func Init() (*Server, error) {
 	router.Mount("/api/dashboard", server.dashBoardRouter())
 	router.Mount("/api/login", server.loginRouter())
 	router.Mount("/api/accounts", server.accountRouter())
+	router.Mount("/api/budgets", server.budgetRouter())
+	router.Mount("/api/envelopes", server.envelopeRouter())
+	router.Mount("/api/scheduled", server.scheduledRouter())
+	router.Mount("/api/reports", server.reportRouter())
+	router.Mount("/api/transactions", server.transactionRouter())
+	router.Mount("/api/payees", server.payeeRouter())
+	router.Mount("/api/rules", server.ruleRouter())
+	router.Mount("/api/tags", server.tagRouter())
+	router.Mount("/api/attachments", server.attachmentRouter())
+	router.Mount("/api/reconciliations", server.reconciliationRouter())
+	router.Mount("/api/duplicates", server.duplicateRouter())
+	router.Mount("/api/loans", server.loanRouter())
+	router.Mount("/api/securities", server.securityRouter())
+	router.Mount("/api/investments", server.investmentRouter())
+	router.Mount("/api/valuations", server.valuationRouter())
+	router.Mount("/api/goals", server.goalRouter())
+	router.Mount("/api/audit", server.auditRouter())
+	router.Mount("/api/history", server.historyRouter())
+	router.Mount("/api/search", server.searchRouter())
+	router.Mount("/api/views", server.viewRouter())
 
 	staticUI := statigz.FileServer(ui.UIBox.(fs.ReadDirFS))
 
@@ -133,6 +186,220 @@ func (s *Server) accountRouter() http.Handler {
 	r.Post("/add", s.AccountService.CreateAccount)
 	r.Post("/all", s.AccountService.All)
 	r.Post("/edit", s.AccountService.EditAccount)
+	r.Post("/archived", s.AccountService.Archived)
+	r.Post("/delete/{id}", s.AccountService.DeleteAccount)
+	r.Post("/archive/{id}", s.AccountService.ArchiveAccount)
+	r.Post("/restore/{id}", s.AccountService.RestoreAccount)
+	r.Post("/purge/{id}", s.AccountService.PurgeAccount)
+	r.Post("/grouped", s.AccountService.Grouped)
+	r.Post("/reorder", s.AccountService.Reorder)
+	r.Post("/groups/add", s.AccountService.CreateGroup)
+	r.Post("/groups/edit", s.AccountService.EditGroup)
+	r.Post("/groups/delete/{id}", s.AccountService.DeleteGroup)
+	r.Post("/groups/reorder", s.AccountService.ReorderGroups)
+	return r
+}
+
+func (s *Server) budgetRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/add", s.BudgetService.CreateBudget)
+	r.Post("/all", s.BudgetService.All)
+	r.Post("/copy", s.BudgetService.Copy)
+	r.Post("/edit", s.BudgetService.EditBudget)
+	r.Post("/delete/{id}", s.BudgetService.DeleteBudget)
+	return r
+}
+
+func (s *Server) envelopeRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/all", s.EnvelopeService.All)
+	r.Post("/move", s.EnvelopeService.Move)
+	return r
+}
+
+func (s *Server) scheduledRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/upcoming", s.ScheduledService.Upcoming)
+	r.Post("/add", s.ScheduledService.CreateScheduled)
+	r.Post("/all", s.ScheduledService.All)
+	r.Post("/edit", s.ScheduledService.EditScheduled)
+	r.Post("/delete/{id}", s.ScheduledService.DeleteScheduled)
+	r.Post("/pending", s.ScheduledService.Pending)
+	r.Post("/confirm/{id}", s.ScheduledService.Confirm)
+	r.Post("/reject/{id}", s.ScheduledService.Reject)
+	return r
+}
+
+func (s *Server) reportRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/forecast", s.ReportService.Forecast)
+	r.Post("/tags", s.ReportService.TagTotals)
+	r.Post("/networth", s.ReportService.NetWorth)
+	r.Post("/networth/series", s.ReportService.NetWorthSeries)
+	return r
+}
+
+func (s *Server) transactionRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/{id}", s.TransactionService.GetTransaction)
+	r.Post("/add", s.TransactionService.CreateTransaction)
+	r.Post("/import", s.TransactionService.Import)
+	r.Post("/all", s.TransactionService.All)
+	r.Post("/suggest", s.TransactionService.SuggestCategory)
+	r.Post("/status", s.TransactionService.SetStatus)
+	r.Post("/unlock/{id}", s.TransactionService.Unlock)
+	r.Post("/edit", s.TransactionService.EditTransaction)
+	r.Post("/delete/{id}", s.TransactionService.DeleteTransaction)
+	r.Post("/bulk/categorize", s.TransactionService.BulkCategorize)
+	r.Post("/bulk/tag", s.TransactionService.BulkTag)
+	r.Post("/bulk/move", s.TransactionService.BulkMove)
+	r.Post("/bulk/delete", s.TransactionService.BulkDelete)
+	return r
+}
+
+func (s *Server) payeeRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/autocomplete", s.PayeeService.Autocomplete)
+	r.Get("/{id}", s.PayeeService.GetPayee)
+	r.Post("/add", s.PayeeService.CreatePayee)
+	r.Post("/all", s.PayeeService.All)
+	r.Post("/edit", s.PayeeService.EditPayee)
+	r.Post("/merge", s.PayeeService.Merge)
+	r.Post("/delete/{id}", s.PayeeService.DeletePayee)
+	return r
+}
+
+func (s *Server) ruleRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/{id}", s.RuleService.GetRule)
+	r.Post("/add", s.RuleService.CreateRule)
+	r.Post("/all", s.RuleService.All)
+	r.Post("/edit", s.RuleService.EditRule)
+	r.Post("/delete/{id}", s.RuleService.DeleteRule)
+	r.Post("/dryrun", s.RuleService.DryRun)
+	r.Post("/apply", s.RuleService.Apply)
+	return r
+}
+
+func (s *Server) tagRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/add", s.TagService.CreateTag)
+	r.Post("/all", s.TagService.All)
+	r.Post("/edit", s.TagService.EditTag)
+	r.Post("/delete/{id}", s.TagService.DeleteTag)
+	return r
+}
+
+func (s *Server) attachmentRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/{id}", s.AttachmentService.Download)
+	r.Post("/upload", s.AttachmentService.Upload)
+	r.Post("/all", s.AttachmentService.All)
+	r.Post("/delete/{id}", s.AttachmentService.DeleteAttachment)
+	r.Post("/cleanup", s.AttachmentService.Cleanup)
+	return r
+}
+
+func (s *Server) reconciliationRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/{id}", s.ReconciliationService.GetReconciliation)
+	r.Post("/start", s.ReconciliationService.Start)
+	r.Post("/all", s.ReconciliationService.All)
+	r.Post("/finish/{id}", s.ReconciliationService.Finish)
+	r.Post("/delete/{id}", s.ReconciliationService.Cancel)
+	return r
+}
+
+func (s *Server) duplicateRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/all", s.DuplicateService.All)
+	r.Post("/scan", s.DuplicateService.Scan)
+	r.Post("/merge/{id}", s.DuplicateService.Merge)
+	r.Post("/dismiss/{id}", s.DuplicateService.Dismiss)
+	return r
+}
+
+func (s *Server) loanRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/{id}", s.LoanService.GetLoan)
+	r.Post("/add", s.LoanService.CreateLoan)
+	r.Post("/all", s.LoanService.All)
+	r.Post("/edit", s.LoanService.EditLoan)
+	r.Post("/delete/{id}", s.LoanService.DeleteLoan)
+	r.Post("/schedule/{id}", s.LoanService.Schedule)
+	r.Post("/projection/{id}", s.LoanService.Projection)
+	return r
+}
+
+func (s *Server) securityRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/{id}", s.SecurityService.GetSecurity)
+	r.Post("/add", s.SecurityService.CreateSecurity)
+	r.Post("/all", s.SecurityService.All)
+	r.Post("/edit", s.SecurityService.EditSecurity)
+	r.Post("/delete/{id}", s.SecurityService.DeleteSecurity)
+	r.Post("/prices/import", s.SecurityService.ImportPrices)
+	r.Post("/prices/{id}", s.SecurityService.Prices)
+	return r
+}
+
+func (s *Server) investmentRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/trades/add", s.InvestmentService.CreateTrade)
+	r.Post("/trades/all", s.InvestmentService.Trades)
+	r.Post("/trades/delete/{id}", s.InvestmentService.DeleteTrade)
+	r.Post("/holdings", s.InvestmentService.Holdings)
+	return r
+}
+
+func (s *Server) valuationRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/add", s.ValuationService.CreateValuation)
+	r.Post("/all", s.ValuationService.All)
+	r.Post("/edit", s.ValuationService.EditValuation)
+	r.Post("/delete/{id}", s.ValuationService.DeleteValuation)
+	r.Post("/depreciation", s.ValuationService.Depreciation)
+	return r
+}
+
+func (s *Server) goalRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/{id}", s.GoalService.GetGoal)
+	r.Post("/add", s.GoalService.CreateGoal)
+	r.Post("/all", s.GoalService.All)
+	r.Post("/edit", s.GoalService.EditGoal)
+	r.Post("/delete/{id}", s.GoalService.DeleteGoal)
+	return r
+}
+
+func (s *Server) auditRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/all", s.AuditService.All)
+	return r
+}
+
+func (s *Server) historyRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/all", s.HistoryService.All)
+	r.Post("/undo", s.HistoryService.Undo)
+	r.Post("/undo/{id}", s.HistoryService.UndoOperation)
+	r.Post("/redo", s.HistoryService.Redo)
+	return r
+}
+
+func (s *Server) searchRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/", s.SearchService.Search)
+	return r
+}
+
+func (s *Server) viewRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/{id}", s.ViewService.GetView)
+	r.Post("/add", s.ViewService.CreateView)
+	r.Post("/all", s.ViewService.All)
+	r.Post("/edit", s.ViewService.EditView)
+	r.Post("/delete/{id}", s.ViewService.DeleteView)
 	return r
 }
 
@@ -153,11 +420,55 @@ func (s *Server) addStaticResponses() http.Handler {
 	return nil
 }
 
+// optionalId maps the zero id sent by the UI for "none" to a nil reference.
+func optionalId(id int) *int64 {
+	if id == 0 {
+		return nil
+	}
+
+	value := int64(id)
+	return &value
+}
+
+// toIds converts the ids of a request to database ids.
+func toIds(values []int) []int64 {
+	ids := make([]int64, 0, len(values))
+
+	for _, value := range values {
+		ids = append(ids, int64(value))
+	}
+
+	return ids
+}
+
+// idValue maps a nil reference to the zero id the UI uses for "none".
+func idValue(id *int64) int {
+	if id == nil {
+		return 0
+	}
+
+	return int(*id)
+}
+
 func WriteFailure(w http.ResponseWriter, error string, errorCode int) {
-	w.WriteHeader(http.StatusBadRequest)
+	w.WriteHeader(errorCode)
 	_, _ = w.Write(Failure(error, errorCode))
 }
 
+// WriteConflict answers an edit of a record that was changed in the meantime
+// with its current state, so the client can show it and retry.
+func WriteConflict(w http.ResponseWriter, current any) {
+	conflictJson, _ := json.Marshal(ApiResponse{
+		Success:   false,
+		Data:      current,
+		Error:     database.ErrorConflict.Error(),
+		ErrorCode: http.StatusConflict,
+	})
+
+	w.WriteHeader(http.StatusConflict)
+	_, _ = w.Write(conflictJson)
+}
+
 func WriteSuccess(w http.ResponseWriter) (int, error) {
 	return w.Write(Success())
 }
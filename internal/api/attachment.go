@@ -0,0 +1,279 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/attachments"
+	"github.com/lembata/para/pkg/database"
+)
+
+const (
+	maxAttachmentSize = 20 << 20
+	// uploads up to this size are kept in memory while parsing the form
+	maxAttachmentMemory = 1 << 20
+	maxAttachmentName   = 255
+)
+
+// allowedMimeTypes are the sniffed content types accepted for upload.
+var allowedMimeTypes = map[string]bool{
+	"application/pdf": true,
+	"image/gif":       true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+}
+
+type AttachmentService struct {
+	store *attachments.Store
+}
+
+type AttachmentListRequest struct {
+	TransactionId int `json:"transactionId"`
+	AccountId     int `json:"accountId"`
+}
+
+// Upload stores the file of a multipart form and attaches it to the
+// transaction or account given in the transactionId or accountId field.
+func (s *AttachmentService) Upload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+maxAttachmentMemory)
+
+	if err := r.ParseMultipartForm(maxAttachmentMemory); err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+	transactionId, _ := strconv.Atoi(r.FormValue("transactionId"))
+	accountId, _ := strconv.Atoi(r.FormValue("accountId"))
+
+	if (transactionId == 0) == (accountId == 0) {
+		WriteFailure(w, "either a transaction or an account is required", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	defer func() { _ = file.Close() }()
+
+	if header.Size > maxAttachmentSize {
+		WriteFailure(w, "file is too large", http.StatusBadRequest)
+		return
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+
+	if err != nil && err != io.ErrUnexpectedEOF {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	head = head[:n]
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+
+	if !allowedMimeTypes[mimeType] {
+		WriteFailure(w, "file type "+mimeType+" is not allowed", http.StatusBadRequest)
+		return
+	}
+
+	attachment := entities.AttachmentEntity{
+		Name:          attachmentName(header.Filename),
+		MimeType:      mimeType,
+		TransactionId: optionalId(transactionId),
+		AccountId:     optionalId(accountId),
+		CreateAt:      time.Now(),
+	}
+
+	logger.Debugf("Uploading attachment: %v", attachment)
+
+	db := database.GetInstance()
+	var id int64
+
+	// exclusive so that a cleanup can not remove the file before it is
+	// referenced
+	err = db.WithTxn(r.Context(), true, func(ctx context.Context) error {
+		if attachment.Hash, attachment.Size, err = s.store.Put(io.MultiReader(bytes.NewReader(head), file)); err != nil {
+			return err
+		}
+
+		id, err = db.CreateAttachment(ctx, attachment)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to upload attachment: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+func (s *AttachmentService) Download(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid attachment id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var attachment entities.AttachmentEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		attachment, err = db.GetAttachmentById(ctx, int64(id))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get attachment: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, err := s.store.Open(attachment.Hash)
+
+	if err != nil {
+		logger.Errorf("failed to open attachment: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	defer func() { _ = file.Close() }()
+
+	w.Header().Set("Content-Type", attachment.MimeType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	http.ServeContent(w, r, "", attachment.CreateAt, file)
+}
+
+// All lists the attachments of a transaction or an account.
+func (s *AttachmentService) All(w http.ResponseWriter, r *http.Request) {
+	var request AttachmentListRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.TransactionId == 0 && request.AccountId == 0 {
+		WriteFailure(w, "a transaction or an account is required", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var attachmentList []entities.AttachmentEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		attachmentList, err = db.GetAttachments(ctx, int64(request.TransactionId), int64(request.AccountId))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get attachments: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, attachmentList)
+}
+
+// DeleteAttachment removes an attachment and its file unless the content is
+// still used by another attachment or by an operation that can be undone.
+func (s *AttachmentService) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid attachment id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), true, func(ctx context.Context) error {
+		attachment, used, err := db.DeleteAttachment(ctx, int64(id))
+
+		if err != nil || used {
+			return err
+		}
+
+		return s.store.Remove(attachment.Hash)
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete attachment: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// Cleanup removes the stored files no attachment refers to anymore and
+// returns how many were removed.
+func (s *AttachmentService) Cleanup(w http.ResponseWriter, r *http.Request) {
+	removed, err := s.RemoveOrphans(r.Context())
+
+	if err != nil {
+		logger.Errorf("failed to clean up attachments: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, removed)
+}
+
+// RemoveOrphans removes the stored files of deleted attachments, deleting a
+// transaction leaves its files behind until then.
+func (s *AttachmentService) RemoveOrphans(ctx context.Context) (int, error) {
+	db := database.GetInstance()
+	removed := 0
+
+	err := db.WithTxn(ctx, true, func(ctx context.Context) error {
+		hashes, err := db.GetAttachmentHashes(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		removed, err = s.store.Cleanup(hashes)
+		return err
+	})
+
+	if removed > 0 {
+		logger.Infof("Removed %d orphaned attachment files", removed)
+	}
+
+	return removed, err
+}
+
+func attachmentName(filename string) string {
+	// browsers on Windows may send the full path
+	name := strings.TrimSpace(filename[strings.LastIndexAny(filename, `/\`)+1:])
+
+	if len(name) > maxAttachmentName {
+		name = strings.ToValidUTF8(name[:maxAttachmentName], "")
+	}
+
+	if name == "" || name == "." || name == ".." {
+		return "attachment"
+	}
+
+	return name
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/database"
+)
+
+// remoteUserHeader names the user signed in at a trusted reverse proxy in
+// front of para, changes are logged under it when it is set.
+const remoteUserHeader = "X-Remote-User"
+
+type AuditService struct {
+}
+
+// auditHandler tags the changes made by a request with who made them and
+// the id middleware.RequestID gave the request, and adds the request to the
+// history of its actor.
+func auditHandler() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := database.WithAuditSource(r.Context(), requestActor(r), middleware.GetReqID(r.Context()),
+				r.Method+" "+r.URL.Path)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// requestActor is who made a request. Without a user signed in at a trusted
+// proxy the client address is the actor.
+func requestActor(r *http.Request) string {
+	var actor string
+
+	if isProxied(r) {
+		actor = r.Header.Get(remoteUserHeader)
+	}
+
+	if actor == "" {
+		actor = r.RemoteAddr
+
+		if host, _, err := net.SplitHostPort(actor); err == nil {
+			actor = host
+		}
+	}
+
+	return actor
+}
+
+// All returns the audit log, the latest change first. It can be filtered by
+// entity, entityId, actor, requestId and the from and to dates.
+func (s *AuditService) All(w http.ResponseWriter, r *http.Request) {
+	var request TableRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := entities.AuditFilter{
+		Entity:    request.Filters["entity"],
+		Actor:     request.Filters["actor"],
+		RequestId: request.Filters["requestId"],
+		FromDate:  request.Filters["from"],
+		ToDate:    request.Filters["to"],
+	}
+
+	if value, ok := request.Filters["entityId"]; ok {
+		if filter.EntityId, err = strconv.ParseInt(value, 10, 64); err != nil {
+			WriteFailure(w, "invalid entity id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	db := database.GetInstance()
+	entries := []entities.AuditEntry{}
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		log, err := db.GetAuditLog(ctx, filter, uint64(request.Offset), uint64(request.Limit))
+		entries = append(entries, log...)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get audit log: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, entries)
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/currency"
+	"github.com/lembata/para/pkg/database"
+)
+
+const monthLayout = "2006-01"
+
+type BudgetService struct {
+}
+
+type BudgetData struct {
+	Id         int     `json:"id"`
+	CategoryId int     `json:"categoryId"`
+	Month      string  `json:"month"`
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency"`
+	Rollover   bool    `json:"rollover"`
+	Version    int     `json:"version"`
+}
+
+type BudgetMonthRequest struct {
+	Month string `json:"month"`
+}
+
+func (b *BudgetData) Validate() string {
+	if b.CategoryId == 0 {
+		return "category is required"
+	}
+
+	if _, err := time.Parse(monthLayout, b.Month); err != nil {
+		return "month is invalid"
+	}
+
+	if len(b.Currency) != 3 {
+		return "currency is invalid"
+	}
+
+	return ""
+}
+
+func (s *BudgetService) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	var budget BudgetData
+	err := json.NewDecoder(r.Body).Decode(&budget)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Creating budget: %v", budget)
+
+	if msg := budget.Validate(); msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	newBudget := entities.BudgetEntity{
+		CategoryId: int64(budget.CategoryId),
+		Month:      budget.Month,
+		Amount:     currency.ToCoins(budget.Amount),
+		Currency:   budget.Currency,
+		Rollover:   budget.Rollover,
+		CreateAt:   time.Now(),
+		UpdateAt:   time.Now(),
+	}
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		id, err = db.CreateBudget(ctx, newBudget)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to create budget: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+func (s *BudgetService) EditBudget(w http.ResponseWriter, r *http.Request) {
+	var budget BudgetData
+	err := json.NewDecoder(r.Body).Decode(&budget)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Editing budget: %v", budget)
+
+	if msg := budget.Validate(); msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	editedBudget := entities.BudgetEntity{
+		Id:       int64(budget.Id),
+		Amount:   currency.ToCoins(budget.Amount),
+		Currency: budget.Currency,
+		Rollover: budget.Rollover,
+		Version:  budget.Version,
+		UpdateAt: time.Now(),
+	}
+
+	db := database.GetInstance()
+	var current BudgetData
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		_, err := db.EditBudget(ctx, editedBudget)
+
+		if err == database.ErrorConflict {
+			var existing entities.BudgetEntity
+
+			if existing, err = db.GetBudgetById(ctx, editedBudget.Id); err == nil {
+				current = BudgetData{
+					Id:         int(existing.Id),
+					CategoryId: int(existing.CategoryId),
+					Month:      existing.Month,
+					Amount:     currency.FromCoins(existing.Amount),
+					Currency:   existing.Currency,
+					Rollover:   existing.Rollover,
+					Version:    existing.Version,
+				}
+				return database.ErrorConflict
+			}
+		}
+
+		return err
+	})
+
+	if err == database.ErrorConflict {
+		WriteConflict(w, current)
+		return
+	}
+
+	if err != nil {
+		logger.Errorf("failed to edit budget: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *BudgetService) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid budget id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.DeleteBudget(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete budget: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// All returns budgeted, actual and remaining amounts for every budgeted
+// category of the requested month.
+func (s *BudgetService) All(w http.ResponseWriter, r *http.Request) {
+	var request BudgetMonthRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err = time.Parse(monthLayout, request.Month); err != nil {
+		WriteFailure(w, "month is invalid", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var budgets []entities.BudgetRow
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		budgets, err = db.GetBudgets(ctx, request.Month)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get budgets: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, budgets)
+}
+
+// Copy copies the budgets of the previous month into the requested month.
+func (s *BudgetService) Copy(w http.ResponseWriter, r *http.Request) {
+	var request BudgetMonthRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err = time.Parse(monthLayout, request.Month); err != nil {
+		WriteFailure(w, "month is invalid", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var copied int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		copied, err = db.CopyBudgets(ctx, request.Month)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to copy budgets: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, copied)
+}
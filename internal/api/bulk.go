@@ -0,0 +1,316 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/internal/loans"
+	"github.com/lembata/para/pkg/database"
+)
+
+const maxBulkTransactions = 5000
+
+// errBulkDryRun rolls back a dry run once it is done.
+var errBulkDryRun = errors.New("dry run")
+
+// BulkRequest selects the transactions of a bulk operation either by Ids or by
+// the filters and query of the transaction list, not both. A dry run reports
+// what the operation would do and changes nothing.
+type BulkRequest struct {
+	Ids     []int             `json:"ids"`
+	Filters map[string]string `json:"filters"`
+	Query   string            `json:"query"`
+	DryRun  bool              `json:"dryRun"`
+}
+
+// BulkCategorizeRequest puts every item of the transactions in a category,
+// CategoryId 0 removes their category.
+type BulkCategorizeRequest struct {
+	BulkRequest
+	CategoryId int `json:"categoryId"`
+}
+
+type BulkTagRequest struct {
+	BulkRequest
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+}
+
+// BulkMoveRequest moves the side of the transactions that is on AccountId
+// to ToAccountId.
+type BulkMoveRequest struct {
+	BulkRequest
+	AccountId   int `json:"accountId"`
+	ToAccountId int `json:"toAccountId"`
+}
+
+// BulkResult counts the transactions that were changed, or would be in a dry
+// run. The transactions that could not be changed are left alone and listed
+// in Errors.
+type BulkResult struct {
+	Count  int         `json:"count"`
+	DryRun bool        `json:"dryRun"`
+	Errors []BulkError `json:"errors"`
+}
+
+type BulkError struct {
+	Id    int    `json:"id"`
+	Error string `json:"error"`
+}
+
+// bulkAction changes one transaction. It returns a message when the
+// transaction can not be changed and an error when the whole operation fails.
+type bulkAction func(ctx context.Context, db *database.Database, transaction entities.TransactionEntity) (string, error)
+
+func (s *TransactionService) BulkCategorize(w http.ResponseWriter, r *http.Request) {
+	var request BulkCategorizeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	categoryId := optionalId(request.CategoryId)
+
+	check := func(ctx context.Context, db *database.Database) error {
+		if categoryId == nil {
+			return nil
+		}
+
+		exists, err := db.CategoryExists(ctx, *categoryId)
+
+		if err == nil && !exists {
+			err = errors.New("the category does not exist")
+		}
+
+		return err
+	}
+
+	s.bulk(w, r, request.BulkRequest, "categorize", check,
+		func(ctx context.Context, db *database.Database, transaction entities.TransactionEntity) (string, error) {
+			return "", db.SetTransactionCategory(ctx, transaction.Id, categoryId)
+		})
+}
+
+func (s *TransactionService) BulkTag(w http.ResponseWriter, r *http.Request) {
+	var request BulkTagRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(request.Add) == 0 && len(request.Remove) == 0 {
+		WriteFailure(w, "no tags given", http.StatusBadRequest)
+		return
+	}
+
+	if msg := validateTags(request.Add); msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	s.bulk(w, r, request.BulkRequest, "tag", nil,
+		func(ctx context.Context, db *database.Database, transaction entities.TransactionEntity) (string, error) {
+			return "", db.RetagTransaction(ctx, transaction.Id, request.Add, request.Remove)
+		})
+}
+
+func (s *TransactionService) BulkMove(w http.ResponseWriter, r *http.Request) {
+	var request BulkMoveRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.AccountId == 0 || request.ToAccountId == 0 {
+		WriteFailure(w, "both accounts are required", http.StatusBadRequest)
+		return
+	}
+
+	if request.AccountId == request.ToAccountId {
+		WriteFailure(w, "the accounts must differ", http.StatusBadRequest)
+		return
+	}
+
+	from, to := int64(request.AccountId), int64(request.ToAccountId)
+
+	check := func(ctx context.Context, db *database.Database) error {
+		source, err := db.GetAccountById(ctx, from)
+
+		if err != nil {
+			return err
+		}
+
+		target, err := db.GetAccountById(ctx, to)
+
+		if err != nil {
+			return err
+		}
+
+		if target.Deleted {
+			return errors.New("the account to move to is deleted")
+		}
+
+		if source.Currency != target.Currency {
+			return errors.New("the accounts have different currencies")
+		}
+
+		return nil
+	}
+
+	s.bulk(w, r, request.BulkRequest, "move", check,
+		func(ctx context.Context, db *database.Database, transaction entities.TransactionEntity) (string, error) {
+			fromId, toId := idValue(transaction.FromAccountId), idValue(transaction.ToAccountId)
+
+			if fromId != int(from) && toId != int(from) {
+				return "transaction is not on the account", nil
+			}
+
+			if fromId == int(to) || toId == int(to) {
+				return "transaction would move to the account it comes from", nil
+			}
+
+			if err := db.MoveTransaction(ctx, transaction.Id, from, to); err != nil {
+				return "", err
+			}
+
+			return "", loans.RecordPayment(ctx, db, transaction.Id)
+		})
+}
+
+func (s *TransactionService) BulkDelete(w http.ResponseWriter, r *http.Request) {
+	var request BulkRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.bulk(w, r, request, "delete", nil,
+		func(ctx context.Context, db *database.Database, transaction entities.TransactionEntity) (string, error) {
+			// the interest charge of a loan payment goes with it and may
+			// have been deleted already
+			if err := db.DeleteTransaction(ctx, transaction.Id); err == database.ErrorNotFound {
+				return err.Error(), nil
+			} else if err != nil {
+				return "", err
+			}
+
+			return "", nil
+		})
+}
+
+// bulk runs action on every selected transaction in one exclusive database
+// transaction, after check passes. Reconciled transactions are locked and
+// skipped.
+func (s *TransactionService) bulk(w http.ResponseWriter, r *http.Request, request BulkRequest, name string,
+	check func(ctx context.Context, db *database.Database) error, action bulkAction) {
+	filtered := len(request.Filters) > 0 || strings.TrimSpace(request.Query) != ""
+
+	if len(request.Ids) == 0 && !filtered {
+		WriteFailure(w, "no transactions selected", http.StatusBadRequest)
+		return
+	}
+
+	if len(request.Ids) > 0 && filtered {
+		WriteFailure(w, "select transactions either by ids or by filters", http.StatusBadRequest)
+		return
+	}
+
+	filter, msg := transactionFilter(TableRequest{Filters: request.Filters, Query: request.Query})
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	result := BulkResult{DryRun: request.DryRun, Errors: []BulkError{}}
+
+	err := db.WithTxn(r.Context(), true, func(ctx context.Context) error {
+		if check != nil {
+			if err := check(ctx, db); err != nil {
+				return err
+			}
+		}
+
+		ids := uniqueIds(request.Ids)
+
+		if len(ids) == 0 {
+			var err error
+
+			if ids, err = db.GetTransactionIds(ctx, filter); err != nil {
+				return err
+			}
+		}
+
+		if len(ids) > maxBulkTransactions {
+			return fmt.Errorf("too many transactions, at most %d can be changed at once", maxBulkTransactions)
+		}
+
+		for _, id := range ids {
+			transaction, err := db.GetTransactionById(ctx, id)
+
+			if err == database.ErrorNotFound {
+				result.Errors = append(result.Errors, BulkError{Id: int(id), Error: err.Error()})
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			if transaction.Status == entities.StatusReconciled {
+				result.Errors = append(result.Errors, BulkError{Id: int(id), Error: database.ErrorReconciled.Error()})
+				continue
+			}
+
+			msg, err := action(ctx, db, transaction)
+
+			if err != nil {
+				return err
+			}
+
+			if msg != "" {
+				result.Errors = append(result.Errors, BulkError{Id: int(id), Error: msg})
+				continue
+			}
+
+			result.Count++
+		}
+
+		if request.DryRun {
+			return errBulkDryRun
+		}
+
+		return nil
+	})
+
+	if err != nil && err != errBulkDryRun {
+		logger.Errorf("failed to %s transactions: %v", name, err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, result)
+}
+
+// uniqueIds drops the repeated ids, keeping their order.
+func uniqueIds(values []int) []int64 {
+	seen := make(map[int]bool, len(values))
+	var ids []int64
+
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			ids = append(ids, int64(value))
+		}
+	}
+
+	return ids
+}
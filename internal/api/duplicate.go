@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/duplicates"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/database"
+)
+
+type DuplicateService struct {
+}
+
+type DuplicateMergeRequest struct {
+	// KeepId is the transaction of the pair that is kept, the lower id when
+	// it is not set.
+	KeepId int `json:"keepId"`
+}
+
+// All returns the suspected duplicates waiting for review, the most likely
+// first.
+func (s *DuplicateService) All(w http.ResponseWriter, r *http.Request) {
+	var request TableRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	rows := []entities.DuplicatePairRow{}
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		pairs, err := db.GetDuplicatePairs(ctx, uint64(request.Offset), uint64(request.Limit))
+
+		if err != nil {
+			return err
+		}
+
+		for _, pair := range pairs {
+			transactions, err := db.GetTransactions(ctx, entities.TransactionFilter{
+				Ids: []int64{pair.TransactionId, pair.DuplicateId},
+			}, 0, 2, "t.id")
+
+			if err != nil {
+				return err
+			}
+
+			if len(transactions) != 2 {
+				return fmt.Errorf("duplicate pair %d: %w", pair.Id, database.ErrorNotFound)
+			}
+
+			rows = append(rows, entities.DuplicatePairRow{
+				Id:          int(pair.Id),
+				Score:       pair.Score,
+				Transaction: transactions[0],
+				Duplicate:   transactions[1],
+			})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get duplicates: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, rows)
+}
+
+// Merge keeps one transaction of a pair and folds the other one into it.
+func (s *DuplicateService) Merge(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid duplicate id", http.StatusBadRequest)
+		return
+	}
+
+	var request DuplicateMergeRequest
+
+	if err = json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		pair, err := db.GetDuplicatePairById(ctx, int64(id))
+
+		if err != nil {
+			return err
+		}
+
+		switch int64(request.KeepId) {
+		case 0, pair.TransactionId:
+			return db.MergeTransactions(ctx, pair.TransactionId, pair.DuplicateId)
+		case pair.DuplicateId:
+			return db.MergeTransactions(ctx, pair.DuplicateId, pair.TransactionId)
+		default:
+			return fmt.Errorf("transaction %d is not part of the pair", request.KeepId)
+		}
+	})
+
+	if err != nil {
+		logger.Errorf("failed to merge duplicate: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// Dismiss marks a pair as two different transactions.
+func (s *DuplicateService) Dismiss(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid duplicate id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.DismissDuplicatePair(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to dismiss duplicate: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// Scan checks a page of existing transactions for duplicates, new
+// transactions are checked when they are created.
+func (s *DuplicateService) Scan(w http.ResponseWriter, r *http.Request) {
+	var request TableRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, msg := transactionFilter(request)
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	checked := 0
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		transactions, err := db.GetTransactions(ctx, filter,
+			uint64(request.Offset), uint64(request.Limit), "t.date")
+
+		if err != nil {
+			return err
+		}
+
+		for _, transaction := range transactions {
+			if err := duplicates.Check(ctx, db, int64(transaction.Id)); err != nil {
+				return err
+			}
+		}
+
+		checked = len(transactions)
+		return nil
+	})
+
+	if err != nil {
+		logger.Errorf("failed to scan for duplicates: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, checked)
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/currency"
+	"github.com/lembata/para/pkg/database"
+)
+
+// maximum number of months returned by a single envelope report
+const maxEnvelopeMonths = 120
+
+type EnvelopeService struct {
+}
+
+// EnvelopeTransferData moves money between two envelopes. Leaving out either
+// category moves the money from or to the "to be assigned" pool.
+type EnvelopeTransferData struct {
+	FromCategoryId int     `json:"fromCategoryId"`
+	ToCategoryId   int     `json:"toCategoryId"`
+	Month          string  `json:"month"`
+	Amount         float64 `json:"amount"`
+	Currency       string  `json:"currency"`
+}
+
+type EnvelopeRequest struct {
+	Currency  string `json:"currency"`
+	FromMonth string `json:"fromMonth"`
+	ToMonth   string `json:"toMonth"`
+}
+
+func (s *EnvelopeService) Move(w http.ResponseWriter, r *http.Request) {
+	var transfer EnvelopeTransferData
+	err := json.NewDecoder(r.Body).Decode(&transfer)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Moving envelope money: %v", transfer)
+
+	if transfer.FromCategoryId == transfer.ToCategoryId {
+		WriteFailure(w, "source and destination envelope are the same", http.StatusBadRequest)
+		return
+	}
+
+	if _, err = time.Parse(monthLayout, transfer.Month); err != nil {
+		WriteFailure(w, "month is invalid", http.StatusBadRequest)
+		return
+	}
+
+	if len(transfer.Currency) != 3 {
+		WriteFailure(w, "currency is invalid", http.StatusBadRequest)
+		return
+	}
+
+	newTransfer := entities.EnvelopeTransferEntity{
+		FromCategoryId: optionalId(transfer.FromCategoryId),
+		ToCategoryId:   optionalId(transfer.ToCategoryId),
+		Month:          transfer.Month,
+		Amount:         currency.ToCoins(transfer.Amount),
+		Currency:       transfer.Currency,
+		CreateAt:       time.Now(),
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		_, err := db.CreateEnvelopeTransfer(ctx, newTransfer)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to move envelope money: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// All returns the amount left to assign and the balance of every envelope for
+// each month of the requested range.
+func (s *EnvelopeService) All(w http.ResponseWriter, r *http.Request) {
+	var request EnvelopeRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(request.Currency) != 3 {
+		WriteFailure(w, "currency is invalid", http.StatusBadRequest)
+		return
+	}
+
+	from, err := time.Parse(monthLayout, request.FromMonth)
+
+	if err != nil {
+		WriteFailure(w, "from month is invalid", http.StatusBadRequest)
+		return
+	}
+
+	to, err := time.Parse(monthLayout, request.ToMonth)
+
+	if err != nil || to.Before(from) {
+		WriteFailure(w, "to month is invalid", http.StatusBadRequest)
+		return
+	}
+
+	if from.AddDate(0, maxEnvelopeMonths, 0).Before(to) {
+		WriteFailure(w, "month range too large", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var months []entities.EnvelopeMonth
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		months, err = db.GetEnvelopes(ctx, request.Currency, request.FromMonth, request.ToMonth)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get envelopes: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, months)
+}
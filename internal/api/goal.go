@@ -0,0 +1,320 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/internal/reports"
+	"github.com/lembata/para/pkg/currency"
+	"github.com/lembata/para/pkg/database"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+type GoalService struct {
+}
+
+// GoalData describes a savings goal. Allocation earmarks part of the account
+// balance for the goal, without it the whole balance counts. StartDate
+// defaults to today and StartAmount to what is saved when the goal is added.
+type GoalData struct {
+	Id           int      `json:"id"`
+	Name         string   `json:"name"`
+	AccountId    int      `json:"accountId"`
+	TargetAmount float64  `json:"targetAmount"`
+	TargetDate   string   `json:"targetDate"`
+	Allocation   *float64 `json:"allocation"`
+	StartDate    string   `json:"startDate"`
+	StartAmount  *float64 `json:"startAmount"`
+	Version      int      `json:"version"`
+}
+
+func (d *GoalData) toEntity() (entities.GoalEntity, string) {
+	if d.Name == "" {
+		return entities.GoalEntity{}, "name is required"
+	}
+
+	if d.AccountId == 0 {
+		return entities.GoalEntity{}, "an account is required"
+	}
+
+	if d.TargetAmount <= 0 {
+		return entities.GoalEntity{}, "target amount must be positive"
+	}
+
+	target, err := time.Parse(recurrence.DateLayout, d.TargetDate)
+
+	if err != nil {
+		return entities.GoalEntity{}, "target date is invalid"
+	}
+
+	if d.StartDate == "" {
+		d.StartDate = time.Now().Format(recurrence.DateLayout)
+	}
+
+	if start, err := time.Parse(recurrence.DateLayout, d.StartDate); err != nil {
+		return entities.GoalEntity{}, "start date is invalid"
+	} else if !target.After(start) {
+		return entities.GoalEntity{}, "target date must be after the start date"
+	}
+
+	goal := entities.GoalEntity{
+		Id:           int64(d.Id),
+		Name:         d.Name,
+		AccountId:    int64(d.AccountId),
+		TargetAmount: currency.ToCoins(d.TargetAmount),
+		TargetDate:   d.TargetDate,
+		StartDate:    d.StartDate,
+		Version:      d.Version,
+	}
+
+	if d.Allocation != nil {
+		if *d.Allocation < 0 {
+			return entities.GoalEntity{}, "allocation can not be negative"
+		}
+
+		allocation := currency.ToCoins(*d.Allocation)
+		goal.Allocation = &allocation
+	}
+
+	if d.StartAmount != nil {
+		goal.StartAmount = currency.ToCoins(*d.StartAmount)
+	}
+
+	return goal, ""
+}
+
+func (s *GoalService) CreateGoal(w http.ResponseWriter, r *http.Request) {
+	var data GoalData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Creating goal: %v", data)
+
+	goal, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	goal.CreateAt = time.Now()
+	goal.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		accounts, err := goalAccounts(ctx, db)
+
+		if err != nil {
+			return err
+		}
+
+		account, ok := accounts[goal.AccountId]
+
+		if !ok {
+			return fmt.Errorf("account %d does not exist", goal.AccountId)
+		}
+
+		if data.StartAmount == nil {
+			goal.StartAmount = reports.GoalSaved(goal, account.Balance.Value)
+		}
+
+		id, err = db.CreateGoal(ctx, goal)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to create goal: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+// EditGoal changes the name, account, target and allocation of a goal, the
+// start it is measured from stays.
+func (s *GoalService) EditGoal(w http.ResponseWriter, r *http.Request) {
+	var data GoalData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Editing goal: %v", data)
+
+	db := database.GetInstance()
+	var current entities.GoalRow
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		existing, err := db.GetGoalById(ctx, int64(data.Id))
+
+		if err != nil {
+			return err
+		}
+
+		data.StartDate = existing.StartDate
+		goal, msg := data.toEntity()
+
+		if msg != "" {
+			return errors.New(msg)
+		}
+
+		goal.UpdateAt = time.Now()
+
+		if _, err := db.EditGoal(ctx, goal); err == database.ErrorConflict {
+			accounts, err := goalAccounts(ctx, db)
+
+			if err != nil {
+				return err
+			}
+
+			current = reports.Goal(existing, accounts[existing.AccountId], time.Now())
+			return database.ErrorConflict
+		} else if err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err == database.ErrorConflict {
+		WriteConflict(w, current)
+		return
+	}
+
+	if err != nil {
+		logger.Errorf("failed to edit goal: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *GoalService) DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid goal id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.DeleteGoal(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete goal: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// GetGoal returns the progress of a goal as of today.
+func (s *GoalService) GetGoal(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid goal id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var row entities.GoalRow
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		goal, err := db.GetGoalById(ctx, int64(id))
+
+		if err != nil {
+			return err
+		}
+
+		accounts, err := goalAccounts(ctx, db)
+
+		if err != nil {
+			return err
+		}
+
+		row = reports.Goal(goal, accounts[goal.AccountId], time.Now())
+		return nil
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get goal: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, row)
+}
+
+// All returns the progress of every goal as of today, the closest target
+// date first.
+func (s *GoalService) All(w http.ResponseWriter, r *http.Request) {
+	db := database.GetInstance()
+	rows := []entities.GoalRow{}
+
+	err := db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		goals, err := db.GetGoals(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		accounts, err := goalAccounts(ctx, db)
+
+		if err != nil {
+			return err
+		}
+
+		for _, goal := range goals {
+			rows = append(rows, reports.Goal(goal, accounts[goal.AccountId], time.Now()))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get goals: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, rows)
+}
+
+// goalAccounts returns the accounts by id with their balance today.
+func goalAccounts(ctx context.Context, db *database.Database) (map[int64]entities.AccountRow, error) {
+	accounts, err := currentBalances(ctx, db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[int64]entities.AccountRow, len(accounts))
+
+	for _, account := range accounts {
+		byId[int64(account.Id)] = account
+	}
+
+	return byId, nil
+}
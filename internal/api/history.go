@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/database"
+)
+
+const maxHistoryCount = 50
+
+type HistoryService struct {
+}
+
+// HistoryRequest undoes or redoes the last Count operations, one by default.
+type HistoryRequest struct {
+	Count int `json:"count"`
+}
+
+// All returns the operations of the current user that can be undone or
+// redone, the latest first.
+func (s *HistoryService) All(w http.ResponseWriter, r *http.Request) {
+	db := database.GetInstance()
+	var operations []entities.Operation
+
+	err := db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		var err error
+		operations, err = db.GetHistory(ctx, requestActor(r))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get history: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, operations)
+}
+
+// Undo reverts the last operations of the current user and returns them.
+func (s *HistoryService) Undo(w http.ResponseWriter, r *http.Request) {
+	s.replay(w, r, "undo", func(ctx context.Context, db *database.Database, count uint64) ([]entities.Operation, error) {
+		return db.Undo(ctx, requestActor(r), count)
+	})
+}
+
+// Redo applies again the operations of the current user that were undone
+// last and returns them.
+func (s *HistoryService) Redo(w http.ResponseWriter, r *http.Request) {
+	s.replay(w, r, "redo", func(ctx context.Context, db *database.Database, count uint64) ([]entities.Operation, error) {
+		return db.Redo(ctx, requestActor(r), count)
+	})
+}
+
+// UndoOperation reverts one operation of the current user, like an import,
+// and returns it.
+func (s *HistoryService) UndoOperation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid operation id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var operation entities.Operation
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		operation, err = db.UndoOperation(ctx, requestActor(r), int64(id))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to undo operation: %v", err)
+		WriteFailure(w, err.Error(), historyStatus(err))
+		return
+	}
+
+	_, _ = WriteData(w, operation)
+}
+
+func (s *HistoryService) replay(w http.ResponseWriter, r *http.Request, action string,
+	fn func(ctx context.Context, db *database.Database, count uint64) ([]entities.Operation, error)) {
+	var request HistoryRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.Count < 0 || request.Count > maxHistoryCount {
+		WriteFailure(w, "count is invalid", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var operations []entities.Operation
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		operations, err = fn(ctx, db, uint64(max(request.Count, 1)))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to %s: %v", action, err)
+		WriteFailure(w, err.Error(), historyStatus(err))
+		return
+	}
+
+	_, _ = WriteData(w, operations)
+}
+
+// historyStatus answers a change that was made over with 409 Conflict.
+func historyStatus(err error) int {
+	if err == database.ErrorConflict {
+		return http.StatusConflict
+	}
+
+	return http.StatusBadRequest
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/lembata/para/internal/classifier"
+	"github.com/lembata/para/internal/duplicates"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/internal/loans"
+	"github.com/lembata/para/internal/rules"
+	"github.com/lembata/para/pkg/currency"
+	"github.com/lembata/para/pkg/database"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+// importConfidence is the confidence a learned category needs to be filled
+// into an imported transaction that has no category from its payee.
+const importConfidence = 0.8
+
+// ImportRequest books the lines of a bank statement on an account. Source
+// is the statement format, the closing balance of the statement is recorded
+// for reconciling the account when it is given.
+type ImportRequest struct {
+	AccountId      int            `json:"accountId"`
+	Source         string         `json:"source"`
+	ClosingBalance *ImportBalance `json:"closingBalance"`
+	Transactions   []ImportLine   `json:"transactions"`
+}
+
+type ImportBalance struct {
+	Date    string  `json:"date"`
+	Balance float64 `json:"balance"`
+}
+
+// ImportResult lists the created transactions and the reconciliation that
+// took the closing balance, if any.
+type ImportResult struct {
+	Ids              []int64 `json:"ids"`
+	ReconciliationId int64   `json:"reconciliationId"`
+}
+
+// ImportLine is one booking of a statement. Negative amounts leave the
+// account. The counterparty is matched to a payee by IBAN or name, a payee
+// is created when none matches.
+type ImportLine struct {
+	Date             string  `json:"date"`
+	Amount           float64 `json:"amount"`
+	Description      string  `json:"description"`
+	Notes            string  `json:"notes"`
+	CounterpartyName string  `json:"counterpartyName"`
+	CounterpartyIban string  `json:"counterpartyIban"`
+}
+
+func (r *ImportRequest) validate() string {
+	if r.AccountId == 0 {
+		return "an account is required"
+	}
+
+	if len(r.Transactions) == 0 {
+		return "no transactions given"
+	}
+
+	if len(r.Transactions) > maxBulkTransactions {
+		return "too many transactions"
+	}
+
+	switch r.Source {
+	case entities.StatementSourceCamt, entities.StatementSourceMT940, entities.StatementSourceOFX:
+	default:
+		return "invalid statement source"
+	}
+
+	if r.ClosingBalance != nil {
+		if _, err := time.Parse(recurrence.DateLayout, r.ClosingBalance.Date); err != nil {
+			return "closing balance date is invalid"
+		}
+	}
+
+	for _, line := range r.Transactions {
+		if _, err := time.Parse(recurrence.DateLayout, line.Date); err != nil {
+			return "date is invalid"
+		}
+
+		if currency.ToCoins(line.Amount) == 0 {
+			return "amount must not be zero"
+		}
+	}
+
+	return ""
+}
+
+// Import creates the transactions of a bank statement on an account, the
+// same way as they are created one by one, and feeds its closing balance into
+// reconciliation. The import is one operation in the history and can be
+// undone as a whole.
+func (s *TransactionService) Import(w http.ResponseWriter, r *http.Request) {
+	var request ImportRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Importing %d transactions into account %d", len(request.Transactions), request.AccountId)
+
+	if msg := request.validate(); msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	accountId := int64(request.AccountId)
+	result := ImportResult{Ids: make([]int64, 0, len(request.Transactions))}
+
+	err = db.WithTxn(r.Context(), true, func(ctx context.Context) error {
+		if _, err := db.GetAccountById(ctx, accountId); err != nil {
+			return err
+		}
+
+		for _, line := range request.Transactions {
+			id, err := s.importLine(ctx, db, accountId, line)
+
+			if err != nil {
+				return err
+			}
+
+			result.Ids = append(result.Ids, id)
+		}
+
+		if request.ClosingBalance == nil {
+			return nil
+		}
+
+		id, err := db.RecordStatementBalance(ctx, accountId, request.ClosingBalance.Date,
+			currency.ToCoins(request.ClosingBalance.Balance), request.Source)
+		result.ReconciliationId = id
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to import transactions: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, result)
+}
+
+// importLine creates the transaction of a statement line with a single item.
+// The item is put in the default category of the payee or, without one, in
+// the category learned from past transactions when it is likely enough.
+func (s *TransactionService) importLine(ctx context.Context, db *database.Database, accountId int64, line ImportLine) (int64, error) {
+	transaction := entities.TransactionEntity{
+		TotalAmount: currency.ToCoins(line.Amount),
+		Date:        line.Date,
+		Description: line.Description,
+		Notes:       line.Notes,
+		CreateAt:    time.Now(),
+		UpdateAt:    time.Now(),
+	}
+
+	if transaction.TotalAmount < 0 {
+		transaction.TotalAmount = -transaction.TotalAmount
+		transaction.FromAccountId = &accountId
+	} else {
+		transaction.ToAccountId = &accountId
+	}
+
+	var categoryId *int64
+	payeeId, err := db.FindOrCreatePayee(ctx, line.CounterpartyName, line.CounterpartyIban)
+
+	if err == nil {
+		transaction.PayeeId = &payeeId
+
+		if categoryId, err = resolvePayee(ctx, db, &transaction, ""); err != nil {
+			return 0, err
+		}
+	} else if err != database.ErrorNotFound {
+		return 0, err
+	}
+
+	if categoryId == nil {
+		categoryId, err = s.classifier.Predict(ctx, classifier.Input{
+			PayeeName:   line.CounterpartyName,
+			Description: line.Description,
+			Amount:      transaction.TotalAmount,
+		}, importConfidence)
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	id, err := db.CreateTransaction(ctx, transaction)
+
+	if err != nil {
+		return 0, err
+	}
+
+	name := line.Description
+
+	if name == "" {
+		name = line.CounterpartyName
+	}
+
+	if name == "" {
+		name = "Imported transaction"
+	}
+
+	_, err = db.CreateItem(ctx, entities.ItemEntity{
+		Name:          name,
+		Price:         transaction.TotalAmount,
+		TransactionId: id,
+		CategoryId:    categoryId,
+		CreateAt:      time.Now(),
+		UpdateAt:      time.Now(),
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err := rules.Apply(ctx, db, id); err != nil {
+		return 0, err
+	}
+
+	if err := duplicates.Check(ctx, db, id); err != nil {
+		return 0, err
+	}
+
+	return id, loans.RecordPayment(ctx, db, id)
+}
@@ -0,0 +1,705 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/internal/investments"
+	"github.com/lembata/para/pkg/currency"
+	"github.com/lembata/para/pkg/database"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+const maxPriceImportSize = 10 << 20
+
+type SecurityService struct {
+}
+
+type InvestmentService struct {
+}
+
+type SecurityData struct {
+	Id       int    `json:"id"`
+	Symbol   string `json:"symbol"`
+	ISIN     string `json:"isin"`
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
+	Version  int    `json:"version"`
+}
+
+type SecurityPriceRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type TradeData struct {
+	AccountId  int                `json:"accountId"`
+	SecurityId int                `json:"securityId"`
+	Kind       entities.TradeKind `json:"kind"`
+	Date       string             `json:"date"`
+	// Quantity and Price are used by buys and sells, Amount by dividends
+	// and fees.
+	Quantity float64 `json:"quantity"`
+	Price    float64 `json:"price"`
+	Fee      float64 `json:"fee"`
+	Amount   float64 `json:"amount"`
+}
+
+type HoldingsRequest struct {
+	AccountId int `json:"accountId"`
+	// Date values the holdings at the end of that day, today when it is not
+	// set.
+	Date string `json:"date"`
+}
+
+type TradeListRequest struct {
+	AccountId  int `json:"accountId"`
+	SecurityId int `json:"securityId"`
+}
+
+func (d *SecurityData) toEntity() (entities.SecurityEntity, string) {
+	d.Symbol = strings.TrimSpace(d.Symbol)
+	d.ISIN = strings.ToUpper(strings.TrimSpace(d.ISIN))
+
+	if d.Symbol == "" {
+		return entities.SecurityEntity{}, "symbol is required"
+	}
+
+	if d.ISIN != "" && len(d.ISIN) != 12 {
+		return entities.SecurityEntity{}, "ISIN is invalid"
+	}
+
+	if len(d.Currency) != 3 {
+		return entities.SecurityEntity{}, "currency is invalid"
+	}
+
+	if d.Name == "" {
+		d.Name = d.Symbol
+	}
+
+	return entities.SecurityEntity{
+		Id:       int64(d.Id),
+		Symbol:   d.Symbol,
+		ISIN:     d.ISIN,
+		Name:     d.Name,
+		Currency: d.Currency,
+		Version:  d.Version,
+	}, ""
+}
+
+func (d *TradeData) toEntity() (entities.TradeEntity, string) {
+	if d.AccountId == 0 || d.SecurityId == 0 {
+		return entities.TradeEntity{}, "an account and a security are required"
+	}
+
+	if _, err := time.Parse(recurrence.DateLayout, d.Date); err != nil {
+		return entities.TradeEntity{}, "date is invalid"
+	}
+
+	trade := entities.TradeEntity{
+		AccountId:  int64(d.AccountId),
+		SecurityId: int64(d.SecurityId),
+		Kind:       d.Kind,
+		Date:       d.Date,
+		Fee:        currency.ToCoins(d.Fee),
+	}
+
+	if trade.Fee < 0 {
+		return entities.TradeEntity{}, "fee must not be negative"
+	}
+
+	switch d.Kind {
+	case entities.TradeBuy, entities.TradeSell:
+		if d.Quantity <= 0 || d.Price <= 0 {
+			return entities.TradeEntity{}, "quantity and price must be positive"
+		}
+
+		trade.Quantity = d.Quantity
+		trade.Price = currency.ToCoins(d.Price)
+		trade.Amount = int(math.Round(d.Quantity * float64(trade.Price)))
+
+		if d.Kind == entities.TradeBuy {
+			trade.Amount += trade.Fee
+		} else {
+			trade.Amount -= trade.Fee
+		}
+	case entities.TradeDividend, entities.TradeFee:
+		trade.Amount = currency.ToCoins(d.Amount)
+	default:
+		return entities.TradeEntity{}, "trade kind is invalid"
+	}
+
+	if trade.Amount <= 0 {
+		return entities.TradeEntity{}, "amount must be positive"
+	}
+
+	return trade, ""
+}
+
+func (s *SecurityService) CreateSecurity(w http.ResponseWriter, r *http.Request) {
+	var data SecurityData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Creating security: %v", data)
+
+	security, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	security.CreateAt = time.Now()
+	security.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		id, err = db.CreateSecurity(ctx, security)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to create security: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+func (s *SecurityService) EditSecurity(w http.ResponseWriter, r *http.Request) {
+	var data SecurityData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Editing security: %v", data)
+
+	security, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	security.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var current entities.SecurityEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		_, err := db.EditSecurity(ctx, security)
+
+		if err == database.ErrorConflict {
+			if current, err = db.GetSecurityById(ctx, security.Id); err == nil {
+				return database.ErrorConflict
+			}
+		}
+
+		return err
+	})
+
+	if err == database.ErrorConflict {
+		WriteConflict(w, current)
+		return
+	}
+
+	if err != nil {
+		logger.Errorf("failed to edit security: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// DeleteSecurity removes a security that was never traded.
+func (s *SecurityService) DeleteSecurity(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid security id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		trades, err := db.GetTrades(ctx, 0, int64(id), "")
+
+		if err != nil {
+			return err
+		}
+
+		if len(trades) > 0 {
+			return fmt.Errorf("security %d has %d trades", id, len(trades))
+		}
+
+		return db.DeleteSecurity(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete security: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *SecurityService) GetSecurity(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid security id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var security entities.SecurityEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		security, err = db.GetSecurityById(ctx, int64(id))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get security: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, security)
+}
+
+func (s *SecurityService) All(w http.ResponseWriter, r *http.Request) {
+	db := database.GetInstance()
+	var securities []entities.SecurityEntity
+
+	err := db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		var err error
+		securities, err = db.GetSecurities(ctx)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get securities: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if securities == nil {
+		securities = []entities.SecurityEntity{}
+	}
+
+	_, _ = WriteData(w, securities)
+}
+
+// Prices returns the price history of a security.
+func (s *SecurityService) Prices(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid security id", http.StatusBadRequest)
+		return
+	}
+
+	var request SecurityPriceRequest
+
+	if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var prices []entities.SecurityPrice
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		prices, err = db.GetSecurityPrices(ctx, int64(id), request.From, request.To)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get prices: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, prices)
+}
+
+// ImportPrices reads closing prices from a CSV body. The header names the
+// columns: symbol or isin, date and price or close. Prices already recorded
+// for a day are replaced. It returns the number of prices imported.
+func (s *SecurityService) ImportPrices(w http.ResponseWriter, r *http.Request) {
+	reader := csv.NewReader(http.MaxBytesReader(w, r.Body, maxPriceImportSize))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+
+	if err != nil {
+		WriteFailure(w, "header is missing: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	columns := map[string]int{}
+
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "symbol", "isin", "ticker":
+			columns["security"] = i
+		case "date":
+			columns["date"] = i
+		case "price", "close":
+			columns["price"] = i
+		}
+	}
+
+	if len(columns) != 3 {
+		WriteFailure(w, "header needs a symbol or isin, a date and a price column", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	imported := 0
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		securities := map[string]int64{}
+
+		for line := 2; ; line++ {
+			record, err := reader.Read()
+
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			code := strings.TrimSpace(record[columns["security"]])
+			securityId, ok := securities[code]
+
+			if !ok {
+				security, err := db.GetSecurityByCode(ctx, code)
+
+				if errors.Is(err, database.ErrorNotFound) {
+					return fmt.Errorf("line %d: unknown security %q", line, code)
+				} else if err != nil {
+					return err
+				}
+
+				securityId = security.Id
+				securities[code] = securityId
+			}
+
+			date := strings.TrimSpace(record[columns["date"]])
+
+			if _, err := time.Parse(recurrence.DateLayout, date); err != nil {
+				return fmt.Errorf("line %d: date is invalid", line)
+			}
+
+			price, err := strconv.ParseFloat(strings.TrimSpace(record[columns["price"]]), 64)
+
+			if err != nil || price < 0 {
+				return fmt.Errorf("line %d: price is invalid", line)
+			}
+
+			err = db.SetSecurityPrice(ctx, entities.SecurityPrice{
+				SecurityId: securityId,
+				Date:       date,
+				Price:      currency.ToCoins(price),
+			})
+
+			if err != nil {
+				return err
+			}
+
+			imported++
+		}
+	})
+
+	if err != nil {
+		logger.Errorf("failed to import prices: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, imported)
+}
+
+// CreateTrade records a trade on an investment account together with the
+// transaction moving its cash. Sells can not exceed the quantity held at
+// any point in time.
+func (s *InvestmentService) CreateTrade(w http.ResponseWriter, r *http.Request) {
+	var data TradeData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Creating trade: %v", data)
+
+	trade, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	trade.CreateAt = time.Now()
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		account, err := db.GetAccountById(ctx, trade.AccountId)
+
+		if err != nil {
+			return err
+		}
+
+		if account.Type != entities.AccountInvestment {
+			return fmt.Errorf("account %d is not an investment account", account.Id)
+		}
+
+		security, err := db.GetSecurityById(ctx, trade.SecurityId)
+
+		if err != nil {
+			return err
+		}
+
+		if security.Currency != account.Currency {
+			return fmt.Errorf("security is traded in %s, the account is in %s", security.Currency, account.Currency)
+		}
+
+		transaction := entities.TransactionEntity{
+			TotalAmount: trade.Amount,
+			Date:        trade.Date,
+			Description: tradeDescription(trade, security),
+			CreateAt:    time.Now(),
+			UpdateAt:    time.Now(),
+		}
+
+		if trade.Kind == entities.TradeBuy || trade.Kind == entities.TradeFee {
+			transaction.FromAccountId = &account.Id
+		} else {
+			transaction.ToAccountId = &account.Id
+		}
+
+		if trade.TransactionId, err = db.CreateTransaction(ctx, transaction); err != nil {
+			return err
+		}
+
+		if id, err = db.CreateTrade(ctx, trade); err != nil {
+			return err
+		}
+
+		return checkHoldings(ctx, db, trade.AccountId, trade.SecurityId)
+	})
+
+	if err != nil {
+		logger.Errorf("failed to create trade: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+// DeleteTrade removes a trade and its transaction, unless a later sell
+// depends on it.
+func (s *InvestmentService) DeleteTrade(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid trade id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		trade, err := db.GetTradeById(ctx, int64(id))
+
+		if err != nil {
+			return err
+		}
+
+		if err := db.CheckTransactionUnlocked(ctx, trade.TransactionId); err != nil {
+			return err
+		}
+
+		if err := db.DeleteTrade(ctx, trade.Id); err != nil {
+			return err
+		}
+
+		return checkHoldings(ctx, db, trade.AccountId, trade.SecurityId)
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete trade: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// Trades lists the trades of an investment account, optionally of a single
+// security.
+func (s *InvestmentService) Trades(w http.ResponseWriter, r *http.Request) {
+	var request TradeListRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.AccountId == 0 {
+		WriteFailure(w, "an account is required", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var trades []entities.TradeEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		trades, err = db.GetTrades(ctx, int64(request.AccountId), int64(request.SecurityId), "")
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get trades: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if trades == nil {
+		trades = []entities.TradeEntity{}
+	}
+
+	_, _ = WriteData(w, trades)
+}
+
+// Holdings returns the positions of an investment account with their open
+// lots, realized and unrealized gains.
+func (s *InvestmentService) Holdings(w http.ResponseWriter, r *http.Request) {
+	var request HoldingsRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.Date == "" {
+		request.Date = time.Now().Format(recurrence.DateLayout)
+	} else if _, err := time.Parse(recurrence.DateLayout, request.Date); err != nil {
+		WriteFailure(w, "date is invalid", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var holdings []entities.Holding
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		account, err := db.GetAccountById(ctx, int64(request.AccountId))
+
+		if err != nil {
+			return err
+		}
+
+		trades, err := db.GetTrades(ctx, account.Id, 0, request.Date)
+
+		if err != nil {
+			return err
+		}
+
+		securityList, err := db.GetSecurities(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		securities := make(map[int64]entities.SecurityEntity, len(securityList))
+
+		for _, security := range securityList {
+			securities[security.Id] = security
+		}
+
+		prices, err := db.GetLatestPrices(ctx, request.Date)
+
+		if err != nil {
+			return err
+		}
+
+		holdings, err = investments.Holdings(trades, securities, prices, account.Currency)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get holdings: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, holdings)
+}
+
+// checkHoldings replays the trades of a security on an account to make sure
+// no sell exceeds what was held at its date.
+func checkHoldings(ctx context.Context, db *database.Database, accountId int64, securityId int64) error {
+	trades, err := db.GetTrades(ctx, accountId, securityId, "")
+
+	if err != nil {
+		return err
+	}
+
+	// on the same day buys come first
+	sort.SliceStable(trades, func(i, j int) bool {
+		if trades[i].Date != trades[j].Date {
+			return trades[i].Date < trades[j].Date
+		}
+
+		return trades[i].Kind == entities.TradeBuy && trades[j].Kind != entities.TradeBuy
+	})
+
+	_, err = investments.Holdings(trades, nil, nil, "")
+	return err
+}
+
+func tradeDescription(trade entities.TradeEntity, security entities.SecurityEntity) string {
+	kind := trade.Kind.String()
+	kind = strings.ToUpper(kind[:1]) + kind[1:]
+
+	switch trade.Kind {
+	case entities.TradeBuy, entities.TradeSell:
+		return fmt.Sprintf("%s %s %s", kind, strconv.FormatFloat(trade.Quantity, 'f', -1, 64), security.Symbol)
+	default:
+		return fmt.Sprintf("%s %s", kind, security.Symbol)
+	}
+}
@@ -0,0 +1,404 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/internal/loans"
+	"github.com/lembata/para/pkg/currency"
+	"github.com/lembata/para/pkg/database"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+type LoanService struct {
+}
+
+type LoanData struct {
+	Id        int     `json:"id"`
+	AccountId int     `json:"accountId"`
+	Principal float64 `json:"principal"`
+	// InterestRate is the yearly rate in percent.
+	InterestRate       float64             `json:"interestRate"`
+	TermMonths         int                 `json:"termMonths"`
+	StartDate          string              `json:"startDate"`
+	Method             entities.LoanMethod `json:"method"`
+	InterestCategoryId int                 `json:"interestCategoryId"`
+	Version            int                 `json:"version"`
+}
+
+type ExtraPaymentData struct {
+	Date   string  `json:"date"`
+	Amount float64 `json:"amount"`
+}
+
+type LoanProjectionRequest struct {
+	ExtraMonthly  float64            `json:"extraMonthly"`
+	ExtraPayments []ExtraPaymentData `json:"extraPayments"`
+}
+
+func (d *LoanData) toEntity() (entities.LoanEntity, string) {
+	if d.AccountId == 0 {
+		return entities.LoanEntity{}, "an account is required"
+	}
+
+	if d.Method < entities.LoanAnnuity || d.Method > entities.LoanLinear {
+		return entities.LoanEntity{}, "loan method is invalid"
+	}
+
+	loan := entities.LoanEntity{
+		Id:                 int64(d.Id),
+		AccountId:          int64(d.AccountId),
+		Principal:          currency.ToCoins(d.Principal),
+		InterestRate:       d.InterestRate,
+		TermMonths:         d.TermMonths,
+		StartDate:          d.StartDate,
+		Method:             d.Method,
+		InterestCategoryId: optionalId(d.InterestCategoryId),
+		Version:            d.Version,
+	}
+
+	if err := loans.Validate(loan); err != nil {
+		return entities.LoanEntity{}, err.Error()
+	}
+
+	return loan, ""
+}
+
+func (d *LoanProjectionRequest) toExtra() (loans.Extra, string) {
+	extra := loans.Extra{Monthly: currency.ToCoins(d.ExtraMonthly)}
+
+	if extra.Monthly < 0 {
+		return loans.Extra{}, "extra payments must be positive"
+	}
+
+	for _, payment := range d.ExtraPayments {
+		if _, err := time.Parse(recurrence.DateLayout, payment.Date); err != nil {
+			return loans.Extra{}, "date of extra payment is invalid"
+		}
+
+		if payment.Amount <= 0 {
+			return loans.Extra{}, "extra payments must be positive"
+		}
+
+		extra.Payments = append(extra.Payments, loans.ExtraPayment{
+			Date:   payment.Date,
+			Amount: currency.ToCoins(payment.Amount),
+		})
+	}
+
+	return extra, ""
+}
+
+// CreateLoan adds the terms of a loan to an account of type loan. Payments
+// into the account are split into principal and interest from then on.
+func (s *LoanService) CreateLoan(w http.ResponseWriter, r *http.Request) {
+	var data LoanData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Creating loan: %v", data)
+
+	loan, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	loan.CreateAt = time.Now()
+	loan.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		if err := checkLoanAccount(ctx, db, loan.AccountId); err != nil {
+			return err
+		}
+
+		if _, err := db.GetLoanByAccount(ctx, loan.AccountId); err == nil {
+			return fmt.Errorf("account %d already has a loan", loan.AccountId)
+		} else if err != database.ErrorNotFound {
+			return err
+		}
+
+		id, err = db.CreateLoan(ctx, loan)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to create loan: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+func (s *LoanService) EditLoan(w http.ResponseWriter, r *http.Request) {
+	var data LoanData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Editing loan: %v", data)
+
+	loan, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	loan.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var current entities.LoanRow
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		_, err := db.EditLoan(ctx, loan)
+
+		if err == database.ErrorConflict {
+			var existing entities.LoanEntity
+
+			if existing, err = db.GetLoanById(ctx, loan.Id); err == nil {
+				if current, err = loanRow(ctx, db, existing); err == nil {
+					return database.ErrorConflict
+				}
+			}
+		}
+
+		return err
+	})
+
+	if err == database.ErrorConflict {
+		WriteConflict(w, current)
+		return
+	}
+
+	if err != nil {
+		logger.Errorf("failed to edit loan: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *LoanService) DeleteLoan(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid loan id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.DeleteLoan(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete loan: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// GetLoan returns a loan with the principal and interest paid so far.
+func (s *LoanService) GetLoan(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid loan id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var row entities.LoanRow
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		loan, err := db.GetLoanById(ctx, int64(id))
+
+		if err != nil {
+			return err
+		}
+
+		row, err = loanRow(ctx, db, loan)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get loan: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, row)
+}
+
+func (s *LoanService) All(w http.ResponseWriter, r *http.Request) {
+	db := database.GetInstance()
+	rows := []entities.LoanRow{}
+
+	err := db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		loanList, err := db.GetLoans(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		for _, loan := range loanList {
+			row, err := loanRow(ctx, db, loan)
+
+			if err != nil {
+				return err
+			}
+
+			rows = append(rows, row)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get loans: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, rows)
+}
+
+// Schedule returns the full amortization schedule of a loan.
+func (s *LoanService) Schedule(w http.ResponseWriter, r *http.Request) {
+	s.project(w, r, func(loan entities.LoanEntity, _ loans.Extra, currency string) any {
+		schedule := loans.Schedule(loan, loans.Extra{})
+		schedule.Currency = currency
+		return schedule
+	})
+}
+
+// Projection returns the payoff date and total interest of a loan with and
+// without the extra payments of the request.
+func (s *LoanService) Projection(w http.ResponseWriter, r *http.Request) {
+	s.project(w, r, func(loan entities.LoanEntity, extra loans.Extra, currency string) any {
+		projection := loans.Project(loan, extra)
+		projection.Baseline.Currency = currency
+		projection.Scenario.Currency = currency
+		// the rows are left out, the schedule has them
+		projection.Baseline.Rows = nil
+		projection.Scenario.Rows = nil
+		return projection
+	})
+}
+
+func (s *LoanService) project(w http.ResponseWriter, r *http.Request,
+	build func(loan entities.LoanEntity, extra loans.Extra, currency string) any) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid loan id", http.StatusBadRequest)
+		return
+	}
+
+	var request LoanProjectionRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	extra, msg := request.toExtra()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var loan entities.LoanEntity
+	var account entities.AccountEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		if loan, err = db.GetLoanById(ctx, int64(id)); err != nil {
+			return err
+		}
+
+		account, err = db.GetAccountById(ctx, loan.AccountId)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get loan: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, build(loan, extra, account.Currency))
+}
+
+func checkLoanAccount(ctx context.Context, db *database.Database, accountId int64) error {
+	account, err := db.GetAccountById(ctx, accountId)
+
+	if err != nil {
+		return err
+	}
+
+	if account.Type != entities.AccountLoan {
+		return fmt.Errorf("account %d is not a loan account", accountId)
+	}
+
+	return nil
+}
+
+func loanRow(ctx context.Context, db *database.Database, loan entities.LoanEntity) (entities.LoanRow, error) {
+	account, err := db.GetAccountById(ctx, loan.AccountId)
+
+	if err != nil {
+		return entities.LoanRow{}, err
+	}
+
+	principalPaid, interestPaid, _, err := db.GetLoanPaymentTotals(ctx, loan.Id, "", 0)
+
+	if err != nil {
+		return entities.LoanRow{}, err
+	}
+
+	value := func(amount int) entities.CurrencyValue {
+		return entities.CurrencyValue{Currency: account.Currency, Value: amount}
+	}
+
+	return entities.LoanRow{
+		Id:                 int(loan.Id),
+		AccountId:          int(loan.AccountId),
+		AccountName:        account.Name,
+		Principal:          value(loan.Principal),
+		InterestRate:       loan.InterestRate,
+		TermMonths:         loan.TermMonths,
+		StartDate:          loan.StartDate,
+		Method:             loan.Method,
+		InterestCategoryId: idValue(loan.InterestCategoryId),
+		PrincipalPaid:      value(principalPaid),
+		InterestPaid:       value(interestPaid),
+		Outstanding:        value(max(0, loan.Principal-principalPaid)),
+		Version:            loan.Version,
+	}, nil
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/database"
+)
+
+const (
+	defaultAutocompleteLimit = 10
+	maxAutocompleteLimit     = 50
+)
+
+type PayeeService struct {
+}
+
+type PayeeData struct {
+	Id                int      `json:"id"`
+	Name              string   `json:"name"`
+	DefaultCategoryId int      `json:"defaultCategoryId"`
+	IBAN              string   `json:"iban"`
+	Aliases           []string `json:"aliases"`
+	Version           int      `json:"version"`
+}
+
+type PayeeMergeRequest struct {
+	TargetId  int   `json:"targetId"`
+	SourceIds []int `json:"sourceIds"`
+}
+
+func (d *PayeeData) toEntity() (entities.PayeeEntity, string) {
+	if d.Name == "" {
+		return entities.PayeeEntity{}, "payee name is required"
+	}
+
+	return entities.PayeeEntity{
+		Id:                int64(d.Id),
+		Name:              d.Name,
+		DefaultCategoryId: optionalId(d.DefaultCategoryId),
+		IBAN:              d.IBAN,
+		Aliases:           d.Aliases,
+		Version:           d.Version,
+	}, ""
+}
+
+func (s *PayeeService) CreatePayee(w http.ResponseWriter, r *http.Request) {
+	var data PayeeData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Creating payee: %v", data)
+
+	payee, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	payee.CreateAt = time.Now()
+	payee.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		id, err = db.CreatePayee(ctx, payee)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to create payee: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+func (s *PayeeService) EditPayee(w http.ResponseWriter, r *http.Request) {
+	var data PayeeData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Editing payee: %v", data)
+
+	payee, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	payee.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var current PayeeData
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		_, err := db.EditPayee(ctx, payee)
+
+		if err == database.ErrorConflict {
+			var existing entities.PayeeEntity
+
+			if existing, err = db.GetPayeeById(ctx, payee.Id); err == nil {
+				current = payeeData(existing)
+				return database.ErrorConflict
+			}
+		}
+
+		return err
+	})
+
+	if err == database.ErrorConflict {
+		WriteConflict(w, current)
+		return
+	}
+
+	if err != nil {
+		logger.Errorf("failed to edit payee: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *PayeeService) DeletePayee(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid payee id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.DeletePayee(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete payee: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *PayeeService) GetPayee(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid payee id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var payee entities.PayeeEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		payee, err = db.GetPayeeById(ctx, int64(id))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get payee: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, payeeData(payee))
+}
+
+func payeeData(payee entities.PayeeEntity) PayeeData {
+	return PayeeData{
+		Id:                int(payee.Id),
+		Name:              payee.Name,
+		DefaultCategoryId: idValue(payee.DefaultCategoryId),
+		IBAN:              payee.IBAN,
+		Aliases:           payee.Aliases,
+		Version:           payee.Version,
+	}
+}
+
+// All returns a page of payees, optionally restricted by the search filter.
+func (s *PayeeService) All(w http.ResponseWriter, r *http.Request) {
+	var tableRequest TableRequest
+	err := json.NewDecoder(r.Body).Decode(&tableRequest)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = tableRequest.Validate(); err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.writePayees(w, r, tableRequest.Filters["search"], tableRequest.Offset, tableRequest.Limit)
+}
+
+// Autocomplete suggests payees for the q query parameter, matching their names
+// and aliases.
+func (s *PayeeService) Autocomplete(w http.ResponseWriter, r *http.Request) {
+	limit := defaultAutocompleteLimit
+
+	if r.URL.Query().Has("limit") {
+		var err error
+
+		if limit, err = strconv.Atoi(r.URL.Query().Get("limit")); err != nil || limit < 1 || limit > maxAutocompleteLimit {
+			WriteFailure(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.writePayees(w, r, r.URL.Query().Get("q"), 0, limit)
+}
+
+func (s *PayeeService) writePayees(w http.ResponseWriter, r *http.Request, search string, offset int, limit int) {
+	db := database.GetInstance()
+	var payees []entities.PayeeRow
+	var err error
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		payees, err = db.GetPayees(ctx, search, uint64(offset), uint64(limit))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get payees: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, payees)
+}
+
+// Merge folds the source payees into the target payee.
+func (s *PayeeService) Merge(w http.ResponseWriter, r *http.Request) {
+	var request PayeeMergeRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.TargetId == 0 || len(request.SourceIds) == 0 {
+		WriteFailure(w, "target and source payees are required", http.StatusBadRequest)
+		return
+	}
+
+	sourceIds := make([]int64, 0, len(request.SourceIds))
+
+	for _, id := range request.SourceIds {
+		sourceIds = append(sourceIds, int64(id))
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.MergePayees(ctx, int64(request.TargetId), sourceIds)
+	})
+
+	if err != nil {
+		logger.Errorf("failed to merge payees: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// trustedProxiesEnv lists the reverse proxies in front of para by address or
+// CIDR, separated by commas. Only their requests are believed about the
+// client address and the signed in user.
+const trustedProxiesEnv = "PARA_TRUSTED_PROXIES"
+
+type proxyKey int
+
+const proxiedKey proxyKey = iota + 1
+
+// parseTrustedProxies reads the addresses and networks of trustedProxiesEnv.
+func parseTrustedProxies(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+
+			bits := 8 * net.IPv4len
+
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// proxyHandler takes the client address of a request from the headers set by
+// a trusted proxy, like middleware.RealIP, and marks the request as proxied.
+// Requests from anywhere else keep their own address.
+func proxyHandler(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		realIP := middleware.RealIP(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isTrustedProxy(trusted, r.RemoteAddr) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), proxiedKey, true)
+			realIP.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// isProxied tells whether a request came through a trusted proxy.
+func isProxied(r *http.Request) bool {
+	proxied, _ := r.Context().Value(proxiedKey).(bool)
+	return proxied
+}
+
+func isTrustedProxy(trusted []*net.IPNet, address string) bool {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+
+	ip := net.ParseIP(address)
+
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
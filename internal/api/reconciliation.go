@@ -0,0 +1,276 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/currency"
+	"github.com/lembata/para/pkg/database"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+// the most transactions an open reconciliation lists
+const maxReconciliationTransactions = 1000
+
+type ReconciliationService struct {
+}
+
+type ReconciliationData struct {
+	AccountId        int     `json:"accountId"`
+	StatementDate    string  `json:"statementDate"`
+	StatementBalance float64 `json:"statementBalance"`
+	// Source is manual or the statement format the balance was read from.
+	Source string `json:"source"`
+}
+
+type ReconciliationListRequest struct {
+	AccountId int `json:"accountId"`
+}
+
+func (d *ReconciliationData) toEntity() (entities.ReconciliationEntity, string) {
+	if d.AccountId == 0 {
+		return entities.ReconciliationEntity{}, "an account is required"
+	}
+
+	if _, err := time.Parse(recurrence.DateLayout, d.StatementDate); err != nil {
+		return entities.ReconciliationEntity{}, "statement date is invalid"
+	}
+
+	switch d.Source {
+	case "":
+		d.Source = entities.StatementSourceManual
+	case entities.StatementSourceManual, entities.StatementSourceCamt,
+		entities.StatementSourceMT940, entities.StatementSourceOFX:
+	default:
+		return entities.ReconciliationEntity{}, "invalid statement source"
+	}
+
+	return entities.ReconciliationEntity{
+		AccountId:        int64(d.AccountId),
+		StatementDate:    d.StatementDate,
+		StatementBalance: currency.ToCoins(d.StatementBalance),
+		Source:           d.Source,
+	}, ""
+}
+
+// Start opens a reconciliation of an account against a statement, an account
+// has at most one open reconciliation.
+func (s *ReconciliationService) Start(w http.ResponseWriter, r *http.Request) {
+	var data ReconciliationData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Starting reconciliation: %v", data)
+
+	reconciliation, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	reconciliation.CreateAt = time.Now()
+	reconciliation.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		if _, err := db.GetOpenReconciliation(ctx, reconciliation.AccountId); err == nil {
+			return fmt.Errorf("account %d already has an open reconciliation", reconciliation.AccountId)
+		} else if err != database.ErrorNotFound {
+			return err
+		}
+
+		id, err = db.CreateReconciliation(ctx, reconciliation)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to start reconciliation: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+// GetReconciliation returns a reconciliation with the difference between the
+// statement and the cleared balance. Open reconciliations also list the
+// transactions up to the statement date that are not reconciled yet.
+func (s *ReconciliationService) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid reconciliation id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var row entities.ReconciliationRow
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		reconciliation, err := db.GetReconciliationById(ctx, int64(id))
+
+		if err != nil {
+			return err
+		}
+
+		if row, err = reconciliationRow(ctx, db, reconciliation); err != nil || row.Finished {
+			return err
+		}
+
+		row.Transactions, err = db.GetTransactions(ctx, entities.TransactionFilter{
+			AccountId: reconciliation.AccountId,
+			ToDate:    reconciliation.StatementDate,
+			Statuses:  []entities.TransactionStatus{entities.StatusUncleared, entities.StatusCleared},
+		}, 0, maxReconciliationTransactions, "t.date")
+
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get reconciliation: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, row)
+}
+
+// All returns the reconciliations of an account, the latest first.
+func (s *ReconciliationService) All(w http.ResponseWriter, r *http.Request) {
+	var request ReconciliationListRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	rows := []entities.ReconciliationRow{}
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		reconciliations, err := db.GetReconciliations(ctx, int64(request.AccountId))
+
+		if err != nil {
+			return err
+		}
+
+		for _, reconciliation := range reconciliations {
+			row, err := reconciliationRow(ctx, db, reconciliation)
+
+			if err != nil {
+				return err
+			}
+
+			rows = append(rows, row)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get reconciliations: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, rows)
+}
+
+// Finish locks the cleared transactions up to the statement date once the
+// cleared balance matches the statement.
+func (s *ReconciliationService) Finish(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid reconciliation id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		reconciliation, err := db.GetReconciliationById(ctx, int64(id))
+
+		if err != nil {
+			return err
+		}
+
+		row, err := reconciliationRow(ctx, db, reconciliation)
+
+		if err != nil {
+			return err
+		}
+
+		if row.Difference.Value != 0 {
+			return fmt.Errorf("statement and cleared balance differ by %.2f %s",
+				currency.FromCoins(row.Difference.Value), row.Difference.Currency)
+		}
+
+		return db.FinishReconciliation(ctx, reconciliation)
+	})
+
+	if err != nil {
+		logger.Errorf("failed to finish reconciliation: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// Cancel drops an open reconciliation, the cleared marks are kept.
+func (s *ReconciliationService) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid reconciliation id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.DeleteReconciliation(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to cancel reconciliation: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func reconciliationRow(ctx context.Context, db *database.Database, reconciliation entities.ReconciliationEntity) (entities.ReconciliationRow, error) {
+	cleared, err := db.GetClearedBalance(ctx, reconciliation.AccountId, reconciliation.StatementDate)
+
+	if err != nil {
+		return entities.ReconciliationRow{}, err
+	}
+
+	return entities.ReconciliationRow{
+		Id:               int(reconciliation.Id),
+		AccountId:        int(reconciliation.AccountId),
+		StatementDate:    reconciliation.StatementDate,
+		StatementBalance: entities.CurrencyValue{Currency: cleared.Currency, Value: reconciliation.StatementBalance},
+		ClearedBalance:   cleared,
+		Difference:       entities.CurrencyValue{Currency: cleared.Currency, Value: reconciliation.StatementBalance - cleared.Value},
+		Source:           reconciliation.Source,
+		Finished:         reconciliation.FinishedAt != nil,
+	}, nil
+}
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/internal/reports"
+	"github.com/lembata/para/internal/scheduler"
+	"github.com/lembata/para/internal/valuations"
+	"github.com/lembata/para/pkg/currency"
+	"github.com/lembata/para/pkg/database"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+const (
+	defaultForecastDays = 30
+	maxForecastDays     = 365
+	maxNetWorthPoints   = 1000
+)
+
+type ReportService struct {
+}
+
+type ForecastRequest struct {
+	Days int `json:"days"`
+	// Threshold is the balance below which an alert is raised, it defaults
+	// to zero and can be overridden per account in Thresholds.
+	Threshold  float64         `json:"threshold"`
+	Thresholds map[int]float64 `json:"thresholds"`
+}
+
+// TagTotalsRequest limits the tag totals to a date range, both ends are
+// optional, to the transactions matching Query and the saved view ViewId and
+// to a single tag when Tag is set.
+type TagTotalsRequest struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Query  string `json:"query"`
+	ViewId int    `json:"viewId"`
+	Tag    string `json:"tag"`
+}
+
+// NetWorthRequest asks for the net worth at the end of Date, today when it is
+// not set.
+type NetWorthRequest struct {
+	Date string `json:"date"`
+}
+
+// NetWorthSeriesRequest asks for the net worth between two dates, Interval
+// is daily, weekly or monthly.
+type NetWorthSeriesRequest struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Interval string `json:"interval"`
+}
+
+// Forecast projects the daily balance of every account for the next days
+// from the current balances, pending and future transactions and upcoming
+// scheduled transactions.
+func (s *ReportService) Forecast(w http.ResponseWriter, r *http.Request) {
+	var request ForecastRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.Days == 0 {
+		request.Days = defaultForecastDays
+	}
+
+	if request.Days < 0 || request.Days > maxForecastDays {
+		WriteFailure(w, "invalid number of days", http.StatusBadRequest)
+		return
+	}
+
+	today := time.Now()
+	from := today.Format(recurrence.DateLayout)
+	until := today.AddDate(0, 0, request.Days).Format(recurrence.DateLayout)
+
+	db := database.GetInstance()
+	var accounts []entities.AccountRow
+	var transactions []entities.TransactionEntity
+	var schedules []entities.ScheduledTransactionEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		if accounts, err = db.GetAccountBalances(ctx, from); err != nil {
+			return err
+		}
+
+		if transactions, err = db.GetExpectedTransactions(ctx, from, until); err != nil {
+			return err
+		}
+
+		schedules, err = db.GetScheduledTransactions(ctx)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get forecast data: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	thresholds := make(map[int]int, len(request.Thresholds))
+
+	for accountId, threshold := range request.Thresholds {
+		thresholds[accountId] = currency.ToCoins(threshold)
+	}
+
+	occurrences := scheduler.Upcoming(schedules, today, today.AddDate(0, 0, request.Days))
+	forecast := reports.Forecast(accounts, transactions, occurrences, today, request.Days,
+		currency.ToCoins(request.Threshold), thresholds)
+
+	_, _ = WriteData(w, forecast)
+}
+
+// TagTotals sums the income and expenses per tag and currency.
+func (s *ReportService) TagTotals(w http.ResponseWriter, r *http.Request) {
+	var request TagTotalsRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, date := range []string{request.From, request.To} {
+		if _, err := time.Parse(recurrence.DateLayout, date); date != "" && err != nil {
+			WriteFailure(w, "date is invalid", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tableRequest, msg := withView(r, TableRequest{Query: request.Query, ViewId: request.ViewId}, transactionsList)
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	filter, msg := transactionFilter(tableRequest)
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if request.From != "" {
+		filter.FromDate = request.From
+	}
+
+	if request.To != "" {
+		filter.ToDate = request.To
+	}
+	db := database.GetInstance()
+	var totals []entities.TagTotal
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		totals, err = db.GetTagTotals(ctx, filter)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get tag totals: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := make([]entities.TagTotal, 0, len(totals))
+
+	for _, total := range totals {
+		if request.Tag == "" || strings.EqualFold(total.Name, strings.TrimSpace(request.Tag)) {
+			result = append(result, total)
+		}
+	}
+
+	_, _ = WriteData(w, result)
+}
+
+// NetWorth sums the balances of the accounts included in the net worth per
+// currency.
+func (s *ReportService) NetWorth(w http.ResponseWriter, r *http.Request) {
+	var request NetWorthRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.Date == "" {
+		request.Date = time.Now().Format(recurrence.DateLayout)
+	} else if _, err := time.Parse(recurrence.DateLayout, request.Date); err != nil {
+		WriteFailure(w, "date is invalid", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var accounts []entities.AccountRow
+
+	var valuationList []entities.ValuationEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		if accounts, err = db.GetAccountBalances(ctx, request.Date); err != nil {
+			return err
+		}
+
+		valuationList, err = db.GetValuations(ctx, 0)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get net worth: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, reports.NetWorth(valuations.Apply(accounts, valuationList, request.Date)))
+}
+
+// NetWorthSeries returns the net worth every day, week or month from the
+// start date and on the end date. Asset valuations are interpolated between
+// the days they were recorded.
+func (s *ReportService) NetWorthSeries(w http.ResponseWriter, r *http.Request) {
+	var request NetWorthSeriesRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, errFrom := time.Parse(recurrence.DateLayout, request.From)
+	to, errTo := time.Parse(recurrence.DateLayout, request.To)
+
+	if errFrom != nil || errTo != nil || to.Before(from) {
+		WriteFailure(w, "date range is invalid", http.StatusBadRequest)
+		return
+	}
+
+	if request.Interval == "" {
+		request.Interval = "monthly"
+	}
+
+	frequency, err := recurrence.ParseFrequency(request.Interval)
+
+	if err != nil || frequency == recurrence.Yearly {
+		WriteFailure(w, "interval is invalid", http.StatusBadRequest)
+		return
+	}
+
+	rule := recurrence.Rule{Frequency: frequency, Interval: 1, Weekday: -1, Start: from}
+	_, dates := rule.Between(from, to)
+
+	if len(dates) == 0 || dates[len(dates)-1].Before(to) {
+		dates = append(dates, to)
+	}
+
+	if len(dates) > maxNetWorthPoints {
+		WriteFailure(w, "too many points, use a longer interval", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	points := make([]entities.NetWorthPoint, 0, len(dates))
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		valuationList, err := db.GetValuations(ctx, 0)
+
+		if err != nil {
+			return err
+		}
+
+		for _, date := range dates {
+			day := date.Format(recurrence.DateLayout)
+			accounts, err := db.GetAccountBalances(ctx, day)
+
+			if err != nil {
+				return err
+			}
+
+			points = append(points, entities.NetWorthPoint{
+				Date:   day,
+				Totals: reports.NetWorth(valuations.Apply(accounts, valuationList, day)),
+			})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get net worth series: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, points)
+}
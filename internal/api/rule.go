@@ -0,0 +1,372 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/internal/rules"
+	"github.com/lembata/para/pkg/currency"
+	"github.com/lembata/para/pkg/database"
+)
+
+type RuleService struct {
+}
+
+type RuleData struct {
+	Id       int    `json:"id"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+	// Enabled defaults to true.
+	Enabled        *bool                `json:"enabled"`
+	StopProcessing bool                 `json:"stopProcessing"`
+	Conditions     RuleConditionsData   `json:"conditions"`
+	Actions        entities.RuleActions `json:"actions"`
+	Version        int                  `json:"version"`
+}
+
+type RuleConditionsData struct {
+	PayeeId   int      `json:"payeeId"`
+	PayeeName string   `json:"payeeName"`
+	Memo      string   `json:"memo"`
+	MemoRegex string   `json:"memoRegex"`
+	MinAmount *float64 `json:"minAmount"`
+	MaxAmount *float64 `json:"maxAmount"`
+	AccountId int      `json:"accountId"`
+	// Direction is one of income, expense or transfer.
+	Direction string `json:"direction"`
+}
+
+// RuleRunRequest selects the transactions to run the rules on with the
+// transaction filters. When RuleId is set only that rule is run, even if it
+// is disabled.
+type RuleRunRequest struct {
+	TableRequest
+	RuleId int `json:"ruleId"`
+}
+
+func (d *RuleData) toEntity() (entities.RuleEntity, string) {
+	rule := entities.RuleEntity{
+		Id:             int64(d.Id),
+		Name:           d.Name,
+		Priority:       d.Priority,
+		Enabled:        d.Enabled == nil || *d.Enabled,
+		StopProcessing: d.StopProcessing,
+		Conditions: entities.RuleConditions{
+			PayeeId:   int64(d.Conditions.PayeeId),
+			PayeeName: d.Conditions.PayeeName,
+			Memo:      d.Conditions.Memo,
+			MemoRegex: d.Conditions.MemoRegex,
+			AccountId: int64(d.Conditions.AccountId),
+			Direction: d.Conditions.Direction,
+		},
+		Actions: d.Actions,
+		Version: d.Version,
+	}
+
+	if d.Conditions.MinAmount != nil {
+		minAmount := currency.ToCoins(*d.Conditions.MinAmount)
+		rule.Conditions.MinAmount = &minAmount
+	}
+
+	if d.Conditions.MaxAmount != nil {
+		maxAmount := currency.ToCoins(*d.Conditions.MaxAmount)
+		rule.Conditions.MaxAmount = &maxAmount
+	}
+
+	if err := rules.Validate(rule); err != nil {
+		return rule, err.Error()
+	}
+
+	return rule, ""
+}
+
+func (s *RuleService) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var data RuleData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Creating rule: %v", data)
+
+	rule, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	rule.CreateAt = time.Now()
+	rule.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		id, err = db.CreateRule(ctx, rule)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to create rule: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+func (s *RuleService) EditRule(w http.ResponseWriter, r *http.Request) {
+	var data RuleData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Editing rule: %v", data)
+
+	rule, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	rule.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var current RuleData
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		_, err := db.EditRule(ctx, rule)
+
+		if err == database.ErrorConflict {
+			var existing entities.RuleEntity
+
+			if existing, err = db.GetRuleById(ctx, rule.Id); err == nil {
+				current = ruleData(existing)
+				return database.ErrorConflict
+			}
+		}
+
+		return err
+	})
+
+	if err == database.ErrorConflict {
+		WriteConflict(w, current)
+		return
+	}
+
+	if err != nil {
+		logger.Errorf("failed to edit rule: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *RuleService) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid rule id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.DeleteRule(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete rule: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *RuleService) GetRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid rule id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var rule entities.RuleEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		rule, err = db.GetRuleById(ctx, int64(id))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get rule: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, ruleData(rule))
+}
+
+// All returns every rule in the order they are evaluated.
+func (s *RuleService) All(w http.ResponseWriter, r *http.Request) {
+	db := database.GetInstance()
+	var ruleList []entities.RuleEntity
+	var err error
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		ruleList, err = db.GetRules(ctx, false)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get rules: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data := make([]RuleData, 0, len(ruleList))
+
+	for _, rule := range ruleList {
+		data = append(data, ruleData(rule))
+	}
+
+	_, _ = WriteData(w, data)
+}
+
+// DryRun shows what the rules would change on existing transactions without
+// changing them.
+func (s *RuleService) DryRun(w http.ResponseWriter, r *http.Request) {
+	s.run(w, r, false)
+}
+
+// Apply runs the rules on existing transactions and returns what changed.
+func (s *RuleService) Apply(w http.ResponseWriter, r *http.Request) {
+	s.run(w, r, true)
+}
+
+func (s *RuleService) run(w http.ResponseWriter, r *http.Request, apply bool) {
+	var request RuleRunRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = request.Validate(); err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, msg := transactionFilter(request.TableRequest)
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	// reconciled transactions are locked
+	filter.Statuses = []entities.TransactionStatus{entities.StatusUncleared, entities.StatusCleared}
+
+	db := database.GetInstance()
+	results := []entities.RuleResult{}
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		var engine *rules.Engine
+
+		if request.RuleId != 0 {
+			rule, err := db.GetRuleById(ctx, int64(request.RuleId))
+
+			if err != nil {
+				return err
+			}
+
+			engine, err = rules.New(db, []entities.RuleEntity{rule})
+
+			if err != nil {
+				return err
+			}
+		} else if engine, err = rules.Load(ctx, db); err != nil {
+			return err
+		}
+
+		transactions, err := db.GetTransactions(ctx, filter,
+			uint64(request.Offset), uint64(request.Limit), "t.date")
+
+		if err != nil {
+			return err
+		}
+
+		for _, transaction := range transactions {
+			result, err := engine.Run(ctx, int64(transaction.Id), apply)
+
+			if err != nil {
+				return err
+			}
+
+			if len(result.Changes) > 0 {
+				results = append(results, result)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logger.Errorf("failed to run rules: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, results)
+}
+
+func ruleData(rule entities.RuleEntity) RuleData {
+	enabled := rule.Enabled
+
+	data := RuleData{
+		Id:             int(rule.Id),
+		Name:           rule.Name,
+		Priority:       rule.Priority,
+		Enabled:        &enabled,
+		StopProcessing: rule.StopProcessing,
+		Conditions: RuleConditionsData{
+			PayeeId:   int(rule.Conditions.PayeeId),
+			PayeeName: rule.Conditions.PayeeName,
+			Memo:      rule.Conditions.Memo,
+			MemoRegex: rule.Conditions.MemoRegex,
+			AccountId: int(rule.Conditions.AccountId),
+			Direction: rule.Conditions.Direction,
+		},
+		Actions: rule.Actions,
+		Version: rule.Version,
+	}
+
+	if rule.Conditions.MinAmount != nil {
+		minAmount := currency.FromCoins(*rule.Conditions.MinAmount)
+		data.Conditions.MinAmount = &minAmount
+	}
+
+	if rule.Conditions.MaxAmount != nil {
+		maxAmount := currency.FromCoins(*rule.Conditions.MaxAmount)
+		data.Conditions.MaxAmount = &maxAmount
+	}
+
+	return data
+}
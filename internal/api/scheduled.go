@@ -0,0 +1,369 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/internal/loans"
+	"github.com/lembata/para/internal/scheduler"
+	"github.com/lembata/para/pkg/currency"
+	"github.com/lembata/para/pkg/database"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+const (
+	defaultUpcomingDays = 30
+	maxUpcomingDays     = 366
+)
+
+type ScheduledService struct {
+	scheduler *scheduler.Scheduler
+}
+
+type ScheduledTransactionData struct {
+	Id            int     `json:"id"`
+	Name          string  `json:"name"`
+	FromAccountId int     `json:"fromAccountId"`
+	ToAccountId   int     `json:"toAccountId"`
+	Amount        float64 `json:"amount"`
+	CategoryId    int     `json:"categoryId"`
+	Frequency     string  `json:"frequency"`
+	Interval      int     `json:"interval"`
+	// Weekday pins weekly transactions to a day of the week, 0 being Sunday.
+	Weekday *int `json:"weekday"`
+	// DayOfMonth pins monthly and yearly transactions to a day of the month.
+	DayOfMonth     int    `json:"dayOfMonth"`
+	StartDate      string `json:"startDate"`
+	EndDate        string `json:"endDate"`
+	MaxOccurrences int    `json:"maxOccurrences"`
+	Occurrences    int    `json:"occurrences"`
+	// Pending transactions have to be confirmed before they count.
+	Pending bool `json:"pending"`
+	Version int  `json:"version"`
+}
+
+func (d *ScheduledTransactionData) toEntity() (entities.ScheduledTransactionEntity, string) {
+	if d.Name == "" {
+		return entities.ScheduledTransactionEntity{}, "name is required"
+	}
+
+	if d.FromAccountId == 0 && d.ToAccountId == 0 {
+		return entities.ScheduledTransactionEntity{}, "an account is required"
+	}
+
+	if d.Amount <= 0 {
+		return entities.ScheduledTransactionEntity{}, "amount must be positive"
+	}
+
+	frequency, err := recurrence.ParseFrequency(d.Frequency)
+
+	if err != nil {
+		return entities.ScheduledTransactionEntity{}, err.Error()
+	}
+
+	scheduled := entities.ScheduledTransactionEntity{
+		Id:             int64(d.Id),
+		Name:           d.Name,
+		FromAccountId:  optionalId(d.FromAccountId),
+		ToAccountId:    optionalId(d.ToAccountId),
+		Amount:         currency.ToCoins(d.Amount),
+		CategoryId:     optionalId(d.CategoryId),
+		Frequency:      int(frequency),
+		Interval:       max(d.Interval, 1),
+		Weekday:        -1,
+		DayOfMonth:     d.DayOfMonth,
+		StartDate:      d.StartDate,
+		EndDate:        d.EndDate,
+		MaxOccurrences: d.MaxOccurrences,
+		Pending:        d.Pending,
+		Version:        d.Version,
+	}
+
+	if d.Weekday != nil {
+		scheduled.Weekday = *d.Weekday
+	}
+
+	if _, err := scheduler.Rule(scheduled); err != nil {
+		return scheduled, err.Error()
+	}
+
+	return scheduled, ""
+}
+
+func (s *ScheduledService) CreateScheduled(w http.ResponseWriter, r *http.Request) {
+	var data ScheduledTransactionData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Creating scheduled transaction: %v", data)
+
+	scheduled, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	scheduled.CreateAt = time.Now()
+	scheduled.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		id, err = db.CreateScheduledTransaction(ctx, scheduled)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to create scheduled transaction: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.scheduler.Trigger()
+	_, _ = WriteData(w, id)
+}
+
+func (s *ScheduledService) EditScheduled(w http.ResponseWriter, r *http.Request) {
+	var data ScheduledTransactionData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Editing scheduled transaction: %v", data)
+
+	scheduled, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	scheduled.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var current entities.ScheduledTransactionEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		existing, err := db.GetScheduledTransactionById(ctx, scheduled.Id)
+
+		if err != nil {
+			return err
+		}
+
+		if scheduled, err = scheduler.Reschedule(existing, scheduled); err != nil {
+			return err
+		}
+
+		_, err = db.EditScheduledTransaction(ctx, scheduled)
+
+		if err == database.ErrorConflict {
+			if current, err = db.GetScheduledTransactionById(ctx, scheduled.Id); err == nil {
+				return database.ErrorConflict
+			}
+		}
+
+		return err
+	})
+
+	if err == database.ErrorConflict {
+		WriteConflict(w, current)
+		return
+	}
+
+	if err != nil {
+		logger.Errorf("failed to edit scheduled transaction: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.scheduler.Trigger()
+	_, _ = WriteSuccess(w)
+}
+
+func (s *ScheduledService) DeleteScheduled(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid scheduled transaction id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.DeleteScheduledTransaction(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete scheduled transaction: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *ScheduledService) All(w http.ResponseWriter, r *http.Request) {
+	db := database.GetInstance()
+	var schedules []entities.ScheduledTransactionEntity
+	var err error
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		schedules, err = db.GetScheduledTransactions(ctx)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get scheduled transactions: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data := make([]ScheduledTransactionData, 0, len(schedules))
+
+	for _, scheduled := range schedules {
+		weekday := scheduled.Weekday
+
+		row := ScheduledTransactionData{
+			Id:             int(scheduled.Id),
+			Name:           scheduled.Name,
+			FromAccountId:  idValue(scheduled.FromAccountId),
+			ToAccountId:    idValue(scheduled.ToAccountId),
+			Amount:         currency.FromCoins(scheduled.Amount),
+			CategoryId:     idValue(scheduled.CategoryId),
+			Frequency:      recurrence.Frequency(scheduled.Frequency).String(),
+			Interval:       scheduled.Interval,
+			DayOfMonth:     scheduled.DayOfMonth,
+			StartDate:      scheduled.StartDate,
+			EndDate:        scheduled.EndDate,
+			MaxOccurrences: scheduled.MaxOccurrences,
+			Occurrences:    scheduled.Occurrences,
+			Pending:        scheduled.Pending,
+		}
+
+		if weekday >= 0 {
+			row.Weekday = &weekday
+		}
+
+		data = append(data, row)
+	}
+
+	_, _ = WriteData(w, data)
+}
+
+// Upcoming lists the occurrences of the next days that have not been turned
+// into transactions yet. The number of days is read from the days query
+// parameter.
+func (s *ScheduledService) Upcoming(w http.ResponseWriter, r *http.Request) {
+	days := defaultUpcomingDays
+
+	if r.URL.Query().Has("days") {
+		var err error
+
+		if days, err = strconv.Atoi(r.URL.Query().Get("days")); err != nil || days < 1 || days > maxUpcomingDays {
+			WriteFailure(w, "invalid number of days", http.StatusBadRequest)
+			return
+		}
+	}
+
+	db := database.GetInstance()
+	var schedules []entities.ScheduledTransactionEntity
+	var err error
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		schedules, err = db.GetScheduledTransactions(ctx)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get scheduled transactions: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	today := time.Now()
+	_, _ = WriteData(w, scheduler.Upcoming(schedules, today, today.AddDate(0, 0, days)))
+}
+
+func (s *ScheduledService) Pending(w http.ResponseWriter, r *http.Request) {
+	db := database.GetInstance()
+	var transactions []entities.TransactionEntity
+	var err error
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		transactions, err = db.GetPendingTransactions(ctx)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get pending transactions: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, transactions)
+}
+
+func (s *ScheduledService) Confirm(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid transaction id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		if err := db.ConfirmTransaction(ctx, int64(id)); err != nil {
+			return err
+		}
+
+		return loans.RecordPayment(ctx, db, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to confirm transaction: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *ScheduledService) Reject(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid transaction id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.DeletePendingTransaction(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to reject transaction: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/database"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 100
+)
+
+type SearchService struct {
+}
+
+// SearchRequest searches the text of transactions, items, payees and tags
+// for Query. Limit is the number of hits returned of each.
+type SearchRequest struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+// Search returns the records matching the query grouped by entity type, the
+// best first. Words match as prefixes and text in double quotes as a phrase.
+func (s *SearchService) Search(w http.ResponseWriter, r *http.Request) {
+	var request SearchRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(request.Query) == "" {
+		WriteFailure(w, "query is required", http.StatusBadRequest)
+		return
+	}
+
+	if request.Limit < 0 || request.Limit > maxSearchLimit {
+		WriteFailure(w, "limit is invalid", http.StatusBadRequest)
+		return
+	}
+
+	if request.Limit == 0 {
+		request.Limit = defaultSearchLimit
+	}
+
+	db := database.GetInstance()
+	var results entities.SearchResults
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		results, err = db.Search(ctx, request.Query, uint64(request.Limit))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to search: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, results)
+}
@@ -1,6 +1,9 @@
 package api
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 const MAX_PAGE_SIZE = 500
 
@@ -9,12 +12,19 @@ var (
 	TableRequestPageTooLarge = errors.New("Page size too large")
 )
 
+// TableRequest asks for a page of a list. Query is a filter expression, the
+// lists that support one narrow Filters down with it. ViewId names a saved
+// view of the list the request builds on.
 type TableRequest struct {
 	Offset  int               `json:"offset"`
 	Limit   int               `json:"limit"`
 	Filters map[string]string `json:"filters"`
+	Query   string            `json:"query"`
 	OrderBy string            `json:"orderBy"`
 	Order   string            `json:"order"`
+	ViewId  int               `json:"viewId"`
+	// viewQuery is the query of the view, it has to match as well.
+	viewQuery string
 }
 
 func (t *TableRequest) Validate() error {
@@ -29,3 +39,19 @@ func (t *TableRequest) Validate() error {
 
 	return nil
 }
+
+// OrderClause maps the requested column to one of the sortable columns,
+// falling back to fallback for unknown columns.
+func (t *TableRequest) OrderClause(columns map[string]string, fallback string) string {
+	column, ok := columns[t.OrderBy]
+
+	if !ok {
+		return fallback
+	}
+
+	if strings.EqualFold(t.Order, "desc") {
+		return column + " desc"
+	}
+
+	return column + " asc"
+}
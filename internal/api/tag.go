@@ -0,0 +1,200 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/database"
+)
+
+const maxTagLength = 255
+
+type TagService struct {
+}
+
+type TagData struct {
+	Id      int    `json:"id"`
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+func (d *TagData) toEntity() (entities.TagEntity, string) {
+	name := strings.TrimSpace(d.Name)
+
+	if msg := validateTag(name); msg != "" {
+		return entities.TagEntity{}, msg
+	}
+
+	return entities.TagEntity{
+		Id:      int64(d.Id),
+		Name:    name,
+		Version: d.Version,
+	}, ""
+}
+
+func (s *TagService) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var data TagData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Creating tag: %v", data)
+
+	tag, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	tag.CreateAt = time.Now()
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		id, err = db.CreateTag(ctx, tag)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to create tag: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+// EditTag renames a tag, the transactions and items keep it.
+func (s *TagService) EditTag(w http.ResponseWriter, r *http.Request) {
+	var data TagData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Editing tag: %v", data)
+
+	tag, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var current TagData
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		_, err := db.EditTag(ctx, tag)
+
+		if err == database.ErrorConflict {
+			var existing entities.TagEntity
+
+			if existing, err = db.GetTagById(ctx, tag.Id); err == nil {
+				current = TagData{Id: int(existing.Id), Name: existing.Name, Version: existing.Version}
+				return database.ErrorConflict
+			}
+		}
+
+		return err
+	})
+
+	if err == database.ErrorConflict {
+		WriteConflict(w, current)
+		return
+	}
+
+	if err != nil {
+		logger.Errorf("failed to edit tag: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *TagService) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid tag id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.DeleteTag(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete tag: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *TagService) All(w http.ResponseWriter, r *http.Request) {
+	db := database.GetInstance()
+	var tags []entities.TagRow
+	var err error
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		tags, err = db.GetTags(ctx)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get tags: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if tags == nil {
+		tags = []entities.TagRow{}
+	}
+
+	_, _ = WriteData(w, tags)
+}
+
+func validateTags(tags []string) string {
+	for _, tag := range tags {
+		if msg := validateTag(strings.TrimSpace(tag)); msg != "" {
+			return msg
+		}
+	}
+
+	return ""
+}
+
+func validateTag(name string) string {
+	if name == "" {
+		return "tag name is required"
+	}
+
+	if len(name) > maxTagLength {
+		return "tag name is too long"
+	}
+
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "tag name contains invalid characters"
+	}
+
+	return ""
+}
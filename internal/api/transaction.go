@@ -0,0 +1,674 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/classifier"
+	"github.com/lembata/para/internal/duplicates"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/internal/loans"
+	"github.com/lembata/para/internal/rules"
+	"github.com/lembata/para/pkg/currency"
+	"github.com/lembata/para/pkg/database"
+	"github.com/lembata/para/pkg/filter"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+var transactionOrderColumns = map[string]string{
+	"id":     "t.id",
+	"date":   "t.date",
+	"amount": "t.total_amount",
+	"payee":  "p.name",
+}
+
+const (
+	defaultSuggestionLimit = 3
+	maxSuggestionLimit     = 20
+)
+
+type TransactionService struct {
+	classifier *classifier.Classifier
+}
+
+type TransactionData struct {
+	Id            int     `json:"id"`
+	FromAccountId int     `json:"fromAccountId"`
+	ToAccountId   int     `json:"toAccountId"`
+	Amount        float64 `json:"amount"`
+	Date          string  `json:"date"`
+	PayeeId       int     `json:"payeeId"`
+	// PayeeName links the transaction to the payee of that name, creating
+	// it when needed. It is only used when PayeeId is not set.
+	PayeeName   string `json:"payeeName"`
+	Description string `json:"description"`
+	Notes       string `json:"notes"`
+	Pending     bool   `json:"pending"`
+	// Status is set by reconciliation, it is ignored when saving.
+	Status  entities.TransactionStatus `json:"status"`
+	Tags    []string                   `json:"tags"`
+	Items   []ItemData                 `json:"items"`
+	Version int                        `json:"version"`
+}
+
+type TransactionStatusRequest struct {
+	Ids    []int                      `json:"ids"`
+	Status entities.TransactionStatus `json:"status"`
+}
+
+type SuggestionRequest struct {
+	PayeeId     int     `json:"payeeId"`
+	PayeeName   string  `json:"payeeName"`
+	Description string  `json:"description"`
+	ItemName    string  `json:"itemName"`
+	Amount      float64 `json:"amount"`
+	Limit       int     `json:"limit"`
+}
+
+type ItemData struct {
+	Id         int      `json:"id"`
+	Name       string   `json:"name"`
+	Price      float64  `json:"price"`
+	CategoryId int      `json:"categoryId"`
+	Tags       []string `json:"tags"`
+}
+
+func (d *TransactionData) toEntity() (entities.TransactionEntity, string) {
+	if d.FromAccountId == 0 && d.ToAccountId == 0 {
+		return entities.TransactionEntity{}, "an account is required"
+	}
+
+	if d.FromAccountId == d.ToAccountId {
+		return entities.TransactionEntity{}, "source and destination account are the same"
+	}
+
+	if d.Amount <= 0 {
+		return entities.TransactionEntity{}, "amount must be positive"
+	}
+
+	if _, err := time.Parse(recurrence.DateLayout, d.Date); err != nil {
+		return entities.TransactionEntity{}, "date is invalid"
+	}
+
+	if msg := validateTags(d.Tags); msg != "" {
+		return entities.TransactionEntity{}, msg
+	}
+
+	for _, item := range d.Items {
+		if item.Name == "" {
+			return entities.TransactionEntity{}, "item name is required"
+		}
+
+		if msg := validateTags(item.Tags); msg != "" {
+			return entities.TransactionEntity{}, msg
+		}
+	}
+
+	return entities.TransactionEntity{
+		Id:            int64(d.Id),
+		FromAccountId: optionalId(d.FromAccountId),
+		ToAccountId:   optionalId(d.ToAccountId),
+		TotalAmount:   currency.ToCoins(d.Amount),
+		Date:          d.Date,
+		PayeeId:       optionalId(d.PayeeId),
+		Description:   d.Description,
+		Notes:         d.Notes,
+		Version:       d.Version,
+	}, ""
+}
+
+func (s *TransactionService) CreateTransaction(w http.ResponseWriter, r *http.Request) {
+	var data TransactionData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Creating transaction: %v", data)
+
+	transaction, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	transaction.Pending = data.Pending
+	transaction.CreateAt = time.Now()
+	transaction.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		defaultCategoryId, err := resolvePayee(ctx, db, &transaction, data.PayeeName)
+
+		if err != nil {
+			return err
+		}
+
+		if id, err = db.CreateTransaction(ctx, transaction); err != nil {
+			return err
+		}
+
+		if err := createItems(ctx, db, id, data.Items, defaultCategoryId); err != nil {
+			return err
+		}
+
+		if err := db.AddTransactionTags(ctx, id, data.Tags); err != nil {
+			return err
+		}
+
+		if err := rules.Apply(ctx, db, id); err != nil {
+			return err
+		}
+
+		if err := duplicates.Check(ctx, db, id); err != nil {
+			return err
+		}
+
+		return loans.RecordPayment(ctx, db, id)
+	})
+
+	if err != nil {
+		logger.Errorf("failed to create transaction: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+// EditTransaction updates a transaction and replaces its items.
+func (s *TransactionService) EditTransaction(w http.ResponseWriter, r *http.Request) {
+	var data TransactionData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Editing transaction: %v", data)
+
+	transaction, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	transaction.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var current TransactionData
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		defaultCategoryId, err := resolvePayee(ctx, db, &transaction, data.PayeeName)
+
+		if err != nil {
+			return err
+		}
+
+		if err := db.CheckTransactionUnlocked(ctx, transaction.Id); err != nil {
+			return err
+		}
+
+		if _, err := db.EditTransaction(ctx, transaction); err == database.ErrorConflict {
+			if current, err = getTransactionData(ctx, db, transaction.Id); err == nil {
+				return database.ErrorConflict
+			}
+
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		if err := db.DeleteItems(ctx, transaction.Id); err != nil {
+			return err
+		}
+
+		if err := db.SetTransactionTags(ctx, transaction.Id, data.Tags); err != nil {
+			return err
+		}
+
+		if err := createItems(ctx, db, transaction.Id, data.Items, defaultCategoryId); err != nil {
+			return err
+		}
+
+		return loans.RecordPayment(ctx, db, transaction.Id)
+	})
+
+	if err == database.ErrorConflict {
+		WriteConflict(w, current)
+		return
+	}
+
+	if err != nil {
+		logger.Errorf("failed to edit transaction: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *TransactionService) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid transaction id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		if err := db.CheckTransactionUnlocked(ctx, int64(id)); err != nil {
+			return err
+		}
+
+		return db.DeleteTransaction(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete transaction: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *TransactionService) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid transaction id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var data TransactionData
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		data, err = getTransactionData(ctx, db, int64(id))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get transaction: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, data)
+}
+
+// All returns a page of transactions. The accountId and payeeId filters
+// restrict the list to one account or payee, from and to to a date range and
+// tag to the transactions tagged with it or with one of their items.
+func (s *TransactionService) All(w http.ResponseWriter, r *http.Request) {
+	var tableRequest TableRequest
+	err := json.NewDecoder(r.Body).Decode(&tableRequest)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = tableRequest.Validate(); err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tableRequest, msg := withView(r, tableRequest, transactionsList)
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	filter, msg := transactionFilter(tableRequest)
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var transactions []entities.TransactionRow
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		transactions, err = db.GetTransactions(ctx, filter,
+			uint64(tableRequest.Offset), uint64(tableRequest.Limit),
+			tableRequest.OrderClause(transactionOrderColumns, "t.date desc"))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get transactions: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, transactions)
+}
+
+// SetStatus marks transactions as uncleared or cleared. Reconciled
+// transactions are locked and have to be unlocked first.
+func (s *TransactionService) SetStatus(w http.ResponseWriter, r *http.Request) {
+	var request TransactionStatusRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.Status == entities.StatusReconciled {
+		WriteFailure(w, "transactions are reconciled by finishing a reconciliation", http.StatusBadRequest)
+		return
+	}
+
+	seen := make(map[int64]bool, len(request.Ids))
+	var ids []int64
+
+	for _, id := range request.Ids {
+		if !seen[int64(id)] {
+			seen[int64(id)] = true
+			ids = append(ids, int64(id))
+		}
+	}
+
+	if len(ids) == 0 {
+		WriteFailure(w, "no transactions given", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.SetTransactionStatus(ctx, ids, request.Status)
+	})
+
+	if err != nil {
+		logger.Errorf("failed to set transaction status: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// Unlock makes a reconciled transaction editable again, it stays cleared.
+func (s *TransactionService) Unlock(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid transaction id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.UnlockTransaction(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to unlock transaction: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// SuggestCategory suggests categories for a new transaction or item based on
+// the categories of past items.
+func (s *TransactionService) SuggestCategory(w http.ResponseWriter, r *http.Request) {
+	var request SuggestionRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.Limit == 0 {
+		request.Limit = defaultSuggestionLimit
+	}
+
+	if request.Limit < 0 || request.Limit > maxSuggestionLimit {
+		WriteFailure(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var suggestions []entities.CategorySuggestion
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		input := classifier.Input{
+			PayeeName:   request.PayeeName,
+			Description: request.Description,
+			ItemName:    request.ItemName,
+			Amount:      currency.ToCoins(request.Amount),
+		}
+
+		if request.PayeeId != 0 {
+			payee, err := db.GetPayeeById(ctx, int64(request.PayeeId))
+
+			if err != nil {
+				return err
+			}
+
+			input.PayeeName = payee.Name
+		}
+
+		suggestions, err = s.classifier.Suggest(ctx, input, request.Limit)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to suggest categories: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if suggestions == nil {
+		suggestions = []entities.CategorySuggestion{}
+	}
+
+	_, _ = WriteData(w, suggestions)
+}
+
+// transactionFilter reads the transaction filters of a table request.
+func transactionFilter(tableRequest TableRequest) (entities.TransactionFilter, string) {
+	var msg string
+	filter := entities.TransactionFilter{
+		FromDate: tableRequest.Filters["from"],
+		ToDate:   tableRequest.Filters["to"],
+		Tag:      tableRequest.Filters["tag"],
+	}
+
+	var err error
+
+	if value, ok := tableRequest.Filters["accountId"]; ok {
+		if filter.AccountId, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return filter, "invalid account id"
+		}
+	}
+
+	if value, ok := tableRequest.Filters["payeeId"]; ok {
+		if filter.PayeeId, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return filter, "invalid payee id"
+		}
+	}
+
+	if value, ok := tableRequest.Filters["status"]; ok {
+		status, err := entities.ParseTransactionStatus(value)
+
+		if err != nil {
+			return filter, err.Error()
+		}
+
+		filter.Statuses = []entities.TransactionStatus{status}
+	}
+
+	if filter.Query, msg = transactionQuery(tableRequest.viewQuery, tableRequest.Query); msg != "" {
+		return filter, msg
+	}
+
+	return filter, ""
+}
+
+// transactionQuery parses filter expressions on transactions that all have
+// to match, empty ones match every transaction.
+func transactionQuery(texts ...string) (filter.Expr, string) {
+	var exprs []filter.Expr
+
+	for _, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		expr, err := filter.Parse(text, database.TransactionFields)
+
+		if err != nil {
+			return nil, "invalid query: " + err.Error()
+		}
+
+		exprs = append(exprs, expr)
+	}
+
+	switch len(exprs) {
+	case 0:
+		return nil, ""
+	case 1:
+		return exprs[0], ""
+	}
+
+	return filter.And(exprs...), ""
+}
+
+// resolvePayee links the transaction to the payee named payeeName when no
+// payee id was given and returns the default category of its payee.
+func resolvePayee(ctx context.Context, db *database.Database, transaction *entities.TransactionEntity, payeeName string) (*int64, error) {
+	if transaction.PayeeId == nil && payeeName != "" {
+		payeeId, err := db.FindOrCreatePayee(ctx, payeeName, "")
+
+		if err != nil {
+			return nil, err
+		}
+
+		transaction.PayeeId = &payeeId
+	}
+
+	if transaction.PayeeId == nil {
+		return nil, nil
+	}
+
+	payee, err := db.GetPayeeById(ctx, *transaction.PayeeId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return payee.DefaultCategoryId, nil
+}
+
+// createItems stores the items of a transaction, items without a category get
+// defaultCategoryId.
+func createItems(ctx context.Context, db *database.Database, transactionId int64, items []ItemData, defaultCategoryId *int64) error {
+	for _, item := range items {
+		categoryId := optionalId(item.CategoryId)
+
+		if categoryId == nil {
+			categoryId = defaultCategoryId
+		}
+
+		itemId, err := db.CreateItem(ctx, entities.ItemEntity{
+			Name:          item.Name,
+			Price:         currency.ToCoins(item.Price),
+			TransactionId: transactionId,
+			CategoryId:    categoryId,
+			CreateAt:      time.Now(),
+			UpdateAt:      time.Now(),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if err := db.AddItemTags(ctx, itemId, item.Tags); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getTransactionData loads a transaction with its items and tags.
+func getTransactionData(ctx context.Context, db *database.Database, id int64) (TransactionData, error) {
+	transaction, err := db.GetTransactionById(ctx, id)
+
+	if err != nil {
+		return TransactionData{}, err
+	}
+
+	items, err := db.GetItems(ctx, id)
+
+	if err != nil {
+		return TransactionData{}, err
+	}
+
+	data := transactionData(transaction, items)
+
+	if data.Tags, err = db.GetTransactionTags(ctx, id); err != nil {
+		return TransactionData{}, err
+	}
+
+	for i := range data.Items {
+		if data.Items[i].Tags, err = db.GetItemTags(ctx, int64(data.Items[i].Id)); err != nil {
+			return TransactionData{}, err
+		}
+	}
+
+	return data, nil
+}
+
+func transactionData(transaction entities.TransactionEntity, items []entities.ItemEntity) TransactionData {
+	data := TransactionData{
+		Id:            int(transaction.Id),
+		FromAccountId: idValue(transaction.FromAccountId),
+		ToAccountId:   idValue(transaction.ToAccountId),
+		Amount:        currency.FromCoins(transaction.TotalAmount),
+		Date:          transaction.Date,
+		PayeeId:       idValue(transaction.PayeeId),
+		Description:   transaction.Description,
+		Notes:         transaction.Notes,
+		Pending:       transaction.Pending,
+		Status:        transaction.Status,
+		Items:         make([]ItemData, 0, len(items)),
+		Version:       transaction.Version,
+	}
+
+	for _, item := range items {
+		data.Items = append(data.Items, ItemData{
+			Id:         int(item.Id),
+			Name:       item.Name,
+			Price:      currency.FromCoins(item.Price),
+			CategoryId: idValue(item.CategoryId),
+		})
+	}
+
+	return data
+}
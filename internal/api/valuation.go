@@ -0,0 +1,339 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/internal/valuations"
+	"github.com/lembata/para/pkg/currency"
+	"github.com/lembata/para/pkg/database"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+type ValuationService struct {
+}
+
+type ValuationData struct {
+	Id        int     `json:"id"`
+	AccountId int     `json:"accountId"`
+	Date      string  `json:"date"`
+	Value     float64 `json:"value"`
+	Notes     string  `json:"notes"`
+	Version   int     `json:"version"`
+}
+
+type ValuationListRequest struct {
+	AccountId int `json:"accountId"`
+}
+
+// DepreciationRequest projects the value of a vehicle or other asset bought
+// for Value on Date. Save records the projected values as valuations of the
+// account.
+type DepreciationRequest struct {
+	AccountId int     `json:"accountId"`
+	Value     float64 `json:"value"`
+	Date      string  `json:"date"`
+	Method    string  `json:"method"`
+	LifeYears int     `json:"lifeYears"`
+	// Rate is the yearly loss in percent of the declining balance method.
+	Rate         float64 `json:"rate"`
+	SalvageValue float64 `json:"salvageValue"`
+	Save         bool    `json:"save"`
+}
+
+// DepreciationResult lists the projected values. When they are saved,
+// Skipped lists the dates that already had a valuation, which is kept.
+type DepreciationResult struct {
+	Valuations []entities.ValuationEntity `json:"valuations"`
+	Skipped    []string                   `json:"skipped"`
+}
+
+var errValuationAccount = errors.New("the account of a valuation can not change")
+
+func (d *ValuationData) toEntity() (entities.ValuationEntity, string) {
+	if d.AccountId == 0 {
+		return entities.ValuationEntity{}, "an account is required"
+	}
+
+	if _, err := time.Parse(recurrence.DateLayout, d.Date); err != nil {
+		return entities.ValuationEntity{}, "date is invalid"
+	}
+
+	return entities.ValuationEntity{
+		Id:        int64(d.Id),
+		AccountId: int64(d.AccountId),
+		Date:      d.Date,
+		Value:     currency.ToCoins(d.Value),
+		Notes:     d.Notes,
+		Version:   d.Version,
+	}, ""
+}
+
+// CreateValuation records what an asset account is worth on a day.
+func (s *ValuationService) CreateValuation(w http.ResponseWriter, r *http.Request) {
+	var data ValuationData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Creating valuation: %v", data)
+
+	valuation, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	valuation.CreateAt = time.Now()
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		if err := checkAssetAccount(ctx, db, valuation.AccountId); err != nil {
+			return err
+		}
+
+		id, err = db.CreateValuation(ctx, valuation)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to create valuation: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+func (s *ValuationService) EditValuation(w http.ResponseWriter, r *http.Request) {
+	var data ValuationData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Editing valuation: %v", data)
+
+	valuation, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var current entities.ValuationEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		existing, err := db.GetValuationById(ctx, valuation.Id)
+
+		if err != nil {
+			return err
+		}
+
+		if existing.AccountId != valuation.AccountId {
+			return errValuationAccount
+		}
+
+		if err := checkAssetAccount(ctx, db, valuation.AccountId); err != nil {
+			return err
+		}
+
+		_, err = db.EditValuation(ctx, valuation)
+
+		if err == database.ErrorConflict {
+			if current, err = db.GetValuationById(ctx, valuation.Id); err == nil {
+				return database.ErrorConflict
+			}
+		}
+
+		return err
+	})
+
+	if err == database.ErrorConflict {
+		WriteConflict(w, current)
+		return
+	}
+
+	if err != nil {
+		logger.Errorf("failed to edit valuation: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *ValuationService) DeleteValuation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid valuation id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.DeleteValuation(ctx, int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete valuation: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+// All returns the valuation history of an account.
+func (s *ValuationService) All(w http.ResponseWriter, r *http.Request) {
+	var request ValuationListRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.AccountId == 0 {
+		WriteFailure(w, "an account is required", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var valuationList []entities.ValuationEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		valuationList, err = db.GetValuations(ctx, int64(request.AccountId))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get valuations: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, valuationList)
+}
+
+// Depreciation projects the yearly value of an asset and optionally records
+// it as valuations. Saving keeps the valuations already recorded and skips
+// their dates.
+func (s *ValuationService) Depreciation(w http.ResponseWriter, r *http.Request) {
+	var request DepreciationRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	start, err := time.Parse(recurrence.DateLayout, request.Date)
+
+	if err != nil {
+		WriteFailure(w, "date is invalid", http.StatusBadRequest)
+		return
+	}
+
+	if request.Value <= 0 {
+		WriteFailure(w, "value must be positive", http.StatusBadRequest)
+		return
+	}
+
+	points, err := valuations.Depreciate(currency.ToCoins(request.Value), start, request.Method,
+		request.LifeYears, request.Rate, currency.ToCoins(request.SalvageValue))
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for i := range points {
+		points[i].AccountId = int64(request.AccountId)
+		points[i].Notes = "Depreciation"
+		points[i].CreateAt = time.Now()
+	}
+
+	result := DepreciationResult{Valuations: points, Skipped: []string{}}
+
+	if !request.Save {
+		_, _ = WriteData(w, result)
+		return
+	}
+
+	db := database.GetInstance()
+	result.Valuations = []entities.ValuationEntity{}
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		if err := checkAssetAccount(ctx, db, int64(request.AccountId)); err != nil {
+			return err
+		}
+
+		existing, err := db.GetValuations(ctx, int64(request.AccountId))
+
+		if err != nil {
+			return err
+		}
+
+		recorded := make(map[string]bool, len(existing))
+
+		for _, valuation := range existing {
+			recorded[valuation.Date] = true
+		}
+
+		for _, point := range points {
+			if recorded[point.Date] {
+				result.Skipped = append(result.Skipped, point.Date)
+				continue
+			}
+
+			if point.Id, err = db.CreateValuation(ctx, point); err != nil {
+				return err
+			}
+
+			result.Valuations = append(result.Valuations, point)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logger.Errorf("failed to save depreciation: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, result)
+}
+
+func checkAssetAccount(ctx context.Context, db *database.Database, accountId int64) error {
+	account, err := db.GetAccountById(ctx, accountId)
+
+	if err != nil {
+		return err
+	}
+
+	if account.Type != entities.AccountAsset {
+		return fmt.Errorf("account %d is not an asset account", accountId)
+	}
+
+	return nil
+}
@@ -0,0 +1,359 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/database"
+)
+
+var errViewList = errors.New("the list of a view can not change")
+
+const (
+	transactionsList = "transactions"
+	accountsList     = "accounts"
+	maxViewName      = 255
+)
+
+// viewList is a list views can be saved for, with the columns it can be
+// sorted by and the columns it can show, which are the fields of its rows.
+type viewList struct {
+	order   map[string]string
+	columns map[string]bool
+}
+
+var viewLists = map[string]viewList{
+	transactionsList: {order: transactionOrderColumns, columns: columnSet("id", "date", "fromAccountName",
+		"toAccountName", "payeeName", "description", "amount", "pending", "status", "tags")},
+	accountsList: {order: accountColumns, columns: columnSet("id", "name", "type", "includeInNetWorth",
+		"archived", "deleted", "groupId", "balance")},
+}
+
+func columnSet(columns ...string) map[string]bool {
+	set := make(map[string]bool, len(columns))
+
+	for _, column := range columns {
+		set[column] = true
+	}
+
+	return set
+}
+
+type ViewService struct {
+}
+
+// ViewData is a view of the current user. Filters, Query, OrderBy and Order
+// are those of a table request of the list, Columns the columns shown.
+type ViewData struct {
+	Id      int               `json:"id"`
+	List    string            `json:"list"`
+	Name    string            `json:"name"`
+	Filters map[string]string `json:"filters"`
+	Query   string            `json:"query"`
+	OrderBy string            `json:"orderBy"`
+	Order   string            `json:"order"`
+	Columns []string          `json:"columns"`
+	Version int               `json:"version"`
+}
+
+// ViewListRequest asks for the views of a list, of every list when List is
+// empty.
+type ViewListRequest struct {
+	List string `json:"list"`
+}
+
+func (d *ViewData) toEntity() (entities.ViewEntity, string) {
+	view := entities.ViewEntity{
+		Id:      int64(d.Id),
+		List:    d.List,
+		Name:    strings.TrimSpace(d.Name),
+		Filters: d.Filters,
+		Query:   strings.TrimSpace(d.Query),
+		OrderBy: d.OrderBy,
+		Order:   strings.ToLower(d.Order),
+		Columns: d.Columns,
+		Version: d.Version,
+	}
+
+	if view.Name == "" {
+		return view, "name is required"
+	}
+
+	if len(view.Name) > maxViewName {
+		return view, "name is too long"
+	}
+
+	list, ok := viewLists[view.List]
+
+	if !ok {
+		return view, "invalid list"
+	}
+
+	if _, ok := list.order[view.OrderBy]; view.OrderBy != "" && !ok {
+		return view, "invalid sort column"
+	}
+
+	shown := make(map[string]bool, len(view.Columns))
+
+	for _, column := range view.Columns {
+		if !list.columns[column] {
+			return view, "invalid column"
+		}
+
+		if shown[column] {
+			return view, "duplicate column"
+		}
+
+		shown[column] = true
+	}
+
+	if view.Order != "" && view.Order != "asc" && view.Order != "desc" {
+		return view, "invalid sort order"
+	}
+
+	switch view.List {
+	case transactionsList:
+		if _, msg := transactionFilter(TableRequest{Filters: view.Filters, Query: view.Query}); msg != "" {
+			return view, msg
+		}
+	case accountsList:
+		if view.Query != "" {
+			return view, "accounts can not be filtered with a query"
+		}
+	}
+
+	return view, ""
+}
+
+func (s *ViewService) CreateView(w http.ResponseWriter, r *http.Request) {
+	var data ViewData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Creating view: %v", data)
+
+	view, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	view.Id = 0
+	view.Owner = requestActor(r)
+	view.CreateAt = time.Now()
+	view.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var id int64
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		id, err = db.CreateView(ctx, view)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to create view: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, id)
+}
+
+func (s *ViewService) EditView(w http.ResponseWriter, r *http.Request) {
+	var data ViewData
+	err := json.NewDecoder(r.Body).Decode(&data)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debugf("Editing view: %v", data)
+
+	view, msg := data.toEntity()
+
+	if msg != "" {
+		WriteFailure(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	view.Owner = requestActor(r)
+	view.UpdateAt = time.Now()
+
+	db := database.GetInstance()
+	var current entities.ViewEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		existing, err := db.GetViewById(ctx, view.Owner, view.Id)
+
+		if err != nil {
+			return err
+		}
+
+		if existing.List != view.List {
+			return errViewList
+		}
+
+		if _, err = db.EditView(ctx, view); err == database.ErrorConflict {
+			current = existing
+		}
+
+		return err
+	})
+
+	if err == database.ErrorConflict {
+		WriteConflict(w, current)
+		return
+	}
+
+	if err != nil {
+		logger.Errorf("failed to edit view: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *ViewService) DeleteView(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid view id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		return db.DeleteView(ctx, requestActor(r), int64(id))
+	})
+
+	if err != nil {
+		logger.Errorf("failed to delete view: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteSuccess(w)
+}
+
+func (s *ViewService) GetView(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil {
+		WriteFailure(w, "invalid view id", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var view entities.ViewEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		view, err = db.GetViewById(ctx, requestActor(r), int64(id))
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get view: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, view)
+}
+
+// All returns the views of the current user by list and name.
+func (s *ViewService) All(w http.ResponseWriter, r *http.Request) {
+	var request ViewListRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+
+	if err != nil {
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := viewLists[request.List]; request.List != "" && !ok {
+		WriteFailure(w, "invalid list", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetInstance()
+	var views []entities.ViewEntity
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		views, err = db.GetViews(ctx, requestActor(r), request.List)
+		return err
+	})
+
+	if err != nil {
+		logger.Errorf("failed to get views: %v", err)
+		WriteFailure(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, _ = WriteData(w, views)
+}
+
+// withView applies the view tableRequest.ViewId of the current user to a
+// request for list. The filters of the request override those of the view,
+// both queries have to match and the view's sort order is used when the
+// request has none.
+func withView(r *http.Request, tableRequest TableRequest, list string) (TableRequest, string) {
+	if tableRequest.ViewId == 0 {
+		return tableRequest, ""
+	}
+
+	db := database.GetInstance()
+	var view entities.ViewEntity
+	var err error
+
+	err = db.WithTxn(r.Context(), false, func(ctx context.Context) error {
+		view, err = db.GetViewById(ctx, requestActor(r), int64(tableRequest.ViewId))
+		return err
+	})
+
+	if err == database.ErrorNotFound {
+		return tableRequest, "view not found"
+	}
+
+	if err != nil {
+		logger.Errorf("failed to get view: %v", err)
+		return tableRequest, err.Error()
+	}
+
+	if view.List != list {
+		return tableRequest, "view is not a view of the " + list
+	}
+
+	filters := make(map[string]string, len(view.Filters)+len(tableRequest.Filters))
+
+	for _, source := range []map[string]string{view.Filters, tableRequest.Filters} {
+		for key, value := range source {
+			filters[key] = value
+		}
+	}
+
+	tableRequest.Filters = filters
+	tableRequest.viewQuery = view.Query
+
+	if tableRequest.OrderBy == "" {
+		tableRequest.OrderBy = view.OrderBy
+		tableRequest.Order = view.Order
+	}
+
+	return tableRequest, ""
+}
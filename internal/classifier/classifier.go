@@ -0,0 +1,275 @@
+package classifier
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/currency"
+	"github.com/lembata/para/pkg/database"
+	log "github.com/lembata/para/pkg/logger"
+)
+
+var logger = log.NewLogger()
+
+// Classifier suggests categories with a naive Bayes model trained on the
+// categories of existing items. The model lives in memory, it is trained on
+// first use and afterwards only catches up with the items that changed.
+type Classifier struct {
+	db       *database.Database
+	mutex    sync.Mutex
+	syncedAt time.Time
+	// observations are the tokens and category learned from every item
+	observations map[int64]observation
+	categories   map[int64]*categoryCounts
+	// vocabulary counts in how many observations a token appears
+	vocabulary map[string]int
+	total      int
+}
+
+type observation struct {
+	categoryId int64
+	tokens     []string
+}
+
+type categoryCounts struct {
+	observations int
+	tokens       map[string]int
+	totalTokens  int
+}
+
+// Input describes the transaction a category is suggested for.
+type Input struct {
+	PayeeName   string
+	Description string
+	ItemName    string
+	Amount      int
+}
+
+func New(db *database.Database) *Classifier {
+	c := &Classifier{db: db}
+	c.reset()
+	return c
+}
+
+// Suggest returns up to limit categories for input, the most likely first.
+// The confidences of all categories add up to one.
+func (c *Classifier) Suggest(ctx context.Context, input Input, limit int) ([]entities.CategorySuggestion, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.sync(ctx); err != nil {
+		return nil, err
+	}
+
+	suggestions := c.predict(Tokens(input))
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions, nil
+}
+
+// Predict returns the most likely category for input when its confidence
+// reaches threshold, nil otherwise. Importers use it to pre-fill categories.
+func (c *Classifier) Predict(ctx context.Context, input Input, threshold float64) (*int64, error) {
+	suggestions, err := c.Suggest(ctx, input, 1)
+
+	if err != nil || len(suggestions) == 0 || suggestions[0].Confidence < threshold {
+		return nil, err
+	}
+
+	categoryId := int64(suggestions[0].CategoryId)
+	return &categoryId, nil
+}
+
+// sync learns the items that changed since the last sync. Deleted items can
+// not be seen that way, when the model ends up with more items than the
+// database it is retrained from scratch.
+func (c *Classifier) sync(ctx context.Context) error {
+	for retrain := false; ; retrain = true {
+		if retrain {
+			logger.Info("Retraining category classifier")
+			c.reset()
+		}
+
+		syncedAt := time.Now()
+		items, err := c.db.GetCategorizedItems(ctx, c.syncedAt)
+
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			c.forget(item.ItemId)
+
+			if item.CategoryId != nil {
+				c.learn(item.ItemId, *item.CategoryId, Tokens(Input{
+					PayeeName:   item.PayeeName,
+					Description: item.Description,
+					ItemName:    item.ItemName,
+					Amount:      item.Amount,
+				}))
+			}
+		}
+
+		c.syncedAt = syncedAt
+		count, err := c.db.CountCategorizedItems(ctx)
+
+		if err != nil || count == len(c.observations) || retrain {
+			return err
+		}
+	}
+}
+
+func (c *Classifier) reset() {
+	c.syncedAt = time.Time{}
+	c.observations = make(map[int64]observation)
+	c.categories = make(map[int64]*categoryCounts)
+	c.vocabulary = make(map[string]int)
+	c.total = 0
+}
+
+func (c *Classifier) learn(itemId int64, categoryId int64, tokens []string) {
+	counts, ok := c.categories[categoryId]
+
+	if !ok {
+		counts = &categoryCounts{tokens: make(map[string]int)}
+		c.categories[categoryId] = counts
+	}
+
+	counts.observations++
+	counts.totalTokens += len(tokens)
+
+	for _, token := range tokens {
+		counts.tokens[token]++
+		c.vocabulary[token]++
+	}
+
+	c.observations[itemId] = observation{categoryId: categoryId, tokens: tokens}
+	c.total++
+}
+
+func (c *Classifier) forget(itemId int64) {
+	learned, ok := c.observations[itemId]
+
+	if !ok {
+		return
+	}
+
+	counts := c.categories[learned.categoryId]
+	counts.observations--
+	counts.totalTokens -= len(learned.tokens)
+
+	for _, token := range learned.tokens {
+		if counts.tokens[token]--; counts.tokens[token] == 0 {
+			delete(counts.tokens, token)
+		}
+
+		if c.vocabulary[token]--; c.vocabulary[token] == 0 {
+			delete(c.vocabulary, token)
+		}
+	}
+
+	if counts.observations == 0 {
+		delete(c.categories, learned.categoryId)
+	}
+
+	delete(c.observations, itemId)
+	c.total--
+}
+
+// predict scores every category with Laplace smoothing, tokens never seen in
+// training are ignored.
+func (c *Classifier) predict(tokens []string) []entities.CategorySuggestion {
+	if c.total == 0 {
+		return nil
+	}
+
+	vocabularySize := float64(len(c.vocabulary))
+	scores := make(map[int64]float64, len(c.categories))
+	best := math.Inf(-1)
+
+	for categoryId, counts := range c.categories {
+		score := math.Log(float64(counts.observations) / float64(c.total))
+
+		for _, token := range tokens {
+			if c.vocabulary[token] == 0 {
+				continue
+			}
+
+			score += math.Log(float64(counts.tokens[token]+1) / (float64(counts.totalTokens) + vocabularySize))
+		}
+
+		scores[categoryId] = score
+		best = max(best, score)
+	}
+
+	var sum float64
+
+	for categoryId, score := range scores {
+		scores[categoryId] = math.Exp(score - best)
+		sum += scores[categoryId]
+	}
+
+	suggestions := make([]entities.CategorySuggestion, 0, len(scores))
+
+	for categoryId, score := range scores {
+		suggestions = append(suggestions, entities.CategorySuggestion{
+			CategoryId: int(categoryId),
+			Confidence: score / sum,
+		})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Confidence != suggestions[j].Confidence {
+			return suggestions[i].Confidence > suggestions[j].Confidence
+		}
+
+		return suggestions[i].CategoryId < suggestions[j].CategoryId
+	})
+
+	return suggestions
+}
+
+// Tokens splits the texts of input into lower case words and adds a token for
+// the order of magnitude of the amount. Numbers are left out since they are
+// mostly references and dates.
+func Tokens(input Input) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+
+	add := func(token string) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	for _, text := range []string{input.PayeeName, input.Description, input.ItemName} {
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+
+		for _, word := range words {
+			if len([]rune(word)) < 2 || strings.IndexFunc(word, unicode.IsLetter) < 0 {
+				continue
+			}
+
+			add(word)
+		}
+	}
+
+	units := uint(math.Abs(currency.FromCoins(input.Amount)))
+	add(fmt.Sprintf("amount:%d", bits.Len(units)))
+
+	return tokens
+}
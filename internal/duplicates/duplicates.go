@@ -0,0 +1,166 @@
+package duplicates
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/database"
+	log "github.com/lembata/para/pkg/logger"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+var logger = log.NewLogger()
+
+const (
+	// Window is the number of days two transactions may be apart and still be
+	// considered the same payment, bank booking dates lag behind card dates.
+	Window = 5
+	// Threshold is the score from which a pair is queued for review.
+	Threshold = 0.6
+	// the most transactions of an account compared in one check
+	maxCandidates = 500
+)
+
+// Score rates how likely two transactions are the same payment, from 0 to 1.
+// Transactions only match when they have the same amount and share an
+// account on the same side, the date distance and the similarity of payee and
+// description make up the rest of the score.
+func Score(a entities.TransactionRow, b entities.TransactionRow) float64 {
+	if a.Amount != b.Amount {
+		return 0
+	}
+
+	if !sameId(a.FromAccountId, b.FromAccountId) && !sameId(a.ToAccountId, b.ToAccountId) {
+		return 0
+	}
+
+	dateA, errA := time.Parse(recurrence.DateLayout, a.Date)
+	dateB, errB := time.Parse(recurrence.DateLayout, b.Date)
+
+	if errA != nil || errB != nil {
+		return 0
+	}
+
+	days := dateA.Sub(dateB).Hours() / 24
+
+	if days < 0 {
+		days = -days
+	}
+
+	if days > Window {
+		return 0
+	}
+
+	return 0.4 + 0.35*(1-days/(Window+1)) + 0.25*similarity(a, b)
+}
+
+// Check compares a transaction with the transactions of its accounts around
+// its date and queues the likely duplicates for review. It is called for
+// every created or imported transaction, after the rules have run.
+func Check(ctx context.Context, db *database.Database, transactionId int64) error {
+	transactions, err := db.GetTransactions(ctx, entities.TransactionFilter{Ids: []int64{transactionId}}, 0, 1, "t.id")
+
+	if err != nil {
+		return err
+	}
+
+	if len(transactions) == 0 {
+		return database.ErrorNotFound
+	}
+
+	transaction := transactions[0]
+	date, err := time.Parse(recurrence.DateLayout, transaction.Date)
+
+	if err != nil {
+		return err
+	}
+
+	filter := entities.TransactionFilter{
+		FromDate: date.AddDate(0, 0, -Window).Format(recurrence.DateLayout),
+		ToDate:   date.AddDate(0, 0, Window).Format(recurrence.DateLayout),
+	}
+
+	seen := map[int]bool{transaction.Id: true}
+
+	for _, accountId := range []*int64{transaction.FromAccountId, transaction.ToAccountId} {
+		if accountId == nil {
+			continue
+		}
+
+		filter.AccountId = *accountId
+		candidates, err := db.GetTransactions(ctx, filter, 0, maxCandidates, "t.date")
+
+		if err != nil {
+			return err
+		}
+
+		for _, candidate := range candidates {
+			if seen[candidate.Id] {
+				continue
+			}
+
+			seen[candidate.Id] = true
+			score := Score(transaction, candidate)
+
+			if score < Threshold {
+				continue
+			}
+
+			logger.Debugf("Transaction %d looks like a duplicate of %d, score %.2f", transaction.Id, candidate.Id, score)
+
+			err = db.CreateDuplicatePair(ctx, entities.DuplicatePairEntity{
+				TransactionId: int64(transaction.Id),
+				DuplicateId:   int64(candidate.Id),
+				Score:         score,
+				CreateAt:      time.Now(),
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// similarity is the share of words the payees and descriptions have in
+// common. It is neutral when either side has no words.
+func similarity(a entities.TransactionRow, b entities.TransactionRow) float64 {
+	wordsA := words(a.PayeeName + " " + a.Description)
+	wordsB := words(b.PayeeName + " " + b.Description)
+
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0.5
+	}
+
+	common := 0
+
+	for word := range wordsA {
+		if wordsB[word] {
+			common++
+		}
+	}
+
+	return float64(common) / float64(len(wordsA)+len(wordsB)-common)
+}
+
+func words(text string) map[string]bool {
+	set := make(map[string]bool)
+
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		set[word] = true
+	}
+
+	return set
+}
+
+func isSeparator(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+}
+
+func sameId(a *int64, b *int64) bool {
+	return a != nil && b != nil && *a == *b
+}
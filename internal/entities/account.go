@@ -1,28 +1,153 @@
 package entities
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
+// AccountType decides how an account is presented and whether its balance
+// adds to or lowers the net worth.
+type AccountType int
+
+const (
+	AccountChecking AccountType = iota
+	AccountSavings
+	AccountCash
+	AccountCreditCard
+	AccountLoan
+	AccountInvestment
+	AccountAsset
+	AccountLiability
+)
+
+var accountTypeNames = []string{"checking", "savings", "cash", "creditCard",
+	"loan", "investment", "asset", "liability"}
+
+func (t AccountType) String() string {
+	if t < 0 || int(t) >= len(accountTypeNames) {
+		return fmt.Sprintf("AccountType(%d)", int(t))
+	}
+
+	return accountTypeNames[t]
+}
+
+// Liability reports whether the balance of the account is money owed.
+func (t AccountType) Liability() bool {
+	return t == AccountCreditCard || t == AccountLoan || t == AccountLiability
+}
+
+func ParseAccountType(name string) (AccountType, error) {
+	for i, typeName := range accountTypeNames {
+		if typeName == name {
+			return AccountType(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid account type %q", name)
+}
+
+func (t AccountType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *AccountType) UnmarshalJSON(data []byte) error {
+	var name string
+
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	accountType, err := ParseAccountType(name)
+	*t = accountType
+	return err
+}
+
 type AccountEntity struct {
-	Id                 int64     `db:"id" json:"id"`
-	Name               string    `db:"name" json:"name"`
-	CreateAt           time.Time `db:"create_at" json:"createAt"`
-	UpdateAt           time.Time `db:"update_at" json:"updateAt"`
-	Currency           string    `db:"currency" json:"currency"`
-	IBAN               string    `db:"iban" json:"iban"`
-	BIC                string    `db:"bic" json:"bic"`
-	AccountNumber      string    `db:"account_number" json:"accountNumber"`
-	OpeningBalance     int       `db:"opening_balance" json:"openingBalance"`
-	OpeningBalanceDate string    `db:"opening_balance_date" json:"openingBalanceDate"`
-	Notes              string    `db:"notes" json:"notes"`
-	IncludeInNetWorth  bool      `db:"include_in_net_worth" json:"includeInNetWorth"`
+	Id                 int64       `db:"id" json:"id"`
+	Name               string      `db:"name" json:"name"`
+	CreateAt           time.Time   `db:"create_at" json:"createAt"`
+	UpdateAt           time.Time   `db:"update_at" json:"updateAt"`
+	Currency           string      `db:"currency" json:"currency"`
+	IBAN               string      `db:"iban" json:"iban"`
+	BIC                string      `db:"bic" json:"bic"`
+	AccountNumber      string      `db:"account_number" json:"accountNumber"`
+	OpeningBalance     int         `db:"opening_balance" json:"openingBalance"`
+	OpeningBalanceDate string      `db:"opening_balance_date" json:"openingBalanceDate"`
+	Notes              string      `db:"notes" json:"notes"`
+	IncludeInNetWorth  bool        `db:"include_in_net_worth" json:"includeInNetWorth"`
+	Type               AccountType `db:"account_type" json:"type"`
+	// StatementClosingDay and PaymentDueDay are the days of the month a
+	// credit card statement closes and has to be paid, 0 when not set.
+	StatementClosingDay int `db:"statement_closing_day" json:"statementClosingDay"`
+	PaymentDueDay       int `db:"payment_due_day" json:"paymentDueDay"`
+	// Archived accounts are hidden from the account list but stay in
+	// reports, deleted ones are hidden everywhere until restored or purged.
+	Archived bool `db:"archived" json:"archived"`
+	Deleted  bool `db:"deleted" json:"deleted"`
+	// GroupId is the account group the account is shown in, nil when it is not in
+	// a group. OrderIndex is its position in the group.
+	GroupId    *int64 `db:"group_id" json:"groupId"`
+	OrderIndex int    `db:"order_index" json:"orderIndex"`
+	// Version is raised by every edit, an edit of an older version is a
+	// conflict.
+	Version int `db:"version" json:"version"`
 }
 
 type AccountRow struct {
-	Id      int           `json:"id"`
-	Name    string        `json:"name"`
-	Balance CurrencyValue `json:"balance"`
+	Id                int           `json:"id"`
+	Name              string        `json:"name"`
+	Type              AccountType   `json:"type"`
+	IncludeInNetWorth bool          `json:"includeInNetWorth"`
+	Archived          bool          `json:"archived"`
+	Deleted           bool          `json:"deleted"`
+	GroupId           int           `json:"groupId"`
+	Balance           CurrencyValue `json:"balance"`
+}
+
+// AccountGroupEntity is a named group of accounts such as "Everyday" or
+// "Business", groups are listed by OrderIndex.
+type AccountGroupEntity struct {
+	Id         int64     `db:"id" json:"id"`
+	Name       string    `db:"name" json:"name"`
+	OrderIndex int       `db:"order_index" json:"orderIndex"`
+	Version    int       `db:"version" json:"version"`
+	CreateAt   time.Time `db:"created_at" json:"createAt"`
+	UpdateAt   time.Time `db:"updated_at" json:"updateAt"`
+}
+
+// AccountGroupRow lists the accounts of a group in their order with the sum
+// of their balances per currency. Accounts outside a group are listed under
+// Id 0.
+type AccountGroupRow struct {
+	Id        int             `json:"id"`
+	Name      string          `json:"name"`
+	Version   int             `json:"version"`
+	Accounts  []AccountRow    `json:"accounts"`
+	Subtotals []CurrencyValue `json:"subtotals"`
+}
+
+// AccountList is a page of the accounts in use with the subtotals of every
+// group, the subtotals cover all accounts and not only the page.
+type AccountList struct {
+	Accounts []AccountRow        `json:"accounts"`
+	Groups   []AccountGroupTotal `json:"groups"`
+}
+
+// AccountGroupTotal is the subtotal of a group per currency, accounts outside
+// a group are under id 0.
+type AccountGroupTotal struct {
+	Id        int             `json:"id"`
+	Name      string          `json:"name"`
+	Subtotals []CurrencyValue `json:"subtotals"`
+}
+
+// NetWorth sums the balances of the accounts included in the net worth per
+// currency, liabilities count against it.
+type NetWorth struct {
+	Assets      CurrencyValue `json:"assets"`
+	Liabilities CurrencyValue `json:"liabilities"`
+	NetWorth    CurrencyValue `json:"netWorth"`
 }
 
 type CurrencyValue struct {
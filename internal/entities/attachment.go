@@ -0,0 +1,18 @@
+package entities
+
+import (
+	"time"
+)
+
+// AttachmentEntity is a file attached to a transaction or an account. The
+// content is stored once per hash and shared between attachments.
+type AttachmentEntity struct {
+	Id            int64     `db:"id" json:"id"`
+	Hash          string    `db:"hash" json:"hash"`
+	Name          string    `db:"name" json:"name"`
+	MimeType      string    `db:"mime_type" json:"mimeType"`
+	Size          int64     `db:"size" json:"size"`
+	TransactionId *int64    `db:"transaction_id" json:"transactionId"`
+	AccountId     *int64    `db:"account_id" json:"accountId"`
+	CreateAt      time.Time `db:"created_at" json:"createAt"`
+}
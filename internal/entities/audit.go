@@ -0,0 +1,38 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit actions.
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditEntry records a change of an account, transaction, item, loan payment,
+// trade, duplicate pair or attachment. Categories are not audited, para has
+// no way to change them yet. Before and After hold the columns that changed,
+// Before is null for a created row and After for a deleted one.
+type AuditEntry struct {
+	Id        int64           `db:"id" json:"id"`
+	CreateAt  time.Time       `db:"created_at" json:"createAt"`
+	Actor     string          `db:"actor" json:"actor"`
+	RequestId string          `db:"request_id" json:"requestId"`
+	Entity    string          `db:"entity" json:"entity"`
+	EntityId  int64           `db:"entity_id" json:"entityId"`
+	Action    string          `db:"action" json:"action"`
+	Before    json.RawMessage `db:"before" json:"before"`
+	After     json.RawMessage `db:"after" json:"after"`
+}
+
+type AuditFilter struct {
+	Entity    string
+	EntityId  int64
+	Actor     string
+	RequestId string
+	FromDate  string
+	ToDate    string
+}
@@ -0,0 +1,30 @@
+package entities
+
+import (
+	"time"
+)
+
+type BudgetEntity struct {
+	Id         int64     `db:"id" json:"id"`
+	CategoryId int64     `db:"category_id" json:"categoryId"`
+	Month      string    `db:"month" json:"month"`
+	Amount     int       `db:"amount" json:"amount"`
+	Currency   string    `db:"currency" json:"currency"`
+	Rollover   bool      `db:"rollover" json:"rollover"`
+	Version    int       `db:"version" json:"version"`
+	CreateAt   time.Time `db:"created_at" json:"createAt"`
+	UpdateAt   time.Time `db:"updated_at" json:"updateAt"`
+}
+
+type BudgetRow struct {
+	Id           int           `json:"id"`
+	CategoryId   int           `json:"categoryId"`
+	CategoryName string        `json:"categoryName"`
+	Month        string        `json:"month"`
+	Rollover     bool          `json:"rollover"`
+	Version      int           `json:"version"`
+	Budgeted     CurrencyValue `json:"budgeted"`
+	CarriedOver  CurrencyValue `json:"carriedOver"`
+	Actual       CurrencyValue `json:"actual"`
+	Remaining    CurrencyValue `json:"remaining"`
+}
@@ -0,0 +1,23 @@
+package entities
+
+import (
+	"time"
+)
+
+// DuplicatePairEntity is a pair of transactions that look like the same
+// payment, TransactionId is always the lower id.
+type DuplicatePairEntity struct {
+	Id            int64     `db:"id" json:"id"`
+	TransactionId int64     `db:"transaction_id" json:"transactionId"`
+	DuplicateId   int64     `db:"duplicate_id" json:"duplicateId"`
+	Score         float64   `db:"score" json:"score"`
+	Dismissed     bool      `db:"dismissed" json:"dismissed"`
+	CreateAt      time.Time `db:"created_at" json:"createAt"`
+}
+
+type DuplicatePairRow struct {
+	Id          int            `json:"id"`
+	Score       float64        `json:"score"`
+	Transaction TransactionRow `json:"transaction"`
+	Duplicate   TransactionRow `json:"duplicate"`
+}
@@ -0,0 +1,32 @@
+package entities
+
+import (
+	"time"
+)
+
+// EnvelopeTransferEntity moves money between category envelopes. A missing
+// category on either side stands for the "to be assigned" pool.
+type EnvelopeTransferEntity struct {
+	Id             int64     `db:"id" json:"id"`
+	FromCategoryId *int64    `db:"from_category_id" json:"fromCategoryId"`
+	ToCategoryId   *int64    `db:"to_category_id" json:"toCategoryId"`
+	Month          string    `db:"month" json:"month"`
+	Amount         int       `db:"amount" json:"amount"`
+	Currency       string    `db:"currency" json:"currency"`
+	CreateAt       time.Time `db:"created_at" json:"createAt"`
+}
+
+type EnvelopeMonth struct {
+	Month        string        `json:"month"`
+	Income       CurrencyValue `json:"income"`
+	ToBeAssigned CurrencyValue `json:"toBeAssigned"`
+	Envelopes    []EnvelopeRow `json:"envelopes"`
+}
+
+type EnvelopeRow struct {
+	CategoryId   int           `json:"categoryId"`
+	CategoryName string        `json:"categoryName"`
+	Assigned     CurrencyValue `json:"assigned"`
+	Activity     CurrencyValue `json:"activity"`
+	Available    CurrencyValue `json:"available"`
+}
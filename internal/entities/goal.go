@@ -0,0 +1,51 @@
+package entities
+
+import (
+	"time"
+)
+
+// Goal statuses.
+const (
+	GoalReached = "reached"
+	GoalOnTrack = "onTrack"
+	GoalBehind  = "behind"
+	GoalOverdue = "overdue"
+)
+
+// GoalEntity is a savings goal funded by an account. Allocation earmarks a
+// part of the balance for the goal, without it the whole balance counts.
+// StartAmount is what was saved on StartDate, progress is expected to grow
+// linearly from there to the target.
+type GoalEntity struct {
+	Id           int64     `db:"id" json:"id"`
+	Name         string    `db:"name" json:"name"`
+	AccountId    int64     `db:"account_id" json:"accountId"`
+	TargetAmount int       `db:"target_amount" json:"targetAmount"`
+	TargetDate   string    `db:"target_date" json:"targetDate"`
+	Allocation   *int      `db:"allocation" json:"allocation"`
+	StartDate    string    `db:"start_date" json:"startDate"`
+	StartAmount  int       `db:"start_amount" json:"startAmount"`
+	Version      int       `db:"version" json:"version"`
+	CreateAt     time.Time `db:"created_at" json:"createAt"`
+	UpdateAt     time.Time `db:"updated_at" json:"updateAt"`
+}
+
+type GoalRow struct {
+	Id          int            `json:"id"`
+	Name        string         `json:"name"`
+	AccountId   int            `json:"accountId"`
+	AccountName string         `json:"accountName"`
+	Target      CurrencyValue  `json:"target"`
+	TargetDate  string         `json:"targetDate"`
+	Allocation  *CurrencyValue `json:"allocation"`
+	Saved       CurrencyValue  `json:"saved"`
+	Remaining   CurrencyValue  `json:"remaining"`
+	// Progress is the share of the target saved, from 0 to 1.
+	Progress float64 `json:"progress"`
+	// Expected is what should have been saved by today to be on track.
+	Expected            CurrencyValue `json:"expected"`
+	MonthsLeft          int           `json:"monthsLeft"`
+	MonthlyContribution CurrencyValue `json:"monthlyContribution"`
+	Status              string        `json:"status"`
+	Version             int           `json:"version"`
+}
@@ -0,0 +1,15 @@
+package entities
+
+import "time"
+
+// Operation is a request of a user that changed accounts, transactions,
+// items or categories. Its changes are the audit entries of the request.
+type Operation struct {
+	Id        int64     `db:"id" json:"id"`
+	Actor     string    `db:"actor" json:"actor"`
+	RequestId string    `db:"request_id" json:"requestId"`
+	Name      string    `db:"name" json:"name"`
+	Undone    bool      `db:"undone" json:"undone"`
+	Changes   int       `db:"-" json:"changes"`
+	CreateAt  time.Time `db:"created_at" json:"createAt"`
+}
@@ -0,0 +1,113 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TradeKind is what happened to a security in an investment account.
+type TradeKind int
+
+const (
+	TradeBuy TradeKind = iota
+	TradeSell
+	TradeDividend
+	TradeFee
+)
+
+var tradeKindNames = []string{"buy", "sell", "dividend", "fee"}
+
+func (k TradeKind) String() string {
+	if k < 0 || int(k) >= len(tradeKindNames) {
+		return fmt.Sprintf("TradeKind(%d)", int(k))
+	}
+
+	return tradeKindNames[k]
+}
+
+func ParseTradeKind(name string) (TradeKind, error) {
+	for i, kindName := range tradeKindNames {
+		if kindName == name {
+			return TradeKind(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid trade kind %q", name)
+}
+
+func (k TradeKind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.String())
+}
+
+func (k *TradeKind) UnmarshalJSON(data []byte) error {
+	var name string
+
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	kind, err := ParseTradeKind(name)
+	*k = kind
+	return err
+}
+
+type SecurityEntity struct {
+	Id       int64     `db:"id" json:"id"`
+	Symbol   string    `db:"symbol" json:"symbol"`
+	ISIN     string    `db:"isin" json:"isin"`
+	Name     string    `db:"name" json:"name"`
+	Currency string    `db:"currency" json:"currency"`
+	Version  int       `db:"version" json:"version"`
+	CreateAt time.Time `db:"created_at" json:"createAt"`
+	UpdateAt time.Time `db:"updated_at" json:"updateAt"`
+}
+
+// TradeEntity is a buy, sell, dividend or fee on an investment account.
+// Amount is the cash it moved, booked by the linked transaction: buys and
+// fees take it from the account, sells and dividends add it.
+type TradeEntity struct {
+	Id            int64     `db:"id" json:"id"`
+	AccountId     int64     `db:"account_id" json:"accountId"`
+	SecurityId    int64     `db:"security_id" json:"securityId"`
+	TransactionId int64     `db:"transaction_id" json:"transactionId"`
+	Kind          TradeKind `db:"kind" json:"kind"`
+	Date          string    `db:"date" json:"date"`
+	Quantity      float64   `db:"quantity" json:"quantity"`
+	Price         int       `db:"price" json:"price"`
+	Fee           int       `db:"fee" json:"fee"`
+	Amount        int       `db:"amount" json:"amount"`
+	CreateAt      time.Time `db:"created_at" json:"createAt"`
+}
+
+type SecurityPrice struct {
+	SecurityId int64  `db:"security_id" json:"securityId"`
+	Date       string `db:"date" json:"date"`
+	Price      int    `db:"price" json:"price"`
+}
+
+// Lot is what is left of a buy after the sells that consumed it first in
+// first out. Cost includes the fee of the buy.
+type Lot struct {
+	TradeId  int     `json:"tradeId"`
+	Date     string  `json:"date"`
+	Quantity float64 `json:"quantity"`
+	Cost     int     `json:"cost"`
+}
+
+// Holding is the position in one security of an investment account.
+type Holding struct {
+	SecurityId     int           `json:"securityId"`
+	Symbol         string        `json:"symbol"`
+	Name           string        `json:"name"`
+	Quantity       float64       `json:"quantity"`
+	Price          CurrencyValue `json:"price"`
+	PriceDate      string        `json:"priceDate"`
+	CostBasis      CurrencyValue `json:"costBasis"`
+	MarketValue    CurrencyValue `json:"marketValue"`
+	UnrealizedGain CurrencyValue `json:"unrealizedGain"`
+	RealizedGain   CurrencyValue `json:"realizedGain"`
+	Dividends      CurrencyValue `json:"dividends"`
+	Fees           CurrencyValue `json:"fees"`
+	Lots           []Lot         `json:"lots"`
+}
@@ -0,0 +1,127 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// LoanMethod is how a loan is paid back: annuity loans have a constant
+// payment, linear loans a constant principal part.
+type LoanMethod int
+
+const (
+	LoanAnnuity LoanMethod = iota
+	LoanLinear
+)
+
+var loanMethodNames = []string{"annuity", "linear"}
+
+func (m LoanMethod) String() string {
+	if m < 0 || int(m) >= len(loanMethodNames) {
+		return fmt.Sprintf("LoanMethod(%d)", int(m))
+	}
+
+	return loanMethodNames[m]
+}
+
+func ParseLoanMethod(name string) (LoanMethod, error) {
+	for i, methodName := range loanMethodNames {
+		if methodName == name {
+			return LoanMethod(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid loan method %q", name)
+}
+
+func (m LoanMethod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+func (m *LoanMethod) UnmarshalJSON(data []byte) error {
+	var name string
+
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	method, err := ParseLoanMethod(name)
+	*m = method
+	return err
+}
+
+// LoanEntity holds the terms of the loan behind an account of type loan.
+// InterestRate is the yearly rate in percent, payments are due monthly from
+// one month after StartDate.
+type LoanEntity struct {
+	Id                 int64      `db:"id" json:"id"`
+	AccountId          int64      `db:"account_id" json:"accountId"`
+	Principal          int        `db:"principal" json:"principal"`
+	InterestRate       float64    `db:"interest_rate" json:"interestRate"`
+	TermMonths         int        `db:"term_months" json:"termMonths"`
+	StartDate          string     `db:"start_date" json:"startDate"`
+	Method             LoanMethod `db:"method" json:"method"`
+	InterestCategoryId *int64     `db:"interest_category_id" json:"interestCategoryId"`
+	Version            int        `db:"version" json:"version"`
+	CreateAt           time.Time  `db:"created_at" json:"createAt"`
+	UpdateAt           time.Time  `db:"updated_at" json:"updateAt"`
+}
+
+// LoanPaymentEntity is the split of a payment into a loan account. The
+// interest is charged to the loan account by a separate transaction so that
+// the balance of the account only drops by the principal.
+type LoanPaymentEntity struct {
+	Id                    int64  `db:"id" json:"id"`
+	LoanId                int64  `db:"loan_id" json:"loanId"`
+	TransactionId         int64  `db:"transaction_id" json:"transactionId"`
+	InterestTransactionId *int64 `db:"interest_transaction_id" json:"interestTransactionId"`
+	Principal             int    `db:"principal" json:"principal"`
+	Interest              int    `db:"interest" json:"interest"`
+}
+
+type LoanRow struct {
+	Id                 int           `json:"id"`
+	AccountId          int           `json:"accountId"`
+	AccountName        string        `json:"accountName"`
+	Principal          CurrencyValue `json:"principal"`
+	InterestRate       float64       `json:"interestRate"`
+	TermMonths         int           `json:"termMonths"`
+	StartDate          string        `json:"startDate"`
+	Method             LoanMethod    `json:"method"`
+	InterestCategoryId int           `json:"interestCategoryId"`
+	PrincipalPaid      CurrencyValue `json:"principalPaid"`
+	InterestPaid       CurrencyValue `json:"interestPaid"`
+	Outstanding        CurrencyValue `json:"outstanding"`
+	Version            int           `json:"version"`
+}
+
+// AmortizationRow is one monthly payment of a loan, Extra is paid on top of
+// the scheduled principal. Amounts are in coins.
+type AmortizationRow struct {
+	Number    int    `json:"number"`
+	Date      string `json:"date"`
+	Payment   int    `json:"payment"`
+	Principal int    `json:"principal"`
+	Interest  int    `json:"interest"`
+	Extra     int    `json:"extra"`
+	Balance   int    `json:"balance"`
+}
+
+type AmortizationSchedule struct {
+	Currency      string            `json:"currency"`
+	Payments      int               `json:"payments"`
+	PayoffDate    string            `json:"payoffDate"`
+	TotalInterest int               `json:"totalInterest"`
+	TotalPaid     int               `json:"totalPaid"`
+	Rows          []AmortizationRow `json:"rows,omitempty"`
+}
+
+// LoanProjection compares the schedule of a loan with a scenario of extra
+// payments.
+type LoanProjection struct {
+	Baseline      AmortizationSchedule `json:"baseline"`
+	Scenario      AmortizationSchedule `json:"scenario"`
+	InterestSaved int                  `json:"interestSaved"`
+	MonthsSaved   int                  `json:"monthsSaved"`
+}
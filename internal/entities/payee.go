@@ -0,0 +1,24 @@
+package entities
+
+import (
+	"time"
+)
+
+type PayeeEntity struct {
+	Id                int64     `db:"id" json:"id"`
+	Name              string    `db:"name" json:"name"`
+	DefaultCategoryId *int64    `db:"default_category_id" json:"defaultCategoryId"`
+	IBAN              string    `db:"iban" json:"iban"`
+	Aliases           []string  `db:"-" json:"aliases"`
+	Version           int       `db:"version" json:"version"`
+	CreateAt          time.Time `db:"created_at" json:"createAt"`
+	UpdateAt          time.Time `db:"updated_at" json:"updateAt"`
+}
+
+type PayeeRow struct {
+	Id                int    `json:"id"`
+	Name              string `json:"name"`
+	DefaultCategoryId *int64 `json:"defaultCategoryId"`
+	IBAN              string `json:"iban"`
+	Transactions      int    `json:"transactions"`
+}
@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"time"
+)
+
+// Sources of a statement balance.
+const (
+	StatementSourceManual = "manual"
+	StatementSourceCamt   = "camt"
+	StatementSourceMT940  = "mt940"
+	StatementSourceOFX    = "ofx"
+)
+
+// ReconciliationEntity compares the balance of a bank statement with the
+// cleared balance of an account. It is open until FinishedAt is set.
+type ReconciliationEntity struct {
+	Id               int64      `db:"id" json:"id"`
+	AccountId        int64      `db:"account_id" json:"accountId"`
+	StatementDate    string     `db:"statement_date" json:"statementDate"`
+	StatementBalance int        `db:"statement_balance" json:"statementBalance"`
+	Source           string     `db:"source" json:"source"`
+	FinishedAt       *time.Time `db:"finished_at" json:"finishedAt"`
+	CreateAt         time.Time  `db:"created_at" json:"createAt"`
+	UpdateAt         time.Time  `db:"updated_at" json:"updateAt"`
+}
+
+// ReconciliationRow shows how far the cleared balance is from the statement,
+// the difference is statement minus cleared balance.
+type ReconciliationRow struct {
+	Id               int              `json:"id"`
+	AccountId        int              `json:"accountId"`
+	StatementDate    string           `json:"statementDate"`
+	StatementBalance CurrencyValue    `json:"statementBalance"`
+	ClearedBalance   CurrencyValue    `json:"clearedBalance"`
+	Difference       CurrencyValue    `json:"difference"`
+	Source           string           `json:"source"`
+	Finished         bool             `json:"finished"`
+	Transactions     []TransactionRow `json:"transactions,omitempty"`
+}
@@ -0,0 +1,25 @@
+package entities
+
+type ForecastAccount struct {
+	AccountId   int             `json:"accountId"`
+	AccountName string          `json:"accountName"`
+	Threshold   CurrencyValue   `json:"threshold"`
+	Lowest      ForecastDay     `json:"lowest"`
+	Days        []ForecastDay   `json:"days"`
+	Alerts      []ForecastAlert `json:"alerts"`
+}
+
+type ForecastDay struct {
+	Date           string        `json:"date"`
+	Balance        CurrencyValue `json:"balance"`
+	BelowZero      bool          `json:"belowZero"`
+	BelowThreshold bool          `json:"belowThreshold"`
+}
+
+// ForecastAlert marks the day a balance drops below zero or below the
+// threshold of its account.
+type ForecastAlert struct {
+	Date    string        `json:"date"`
+	Balance CurrencyValue `json:"balance"`
+	Reason  string        `json:"reason"`
+}
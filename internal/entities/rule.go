@@ -0,0 +1,59 @@
+package entities
+
+import (
+	"time"
+)
+
+type RuleEntity struct {
+	Id             int64          `db:"id" json:"id"`
+	Name           string         `db:"name" json:"name"`
+	Priority       int            `db:"priority" json:"priority"`
+	Enabled        bool           `db:"enabled" json:"enabled"`
+	StopProcessing bool           `db:"stop_processing" json:"stopProcessing"`
+	Conditions     RuleConditions `db:"conditions" json:"conditions"`
+	Actions        RuleActions    `db:"actions" json:"actions"`
+	Version        int            `db:"version" json:"version"`
+	CreateAt       time.Time      `db:"created_at" json:"createAt"`
+	UpdateAt       time.Time      `db:"updated_at" json:"updateAt"`
+}
+
+// RuleConditions must all hold for a rule to match, empty conditions are
+// ignored. Amounts are in coins.
+type RuleConditions struct {
+	PayeeId   int64  `json:"payeeId,omitempty"`
+	PayeeName string `json:"payeeName,omitempty"`
+	// Memo and MemoRegex are matched against the description and the notes.
+	Memo      string `json:"memo,omitempty"`
+	MemoRegex string `json:"memoRegex,omitempty"`
+	MinAmount *int   `json:"minAmount,omitempty"`
+	MaxAmount *int   `json:"maxAmount,omitempty"`
+	AccountId int64  `json:"accountId,omitempty"`
+	Direction string `json:"direction,omitempty"`
+}
+
+// RuleActions are applied to the matching transactions, empty actions are
+// ignored.
+type RuleActions struct {
+	CategoryId  int64    `json:"categoryId,omitempty"`
+	PayeeId     int64    `json:"payeeId,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Description string   `json:"description,omitempty"`
+	// TransferAccountId turns an incoming or outgoing transaction into a
+	// transfer from or to that account.
+	TransferAccountId int64 `json:"transferAccountId,omitempty"`
+}
+
+// RuleChange is a field a rule changes, ItemId is set for changes to an item.
+type RuleChange struct {
+	ItemId int    `json:"itemId,omitempty"`
+	Field  string `json:"field"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// RuleResult lists what the matching rules change on a transaction.
+type RuleResult struct {
+	TransactionId int          `json:"transactionId"`
+	RuleIds       []int        `json:"ruleIds"`
+	Changes       []RuleChange `json:"changes"`
+}
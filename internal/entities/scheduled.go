@@ -0,0 +1,38 @@
+package entities
+
+import (
+	"time"
+)
+
+type ScheduledTransactionEntity struct {
+	Id             int64     `db:"id" json:"id"`
+	Name           string    `db:"name" json:"name"`
+	FromAccountId  *int64    `db:"from_account_id" json:"fromAccountId"`
+	ToAccountId    *int64    `db:"to_account_id" json:"toAccountId"`
+	Amount         int       `db:"amount" json:"amount"`
+	CategoryId     *int64    `db:"category_id" json:"categoryId"`
+	Frequency      int       `db:"frequency" json:"frequency"`
+	Interval       int       `db:"interval" json:"interval"`
+	Weekday        int       `db:"weekday" json:"weekday"`
+	DayOfMonth     int       `db:"day_of_month" json:"dayOfMonth"`
+	StartDate      string    `db:"start_date" json:"startDate"`
+	EndDate        string    `db:"end_date" json:"endDate"`
+	MaxOccurrences int       `db:"max_occurrences" json:"maxOccurrences"`
+	Occurrences    int       `db:"occurrences" json:"occurrences"`
+	Pending        bool      `db:"pending" json:"pending"`
+	Version        int       `db:"version" json:"version"`
+	CreateAt       time.Time `db:"created_at" json:"createAt"`
+	UpdateAt       time.Time `db:"updated_at" json:"updateAt"`
+
+	// Currency is the currency of the accounts involved, it is not stored
+	Currency string `db:"currency" json:"currency"`
+}
+
+type ScheduledOccurrence struct {
+	ScheduledTransactionId int           `json:"scheduledTransactionId"`
+	Name                   string        `json:"name"`
+	Date                   string        `json:"date"`
+	FromAccountId          *int64        `json:"fromAccountId"`
+	ToAccountId            *int64        `json:"toAccountId"`
+	Amount                 CurrencyValue `json:"amount"`
+}
@@ -0,0 +1,20 @@
+package entities
+
+// SearchHit is a record matching a search. Items carry the transaction they
+// belong to, transactions and items their date.
+type SearchHit struct {
+	Id            int64   `json:"id"`
+	Title         string  `json:"title"`
+	Snippet       string  `json:"snippet"`
+	TransactionId *int64  `json:"transactionId"`
+	Date          string  `json:"date"`
+	Rank          float64 `json:"rank"`
+}
+
+// SearchResults are the hits of a search by entity type, the best first.
+type SearchResults struct {
+	Transactions []SearchHit `json:"transactions"`
+	Items        []SearchHit `json:"items"`
+	Payees       []SearchHit `json:"payees"`
+	Tags         []SearchHit `json:"tags"`
+}
@@ -0,0 +1,17 @@
+package entities
+
+// CategorizedItem is an item as seen by the category classifier. CategoryId
+// is nil for items that lost their category.
+type CategorizedItem struct {
+	ItemId      int64
+	CategoryId  *int64
+	PayeeName   string
+	Description string
+	ItemName    string
+	Amount      int
+}
+
+type CategorySuggestion struct {
+	CategoryId int     `json:"categoryId"`
+	Confidence float64 `json:"confidence"`
+}
@@ -0,0 +1,31 @@
+package entities
+
+import (
+	"time"
+)
+
+type TagEntity struct {
+	Id       int64     `db:"id" json:"id"`
+	Name     string    `db:"name" json:"name"`
+	Version  int       `db:"version" json:"version"`
+	CreateAt time.Time `db:"created_at" json:"createAt"`
+}
+
+type TagRow struct {
+	Id           int    `json:"id"`
+	Name         string `json:"name"`
+	Transactions int    `json:"transactions"`
+	Items        int    `json:"items"`
+	Version      int    `json:"version"`
+}
+
+// TagTotal sums the income and expenses tagged with a tag in one currency.
+// Tagged transactions count with their whole amount, tagged items with their
+// price.
+type TagTotal struct {
+	TagId        int           `json:"tagId"`
+	Name         string        `json:"name"`
+	Income       CurrencyValue `json:"income"`
+	Expense      CurrencyValue `json:"expense"`
+	Transactions int           `json:"transactions"`
+}
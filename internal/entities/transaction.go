@@ -0,0 +1,113 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lembata/para/pkg/filter"
+)
+
+// TransactionStatus tracks a transaction through reconciliation. Reconciled
+// transactions are locked against edits.
+type TransactionStatus int
+
+const (
+	StatusUncleared TransactionStatus = iota
+	StatusCleared
+	StatusReconciled
+)
+
+var transactionStatusNames = []string{"uncleared", "cleared", "reconciled"}
+
+func (s TransactionStatus) String() string {
+	if s < 0 || int(s) >= len(transactionStatusNames) {
+		return fmt.Sprintf("TransactionStatus(%d)", int(s))
+	}
+
+	return transactionStatusNames[s]
+}
+
+func ParseTransactionStatus(name string) (TransactionStatus, error) {
+	for i, statusName := range transactionStatusNames {
+		if statusName == name {
+			return TransactionStatus(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid transaction status %q", name)
+}
+
+func (s TransactionStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s *TransactionStatus) UnmarshalJSON(data []byte) error {
+	var name string
+
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	status, err := ParseTransactionStatus(name)
+	*s = status
+	return err
+}
+
+// TransactionEntity moves money between two accounts. A missing account on
+// either side means the money came from or went to the outside world.
+type TransactionEntity struct {
+	Id                     int64             `db:"id" json:"id"`
+	FromAccountId          *int64            `db:"from_account_id" json:"fromAccountId"`
+	ToAccountId            *int64            `db:"to_account_id" json:"toAccountId"`
+	TotalAmount            int               `db:"total_amount" json:"totalAmount"`
+	Date                   string            `db:"date" json:"date"`
+	PayeeId                *int64            `db:"payee_id" json:"payeeId"`
+	Description            string            `db:"description" json:"description"`
+	Notes                  string            `db:"notes" json:"notes"`
+	Pending                bool              `db:"pending" json:"pending"`
+	Status                 TransactionStatus `db:"status" json:"status"`
+	ScheduledTransactionId *int64            `db:"scheduled_transaction_id" json:"scheduledTransactionId"`
+	Version                int               `db:"version" json:"version"`
+	CreateAt               time.Time         `db:"created_at" json:"createAt"`
+	UpdateAt               time.Time         `db:"updated_at" json:"updateAt"`
+}
+
+type ItemEntity struct {
+	Id            int64     `db:"id" json:"id"`
+	Name          string    `db:"name" json:"name"`
+	Price         int       `db:"price" json:"price"`
+	TransactionId int64     `db:"transaction_id" json:"transactionId"`
+	CategoryId    *int64    `db:"category_id" json:"categoryId"`
+	CreateAt      time.Time `db:"created_at" json:"createAt"`
+	UpdateAt      time.Time `db:"updated_at" json:"updateAt"`
+}
+
+type TransactionRow struct {
+	Id              int               `json:"id"`
+	Date            string            `json:"date"`
+	FromAccountId   *int64            `json:"fromAccountId"`
+	FromAccountName string            `json:"fromAccountName"`
+	ToAccountId     *int64            `json:"toAccountId"`
+	ToAccountName   string            `json:"toAccountName"`
+	PayeeId         *int64            `json:"payeeId"`
+	PayeeName       string            `json:"payeeName"`
+	Description     string            `json:"description"`
+	Amount          CurrencyValue     `json:"amount"`
+	Pending         bool              `json:"pending"`
+	Status          TransactionStatus `json:"status"`
+	Tags            []string          `json:"tags"`
+}
+
+type TransactionFilter struct {
+	Ids       []int64
+	AccountId int64
+	PayeeId   int64
+	FromDate  string
+	ToDate    string
+	// Tag matches transactions tagged with it or with an item tagged with it.
+	Tag      string
+	Statuses []TransactionStatus
+	// Query is a filter expression on the fields of a transaction.
+	Query filter.Expr
+}
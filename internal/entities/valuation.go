@@ -0,0 +1,22 @@
+package entities
+
+import (
+	"time"
+)
+
+// ValuationEntity is what an asset account was worth on a day. Valuations
+// take the place of the balance of the account in the net worth.
+type ValuationEntity struct {
+	Id        int64     `db:"id" json:"id"`
+	AccountId int64     `db:"account_id" json:"accountId"`
+	Date      string    `db:"date" json:"date"`
+	Value     int       `db:"value" json:"value"`
+	Notes     string    `db:"notes" json:"notes"`
+	Version   int       `db:"version" json:"version"`
+	CreateAt  time.Time `db:"created_at" json:"createAt"`
+}
+
+type NetWorthPoint struct {
+	Date   string     `json:"date"`
+	Totals []NetWorth `json:"totals"`
+}
@@ -0,0 +1,21 @@
+package entities
+
+import "time"
+
+// ViewEntity is a saved way of showing a list, the transactions or the
+// accounts, to the user owning it: its filters, query, sort order and
+// visible columns.
+type ViewEntity struct {
+	Id       int64             `db:"id" json:"id"`
+	Owner    string            `db:"owner" json:"owner"`
+	List     string            `db:"list" json:"list"`
+	Name     string            `db:"name" json:"name"`
+	Filters  map[string]string `db:"filters" json:"filters"`
+	Query    string            `db:"query" json:"query"`
+	OrderBy  string            `db:"order_by" json:"orderBy"`
+	Order    string            `db:"sort_order" json:"order"`
+	Columns  []string          `db:"columns" json:"columns"`
+	Version  int               `db:"version" json:"version"`
+	CreateAt time.Time         `db:"created_at" json:"createAt"`
+	UpdateAt time.Time         `db:"updated_at" json:"updateAt"`
+}
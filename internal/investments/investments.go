@@ -0,0 +1,140 @@
+package investments
+
+import (
+	"errors"
+	"math"
+
+	"github.com/lembata/para/internal/entities"
+)
+
+// quantities closer to zero than this are treated as zero, they are left
+// over by floating point arithmetic on fractional shares
+const epsilon = 1e-9
+
+var ErrorOversold = errors.New("sell exceeds the quantity held")
+
+// Holdings works out the position in every security from the trades of an
+// account, given in the order they happened. Sells consume the oldest lots
+// first. The market value uses the price in prices, or the price of the
+// latest buy or sell when a security has none. Amounts are in the currency
+// of the account.
+func Holdings(trades []entities.TradeEntity, securities map[int64]entities.SecurityEntity,
+	prices map[int64]entities.SecurityPrice, currency string) ([]entities.Holding, error) {
+	value := func(amount int) entities.CurrencyValue {
+		return entities.CurrencyValue{Currency: currency, Value: amount}
+	}
+
+	holdings := make(map[int64]*entities.Holding)
+	var order []int64
+
+	for _, trade := range trades {
+		holding, ok := holdings[trade.SecurityId]
+
+		if !ok {
+			security := securities[trade.SecurityId]
+			holding = &entities.Holding{
+				SecurityId:   int(trade.SecurityId),
+				Symbol:       security.Symbol,
+				Name:         security.Name,
+				Price:        value(0),
+				RealizedGain: value(0),
+				Dividends:    value(0),
+				Fees:         value(0),
+				Lots:         []entities.Lot{},
+			}
+			holdings[trade.SecurityId] = holding
+			order = append(order, trade.SecurityId)
+		}
+
+		switch trade.Kind {
+		case entities.TradeBuy:
+			holding.Lots = append(holding.Lots, entities.Lot{
+				TradeId:  int(trade.Id),
+				Date:     trade.Date,
+				Quantity: trade.Quantity,
+				Cost:     trade.Amount,
+			})
+			holding.Quantity += trade.Quantity
+			holding.Price.Value = trade.Price
+			holding.PriceDate = trade.Date
+		case entities.TradeSell:
+			cost, err := consume(holding, trade.Quantity)
+
+			if err != nil {
+				return nil, err
+			}
+
+			holding.RealizedGain.Value += trade.Amount - cost
+			holding.Price.Value = trade.Price
+			holding.PriceDate = trade.Date
+		case entities.TradeDividend:
+			holding.Dividends.Value += trade.Amount
+		case entities.TradeFee:
+			holding.Fees.Value += trade.Amount
+		}
+	}
+
+	result := make([]entities.Holding, 0, len(order))
+
+	for _, securityId := range order {
+		holding := holdings[securityId]
+
+		if price, ok := prices[securityId]; ok && price.Date >= holding.PriceDate {
+			holding.Price.Value = price.Price
+			holding.PriceDate = price.Date
+		}
+
+		costBasis := 0
+
+		for _, lot := range holding.Lots {
+			costBasis += lot.Cost
+		}
+
+		holding.CostBasis = value(costBasis)
+		holding.MarketValue = value(round(holding.Quantity * float64(holding.Price.Value)))
+		holding.UnrealizedGain = value(holding.MarketValue.Value - costBasis)
+
+		result = append(result, *holding)
+	}
+
+	return result, nil
+}
+
+// consume removes quantity from the oldest lots of a holding and returns
+// the cost of what was removed.
+func consume(holding *entities.Holding, quantity float64) (int, error) {
+	if quantity > holding.Quantity+epsilon {
+		return 0, ErrorOversold
+	}
+
+	cost := 0
+
+	for quantity > epsilon && len(holding.Lots) > 0 {
+		lot := &holding.Lots[0]
+
+		if lot.Quantity <= quantity+epsilon {
+			cost += lot.Cost
+			quantity -= lot.Quantity
+			holding.Quantity -= lot.Quantity
+			holding.Lots = holding.Lots[1:]
+			continue
+		}
+
+		part := round(float64(lot.Cost) * quantity / lot.Quantity)
+		cost += part
+		lot.Cost -= part
+		lot.Quantity -= quantity
+		holding.Quantity -= quantity
+		quantity = 0
+	}
+
+	if math.Abs(holding.Quantity) < epsilon {
+		holding.Quantity = 0
+	}
+
+	return cost, nil
+}
+
+func round(value float64) int {
+	return int(math.Round(value))
+}
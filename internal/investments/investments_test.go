@@ -0,0 +1,152 @@
+package investments
+
+import (
+	"testing"
+
+	"github.com/lembata/para/internal/entities"
+)
+
+func TestHoldings(t *testing.T) {
+	securities := map[int64]entities.SecurityEntity{
+		1: {Id: 1, Symbol: "VWCE", Name: "All World"},
+		2: {Id: 2, Symbol: "EUNL", Name: "Core World"},
+	}
+
+	buy := func(id int64, security int64, date string, quantity float64, price int, amount int) entities.TradeEntity {
+		return entities.TradeEntity{Id: id, SecurityId: security, Kind: entities.TradeBuy, Date: date,
+			Quantity: quantity, Price: price, Amount: amount}
+	}
+
+	sell := func(id int64, security int64, date string, quantity float64, price int, amount int) entities.TradeEntity {
+		return entities.TradeEntity{Id: id, SecurityId: security, Kind: entities.TradeSell, Date: date,
+			Quantity: quantity, Price: price, Amount: amount}
+	}
+
+	trades := []entities.TradeEntity{
+		buy(1, 1, "2026-01-01", 10, 100, 1010),
+		buy(2, 1, "2026-02-01", 5, 120, 600),
+		{Id: 3, SecurityId: 1, Kind: entities.TradeDividend, Date: "2026-02-15", Amount: 25},
+		sell(4, 1, "2026-03-01", 12, 150, 1790),
+		{Id: 5, SecurityId: 1, Kind: entities.TradeFee, Date: "2026-03-02", Amount: 5},
+	}
+
+	tests := []struct {
+		name     string
+		trades   []entities.TradeEntity
+		prices   map[int64]entities.SecurityPrice
+		quantity float64
+		price    int
+		cost     int
+		realized int
+		lots     []entities.Lot
+	}{
+		{
+			name: "buys only", trades: trades[:2],
+			quantity: 15, price: 120, cost: 1610,
+			lots: []entities.Lot{{TradeId: 1, Date: "2026-01-01", Quantity: 10, Cost: 1010},
+				{TradeId: 2, Date: "2026-02-01", Quantity: 5, Cost: 600}},
+		},
+		{
+			name: "sell consumes the oldest lot first", trades: trades,
+			quantity: 3, price: 150, cost: 360, realized: 1790 - 1010 - 240,
+			lots: []entities.Lot{{TradeId: 2, Date: "2026-02-01", Quantity: 3, Cost: 360}},
+		},
+		{
+			name: "newer price", trades: trades,
+			prices:   map[int64]entities.SecurityPrice{1: {SecurityId: 1, Date: "2026-03-05", Price: 200}},
+			quantity: 3, price: 200, cost: 360, realized: 540,
+			lots: []entities.Lot{{TradeId: 2, Date: "2026-02-01", Quantity: 3, Cost: 360}},
+		},
+		{
+			name: "older price", trades: trades,
+			prices:   map[int64]entities.SecurityPrice{1: {SecurityId: 1, Date: "2026-02-20", Price: 200}},
+			quantity: 3, price: 150, cost: 360, realized: 540,
+			lots: []entities.Lot{{TradeId: 2, Date: "2026-02-01", Quantity: 3, Cost: 360}},
+		},
+		{
+			name: "sell of a whole lot", trades: []entities.TradeEntity{trades[0], trades[1],
+				sell(4, 1, "2026-03-01", 10, 150, 1500)},
+			quantity: 5, price: 150, cost: 600, realized: 490,
+			lots: []entities.Lot{{TradeId: 2, Date: "2026-02-01", Quantity: 5, Cost: 600}},
+		},
+		{
+			name: "fractional shares sold out", trades: []entities.TradeEntity{
+				buy(1, 1, "2026-01-01", 0.1, 1000, 100),
+				buy(2, 1, "2026-01-02", 0.2, 1000, 200),
+				sell(3, 1, "2026-01-03", 0.3, 1100, 330)},
+			quantity: 0, price: 1100, cost: 0, realized: 30,
+			lots: []entities.Lot{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			holdings, err := Holdings(test.trades, securities, test.prices, "EUR")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(holdings) != 1 {
+				t.Fatalf("got %d holdings, want 1", len(holdings))
+			}
+
+			holding := holdings[0]
+
+			if holding.Symbol != "VWCE" || holding.Quantity != test.quantity || holding.Price.Value != test.price ||
+				holding.CostBasis.Value != test.cost || holding.RealizedGain.Value != test.realized {
+				t.Errorf("got %s %v at %d costing %d with %d realized, want VWCE %v at %d costing %d with %d",
+					holding.Symbol, holding.Quantity, holding.Price.Value, holding.CostBasis.Value,
+					holding.RealizedGain.Value, test.quantity, test.price, test.cost, test.realized)
+			}
+
+			if market := round(test.quantity * float64(test.price)); holding.MarketValue.Value != market ||
+				holding.UnrealizedGain.Value != market-test.cost {
+				t.Errorf("got market value %d with %d unrealized, want %d with %d", holding.MarketValue.Value,
+					holding.UnrealizedGain.Value, market, market-test.cost)
+			}
+
+			if len(holding.Lots) != len(test.lots) {
+				t.Fatalf("got lots %+v, want %+v", holding.Lots, test.lots)
+			}
+
+			for i, lot := range holding.Lots {
+				if lot != test.lots[i] {
+					t.Errorf("got lot %+v, want %+v", lot, test.lots[i])
+				}
+			}
+		})
+	}
+
+	holdings, err := Holdings(trades, securities, nil, "EUR")
+
+	if err != nil || holdings[0].Dividends.Value != 25 || holdings[0].Fees.Value != 5 {
+		t.Errorf("got %+v, %v, want 25 dividends and 5 fees", holdings, err)
+	}
+}
+
+func TestHoldingsOrder(t *testing.T) {
+	trades := []entities.TradeEntity{
+		{Id: 1, SecurityId: 2, Kind: entities.TradeBuy, Date: "2026-01-01", Quantity: 1, Price: 10, Amount: 10},
+		{Id: 2, SecurityId: 1, Kind: entities.TradeBuy, Date: "2026-01-02", Quantity: 1, Price: 10, Amount: 10},
+		{Id: 3, SecurityId: 2, Kind: entities.TradeBuy, Date: "2026-01-03", Quantity: 1, Price: 10, Amount: 10},
+	}
+
+	holdings, err := Holdings(trades, nil, nil, "EUR")
+
+	if err != nil || len(holdings) != 2 || holdings[0].SecurityId != 2 || holdings[1].SecurityId != 1 ||
+		holdings[0].Quantity != 2 {
+		t.Errorf("got %+v, %v, want security 2 with 2 shares before security 1", holdings, err)
+	}
+}
+
+func TestHoldingsOversold(t *testing.T) {
+	trades := []entities.TradeEntity{
+		{Id: 1, SecurityId: 1, Kind: entities.TradeBuy, Date: "2026-01-01", Quantity: 10, Price: 10, Amount: 100},
+		{Id: 2, SecurityId: 1, Kind: entities.TradeSell, Date: "2026-01-02", Quantity: 10.5, Price: 10, Amount: 105},
+	}
+
+	if _, err := Holdings(trades, nil, nil, "EUR"); err != ErrorOversold {
+		t.Errorf("got %v, want %v", err, ErrorOversold)
+	}
+}
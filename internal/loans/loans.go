@@ -0,0 +1,254 @@
+package loans
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/database"
+	log "github.com/lembata/para/pkg/logger"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+var logger = log.NewLogger()
+
+const interestDescription = "Interest"
+
+var (
+	ErrorInvalidPrincipal = errors.New("principal must be positive")
+	ErrorInvalidRate      = errors.New("interest rate is invalid")
+	ErrorInvalidTerm      = errors.New("term must be at least one month")
+)
+
+// ExtraPayment is paid on top of the scheduled principal with the first
+// payment falling on or after Date.
+type ExtraPayment struct {
+	Date   string
+	Amount int
+}
+
+// Extra describes a scenario of payments on top of the schedule. Annuity
+// loans keep their payment and are paid off earlier, linear loans keep
+// their principal part.
+type Extra struct {
+	Monthly  int
+	Payments []ExtraPayment
+}
+
+func Validate(loan entities.LoanEntity) error {
+	if loan.Principal <= 0 {
+		return ErrorInvalidPrincipal
+	}
+
+	if loan.InterestRate < 0 || loan.InterestRate >= 100 {
+		return ErrorInvalidRate
+	}
+
+	if loan.TermMonths < 1 {
+		return ErrorInvalidTerm
+	}
+
+	if _, err := time.Parse(recurrence.DateLayout, loan.StartDate); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Schedule builds the amortization schedule of a loan with the extra
+// payments of a scenario. Payments are due monthly from one month after the
+// start date, interest is a twelfth of the yearly rate on the balance and
+// the last payment settles what is left.
+func Schedule(loan entities.LoanEntity, extra Extra) entities.AmortizationSchedule {
+	start, _ := time.Parse(recurrence.DateLayout, loan.StartDate)
+	rule := recurrence.Rule{Frequency: recurrence.Monthly, Interval: 1, Start: start}
+	rate := loan.InterestRate / 100 / 12
+	balance := loan.Principal
+	payment := annuity(loan.Principal, rate, loan.TermMonths)
+	pending := extra.Payments
+
+	schedule := entities.AmortizationSchedule{Rows: []entities.AmortizationRow{}}
+
+	for n := 1; n <= loan.TermMonths && balance > 0; n++ {
+		date, _ := rule.Occurrence(n)
+		row := entities.AmortizationRow{
+			Number:   n,
+			Date:     date.Format(recurrence.DateLayout),
+			Interest: round(float64(balance) * rate),
+		}
+
+		if loan.Method == entities.LoanLinear {
+			row.Principal = round(float64(loan.Principal) / float64(loan.TermMonths))
+		} else {
+			row.Principal = payment - row.Interest
+		}
+
+		if n == loan.TermMonths || row.Principal > balance {
+			row.Principal = balance
+		}
+
+		row.Extra = extra.Monthly
+		var remaining []ExtraPayment
+
+		for _, extraPayment := range pending {
+			if extraPayment.Date <= row.Date {
+				row.Extra += extraPayment.Amount
+			} else {
+				remaining = append(remaining, extraPayment)
+			}
+		}
+
+		pending = remaining
+		row.Extra = max(0, min(row.Extra, balance-row.Principal))
+		row.Payment = row.Principal + row.Interest + row.Extra
+		balance -= row.Principal + row.Extra
+		row.Balance = balance
+
+		schedule.Rows = append(schedule.Rows, row)
+		schedule.TotalInterest += row.Interest
+		schedule.TotalPaid += row.Payment
+	}
+
+	schedule.Payments = len(schedule.Rows)
+
+	if schedule.Payments > 0 {
+		schedule.PayoffDate = schedule.Rows[schedule.Payments-1].Date
+	}
+
+	return schedule
+}
+
+// Project compares the schedule of a loan with and without the extra
+// payments.
+func Project(loan entities.LoanEntity, extra Extra) entities.LoanProjection {
+	baseline := Schedule(loan, Extra{})
+	scenario := Schedule(loan, extra)
+
+	return entities.LoanProjection{
+		Baseline:      baseline,
+		Scenario:      scenario,
+		InterestSaved: baseline.TotalInterest - scenario.TotalInterest,
+		MonthsSaved:   baseline.Payments - scenario.Payments,
+	}
+}
+
+// RecordPayment splits a payment into a loan account into principal and
+// interest. The interest accrues daily on the outstanding principal since
+// the previous payment and is charged to the loan account by a transaction
+// of its own, so the balance of the account drops by the principal only. A
+// previous split of the payment is replaced, transactions that are pending
+// or do not go into a loan account are left alone. It is called for every
+// created, edited or confirmed transaction.
+func RecordPayment(ctx context.Context, db *database.Database, transactionId int64) error {
+	if err := db.DeleteLoanPayment(ctx, transactionId); err != nil && err != database.ErrorNotFound {
+		return err
+	}
+
+	transaction, err := db.GetTransactionById(ctx, transactionId)
+
+	if err != nil {
+		return err
+	}
+
+	if transaction.Pending || transaction.ToAccountId == nil {
+		return nil
+	}
+
+	loan, err := db.GetLoanByAccount(ctx, *transaction.ToAccountId)
+
+	if err == database.ErrorNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	principalPaid, _, previous, err := db.GetLoanPaymentTotals(ctx, loan.Id, transaction.Date, transaction.Id)
+
+	if err != nil {
+		return err
+	}
+
+	if previous == "" || previous < loan.StartDate {
+		previous = loan.StartDate
+	}
+
+	from, err := time.Parse(recurrence.DateLayout, previous)
+
+	if err != nil {
+		return err
+	}
+
+	to, err := time.Parse(recurrence.DateLayout, transaction.Date)
+
+	if err != nil {
+		return err
+	}
+
+	days := max(0, to.Sub(from).Hours()/24)
+	outstanding := max(0, loan.Principal-principalPaid)
+	interest := round(float64(outstanding) * loan.InterestRate / 100 * days / 365)
+	interest = min(interest, transaction.TotalAmount)
+
+	payment := entities.LoanPaymentEntity{
+		LoanId:        loan.Id,
+		TransactionId: transaction.Id,
+		Principal:     transaction.TotalAmount - interest,
+		Interest:      interest,
+	}
+
+	logger.Debugf("Splitting loan payment %d into %d principal and %d interest",
+		transaction.Id, payment.Principal, payment.Interest)
+
+	if interest > 0 {
+		charge := entities.TransactionEntity{
+			FromAccountId: transaction.ToAccountId,
+			TotalAmount:   interest,
+			Date:          transaction.Date,
+			PayeeId:       transaction.PayeeId,
+			Description:   interestDescription,
+			CreateAt:      time.Now(),
+			UpdateAt:      time.Now(),
+		}
+
+		chargeId, err := db.CreateTransaction(ctx, charge)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = db.CreateItem(ctx, entities.ItemEntity{
+			Name:          interestDescription,
+			Price:         interest,
+			TransactionId: chargeId,
+			CategoryId:    loan.InterestCategoryId,
+			CreateAt:      time.Now(),
+			UpdateAt:      time.Now(),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		payment.InterestTransactionId = &chargeId
+	}
+
+	_, err = db.CreateLoanPayment(ctx, payment)
+
+	return err
+}
+
+// annuity returns the constant monthly payment paying off principal in
+// months at the monthly rate.
+func annuity(principal int, rate float64, months int) int {
+	if rate == 0 {
+		return round(float64(principal) / float64(months))
+	}
+
+	return round(float64(principal) * rate / (1 - math.Pow(1+rate, -float64(months))))
+}
+
+func round(value float64) int {
+	return int(math.Round(value))
+}
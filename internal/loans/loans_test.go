@@ -0,0 +1,157 @@
+package loans
+
+import (
+	"testing"
+
+	"github.com/lembata/para/internal/entities"
+)
+
+func TestValidate(t *testing.T) {
+	valid := entities.LoanEntity{Principal: 100, InterestRate: 3.5, TermMonths: 12, StartDate: "2026-01-15"}
+
+	tests := []struct {
+		name   string
+		change func(l *entities.LoanEntity)
+		want   error
+	}{
+		{"valid", func(l *entities.LoanEntity) {}, nil},
+		{"interest free", func(l *entities.LoanEntity) { l.InterestRate = 0 }, nil},
+		{"no principal", func(l *entities.LoanEntity) { l.Principal = 0 }, ErrorInvalidPrincipal},
+		{"negative rate", func(l *entities.LoanEntity) { l.InterestRate = -1 }, ErrorInvalidRate},
+		{"rate of 100 percent", func(l *entities.LoanEntity) { l.InterestRate = 100 }, ErrorInvalidRate},
+		{"no term", func(l *entities.LoanEntity) { l.TermMonths = 0 }, ErrorInvalidTerm},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			loan := valid
+			test.change(&loan)
+
+			if err := Validate(loan); err != test.want {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	valid.StartDate = "2026-13-01"
+
+	if err := Validate(valid); err == nil {
+		t.Error("accepted an invalid start date")
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	annuity := entities.LoanEntity{Principal: 10000000, InterestRate: 12, TermMonths: 12, StartDate: "2026-01-15"}
+	linear := annuity
+	linear.Method = entities.LoanLinear
+
+	tests := []struct {
+		name          string
+		loan          entities.LoanEntity
+		extra         Extra
+		payments      int
+		payoffDate    string
+		totalInterest int
+		first         entities.AmortizationRow
+		last          entities.AmortizationRow
+	}{
+		{
+			name: "annuity", loan: annuity,
+			payments: 12, payoffDate: "2027-01-15", totalInterest: 661853,
+			first: entities.AmortizationRow{Number: 1, Date: "2026-02-15", Payment: 888488, Principal: 788488,
+				Interest: 100000, Balance: 9211512},
+			last: entities.AmortizationRow{Number: 12, Date: "2027-01-15", Payment: 888485, Principal: 879688,
+				Interest: 8797},
+		},
+		{
+			name: "linear", loan: linear,
+			payments: 12, payoffDate: "2027-01-15", totalInterest: 650000,
+			first: entities.AmortizationRow{Number: 1, Date: "2026-02-15", Payment: 933333, Principal: 833333,
+				Interest: 100000, Balance: 9166667},
+			last: entities.AmortizationRow{Number: 12, Date: "2027-01-15", Payment: 841670, Principal: 833337,
+				Interest: 8333},
+		},
+		{
+			name: "interest free", loan: entities.LoanEntity{Principal: 1200000, TermMonths: 12, StartDate: "2026-01-31"},
+			payments: 12, payoffDate: "2027-01-31", totalInterest: 0,
+			first: entities.AmortizationRow{Number: 1, Date: "2026-02-28", Payment: 100000, Principal: 100000,
+				Balance: 1100000},
+			last: entities.AmortizationRow{Number: 12, Date: "2027-01-31", Payment: 100000, Principal: 100000},
+		},
+		{
+			name: "monthly extra", loan: annuity, extra: Extra{Monthly: 1000000},
+			payments: 6, payoffDate: "2026-07-15", totalInterest: 328122,
+			first: entities.AmortizationRow{Number: 1, Date: "2026-02-15", Payment: 1888488, Principal: 788488,
+				Interest: 100000, Extra: 1000000, Balance: 8211512},
+			last: entities.AmortizationRow{Number: 6, Date: "2026-07-15", Payment: 885682, Principal: 876913,
+				Interest: 8769},
+		},
+		{
+			name: "single extra on the next payment", loan: annuity,
+			extra:    Extra{Payments: []ExtraPayment{{Date: "2026-03-20", Amount: 5000000}}},
+			payments: 6, payoffDate: "2026-07-15", totalInterest: 328632,
+			first: entities.AmortizationRow{Number: 1, Date: "2026-02-15", Payment: 888488, Principal: 788488,
+				Interest: 100000, Balance: 9211512},
+			last: entities.AmortizationRow{Number: 6, Date: "2026-07-15", Payment: 886192, Principal: 877418,
+				Interest: 8774},
+		},
+		{
+			name: "extra above the balance", loan: annuity, extra: Extra{Monthly: 20000000},
+			payments: 1, payoffDate: "2026-02-15", totalInterest: 100000,
+			first: entities.AmortizationRow{Number: 1, Date: "2026-02-15", Payment: 10100000, Principal: 788488,
+				Interest: 100000, Extra: 9211512},
+			last: entities.AmortizationRow{Number: 1, Date: "2026-02-15", Payment: 10100000, Principal: 788488,
+				Interest: 100000, Extra: 9211512},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule := Schedule(test.loan, test.extra)
+
+			if schedule.Payments != test.payments || len(schedule.Rows) != test.payments ||
+				schedule.PayoffDate != test.payoffDate || schedule.TotalInterest != test.totalInterest {
+				t.Fatalf("got %d payments until %s with %d interest, want %d until %s with %d",
+					schedule.Payments, schedule.PayoffDate, schedule.TotalInterest,
+					test.payments, test.payoffDate, test.totalInterest)
+			}
+
+			if first := schedule.Rows[0]; first != test.first {
+				t.Errorf("first payment is %+v, want %+v", first, test.first)
+			}
+
+			if last := schedule.Rows[len(schedule.Rows)-1]; last != test.last {
+				t.Errorf("last payment is %+v, want %+v", last, test.last)
+			}
+
+			principal, paid := 0, 0
+
+			for _, row := range schedule.Rows {
+				principal += row.Principal + row.Extra
+				paid += row.Payment
+			}
+
+			if principal != test.loan.Principal || paid != schedule.TotalPaid ||
+				paid != test.loan.Principal+schedule.TotalInterest {
+				t.Errorf("paid %d principal of %d and %d in total, want %d", principal, test.loan.Principal,
+					paid, schedule.TotalPaid)
+			}
+		})
+	}
+}
+
+func TestProject(t *testing.T) {
+	loan := entities.LoanEntity{Principal: 10000000, InterestRate: 12, TermMonths: 12, StartDate: "2026-01-15"}
+
+	projection := Project(loan, Extra{Monthly: 1000000})
+
+	if projection.InterestSaved != 661853-328122 || projection.MonthsSaved != 6 {
+		t.Errorf("saved %d interest and %d months, want %d and 6", projection.InterestSaved,
+			projection.MonthsSaved, 661853-328122)
+	}
+
+	if projection = Project(loan, Extra{}); projection.InterestSaved != 0 || projection.MonthsSaved != 0 {
+		t.Errorf("saved %d interest and %d months without extra payments", projection.InterestSaved,
+			projection.MonthsSaved)
+	}
+}
@@ -0,0 +1,57 @@
+package reports
+
+import (
+	"github.com/lembata/para/internal/entities"
+)
+
+// GroupAccounts lists the accounts under their groups, in the order given,
+// and sums the balances of each group per currency. Accounts outside a group
+// come last under a group with id 0, it is left out when empty.
+func GroupAccounts(groups []entities.AccountGroupEntity, accounts []entities.AccountRow) []entities.AccountGroupRow {
+	rows := make([]entities.AccountGroupRow, 0, len(groups)+1)
+	index := make(map[int]int, len(groups))
+
+	for _, group := range groups {
+		index[int(group.Id)] = len(rows)
+		rows = append(rows, entities.AccountGroupRow{
+			Id:        int(group.Id),
+			Name:      group.Name,
+			Version:   group.Version,
+			Accounts:  []entities.AccountRow{},
+			Subtotals: []entities.CurrencyValue{},
+		})
+	}
+
+	ungrouped := entities.AccountGroupRow{
+		Accounts:  []entities.AccountRow{},
+		Subtotals: []entities.CurrencyValue{},
+	}
+
+	for _, account := range accounts {
+		row := &ungrouped
+
+		if i, ok := index[account.GroupId]; ok {
+			row = &rows[i]
+		}
+
+		row.Accounts = append(row.Accounts, account)
+		row.Subtotals = addSubtotal(row.Subtotals, account.Balance)
+	}
+
+	if len(ungrouped.Accounts) > 0 {
+		rows = append(rows, ungrouped)
+	}
+
+	return rows
+}
+
+func addSubtotal(subtotals []entities.CurrencyValue, balance entities.CurrencyValue) []entities.CurrencyValue {
+	for i := range subtotals {
+		if subtotals[i].Currency == balance.Currency {
+			subtotals[i].Value += balance.Value
+			return subtotals
+		}
+	}
+
+	return append(subtotals, balance)
+}
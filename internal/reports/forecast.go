@@ -0,0 +1,106 @@
+package reports
+
+import (
+	"time"
+
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+const (
+	AlertBelowZero      = "belowZero"
+	AlertBelowThreshold = "belowThreshold"
+)
+
+// Forecast projects the daily balance of every account from today for the
+// given number of days. Balances start from the confirmed balance of today,
+// pending transactions dated in the past are expected today, later
+// transactions and scheduled occurrences on their own dates. Thresholds maps
+// account ids to the balance below which an alert is raised, accounts missing
+// from it use defaultThreshold.
+func Forecast(accounts []entities.AccountRow, transactions []entities.TransactionEntity,
+	occurrences []entities.ScheduledOccurrence, today time.Time, days int,
+	defaultThreshold int, thresholds map[int]int) []entities.ForecastAccount {
+	first := today.Format(recurrence.DateLayout)
+	changes := make(map[string]map[int]int)
+
+	addChange := func(date string, from *int64, to *int64, amount int) {
+		date = max(date, first)
+
+		if changes[date] == nil {
+			changes[date] = make(map[int]int)
+		}
+
+		if from != nil {
+			changes[date][int(*from)] -= amount
+		}
+
+		if to != nil {
+			changes[date][int(*to)] += amount
+		}
+	}
+
+	for _, transaction := range transactions {
+		addChange(transaction.Date,
+			transaction.FromAccountId, transaction.ToAccountId, transaction.TotalAmount)
+	}
+
+	for _, occurrence := range occurrences {
+		addChange(occurrence.Date, occurrence.FromAccountId, occurrence.ToAccountId, occurrence.Amount.Value)
+	}
+
+	forecasts := make([]entities.ForecastAccount, 0, len(accounts))
+
+	for _, account := range accounts {
+		threshold, ok := thresholds[account.Id]
+
+		if !ok {
+			threshold = defaultThreshold
+		}
+
+		forecast := entities.ForecastAccount{
+			AccountId:   account.Id,
+			AccountName: account.Name,
+			Threshold:   entities.CurrencyValue{Currency: account.Balance.Currency, Value: threshold},
+			Days:        make([]entities.ForecastDay, 0, days+1),
+		}
+
+		balance := account.Balance.Value
+		var previous entities.ForecastDay
+
+		for i := 0; i <= days; i++ {
+			date := today.AddDate(0, 0, i).Format(recurrence.DateLayout)
+			balance += changes[date][account.Id]
+
+			day := entities.ForecastDay{
+				Date:           date,
+				Balance:        entities.CurrencyValue{Currency: account.Balance.Currency, Value: balance},
+				BelowZero:      balance < 0,
+				BelowThreshold: balance < threshold,
+			}
+
+			if day.BelowZero && !previous.BelowZero {
+				forecast.Alerts = append(forecast.Alerts, entities.ForecastAlert{
+					Date: date, Balance: day.Balance, Reason: AlertBelowZero,
+				})
+			}
+
+			if day.BelowThreshold && !previous.BelowThreshold {
+				forecast.Alerts = append(forecast.Alerts, entities.ForecastAlert{
+					Date: date, Balance: day.Balance, Reason: AlertBelowThreshold,
+				})
+			}
+
+			if i == 0 || balance < forecast.Lowest.Balance.Value {
+				forecast.Lowest = day
+			}
+
+			forecast.Days = append(forecast.Days, day)
+			previous = day
+		}
+
+		forecasts = append(forecasts, forecast)
+	}
+
+	return forecasts
+}
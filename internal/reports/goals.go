@@ -0,0 +1,92 @@
+package reports
+
+import (
+	"math"
+	"time"
+
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+// Goal works out the progress of a savings goal from the balance of its
+// account today. A goal is on track while the saved amount keeps up with a
+// straight line from the start amount to the target. The monthly
+// contribution spreads what is missing over the months left, counting the
+// month of the target date.
+func Goal(goal entities.GoalEntity, account entities.AccountRow, today time.Time) entities.GoalRow {
+	value := func(amount int) entities.CurrencyValue {
+		return entities.CurrencyValue{Currency: account.Balance.Currency, Value: amount}
+	}
+
+	saved := GoalSaved(goal, account.Balance.Value)
+
+	row := entities.GoalRow{
+		Id:          int(goal.Id),
+		Name:        goal.Name,
+		AccountId:   int(goal.AccountId),
+		AccountName: account.Name,
+		Target:      value(goal.TargetAmount),
+		TargetDate:  goal.TargetDate,
+		Saved:       value(saved),
+		Remaining:   value(max(0, goal.TargetAmount-saved)),
+		Progress:    1,
+		Expected:    value(goal.TargetAmount),
+		Version:     goal.Version,
+	}
+
+	if goal.Allocation != nil {
+		allocation := value(*goal.Allocation)
+		row.Allocation = &allocation
+	}
+
+	if goal.TargetAmount > 0 {
+		row.Progress = min(1, float64(saved)/float64(goal.TargetAmount))
+	}
+
+	start, errStart := time.Parse(recurrence.DateLayout, goal.StartDate)
+	target, errTarget := time.Parse(recurrence.DateLayout, goal.TargetDate)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	if errStart == nil && errTarget == nil && target.After(start) && today.Before(target) {
+		share := max(0, today.Sub(start).Hours()/target.Sub(start).Hours())
+		row.Expected.Value = goal.StartAmount + int(math.Round(share*float64(goal.TargetAmount-goal.StartAmount)))
+	}
+
+	if errTarget == nil && !today.After(target) {
+		row.MonthsLeft = (target.Year()-today.Year())*12 + int(target.Month()-today.Month()) + 1
+	}
+
+	switch {
+	case saved >= goal.TargetAmount:
+		row.Status = entities.GoalReached
+	case row.MonthsLeft == 0:
+		row.Status = entities.GoalOverdue
+	case saved >= row.Expected.Value:
+		row.Status = entities.GoalOnTrack
+	default:
+		row.Status = entities.GoalBehind
+	}
+
+	switch row.Status {
+	case entities.GoalReached:
+		row.MonthlyContribution = value(0)
+	case entities.GoalOverdue:
+		row.MonthlyContribution = row.Remaining
+	default:
+		row.MonthlyContribution = value(int(math.Ceil(float64(row.Remaining.Value) / float64(row.MonthsLeft))))
+	}
+
+	return row
+}
+
+// GoalSaved returns how much of a balance counts towards a goal: the whole
+// balance, or at most the allocation when the goal earmarks part of it.
+func GoalSaved(goal entities.GoalEntity, balance int) int {
+	saved := max(0, balance)
+
+	if goal.Allocation != nil {
+		saved = min(saved, *goal.Allocation)
+	}
+
+	return saved
+}
@@ -0,0 +1,57 @@
+package reports
+
+import (
+	"github.com/lembata/para/internal/entities"
+)
+
+// NetWorth sums the balances of the accounts included in the net worth per
+// currency. Liability accounts lower the net worth by what is owed whatever
+// the sign of their balance, so a loan can be entered with a positive
+// opening balance and a credit card goes negative as it is spent.
+func NetWorth(accounts []entities.AccountRow) []entities.NetWorth {
+	totals := make(map[string]*entities.NetWorth)
+	var result []*entities.NetWorth
+
+	for _, account := range accounts {
+		if !account.IncludeInNetWorth {
+			continue
+		}
+
+		currency := account.Balance.Currency
+		total, ok := totals[currency]
+
+		if !ok {
+			total = &entities.NetWorth{
+				Assets:      entities.CurrencyValue{Currency: currency},
+				Liabilities: entities.CurrencyValue{Currency: currency},
+				NetWorth:    entities.CurrencyValue{Currency: currency},
+			}
+			totals[currency] = total
+			result = append(result, total)
+		}
+
+		if account.Type.Liability() {
+			total.Liabilities.Value += abs(account.Balance.Value)
+		} else {
+			total.Assets.Value += account.Balance.Value
+		}
+
+		total.NetWorth.Value = total.Assets.Value - total.Liabilities.Value
+	}
+
+	netWorth := make([]entities.NetWorth, 0, len(result))
+
+	for _, total := range result {
+		netWorth = append(netWorth, *total)
+	}
+
+	return netWorth
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
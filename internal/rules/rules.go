@@ -0,0 +1,425 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/database"
+	log "github.com/lembata/para/pkg/logger"
+)
+
+var logger = log.NewLogger()
+
+// Directions a rule can match on.
+const (
+	DirectionIncome   = "income"
+	DirectionExpense  = "expense"
+	DirectionTransfer = "transfer"
+)
+
+// Engine evaluates rules against stored transactions.
+type Engine struct {
+	db    *database.Database
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	entities.RuleEntity
+	memoRegex *regexp.Regexp
+}
+
+// state is the part of a transaction that rules look at and change.
+type state struct {
+	transaction entities.TransactionEntity
+	payeeName   string
+	items       []entities.ItemEntity
+	tags        []string
+}
+
+// Load builds an engine from the enabled rules.
+func Load(ctx context.Context, db *database.Database) (*Engine, error) {
+	rules, err := db.GetRules(ctx, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return New(db, rules)
+}
+
+// New builds an engine evaluating rules in the given order.
+func New(db *database.Database, rules []entities.RuleEntity) (*Engine, error) {
+	engine := &Engine{db: db}
+
+	for _, rule := range rules {
+		compiled := compiledRule{RuleEntity: rule}
+
+		if rule.Conditions.MemoRegex != "" {
+			var err error
+
+			if compiled.memoRegex, err = regexp.Compile(rule.Conditions.MemoRegex); err != nil {
+				return nil, fmt.Errorf("rule %d: %w", rule.Id, err)
+			}
+		}
+
+		engine.rules = append(engine.rules, compiled)
+	}
+
+	return engine, nil
+}
+
+// Apply runs the enabled rules on a transaction and stores the changes. It is
+// called for every created or imported transaction.
+func Apply(ctx context.Context, db *database.Database, transactionId int64) error {
+	engine, err := Load(ctx, db)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = engine.Run(ctx, transactionId, true)
+	return err
+}
+
+// Validate checks that a rule can be evaluated and does something.
+func Validate(rule entities.RuleEntity) error {
+	conditions := rule.Conditions
+	actions := rule.Actions
+
+	if rule.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if conditions.MemoRegex != "" {
+		if _, err := regexp.Compile(conditions.MemoRegex); err != nil {
+			return fmt.Errorf("invalid memo regex: %w", err)
+		}
+	}
+
+	switch conditions.Direction {
+	case "", DirectionIncome, DirectionExpense, DirectionTransfer:
+	default:
+		return fmt.Errorf("invalid direction %q", conditions.Direction)
+	}
+
+	if conditions.MinAmount != nil && conditions.MaxAmount != nil && *conditions.MinAmount > *conditions.MaxAmount {
+		return errors.New("minimum amount is larger than the maximum amount")
+	}
+
+	if actions.CategoryId == 0 && actions.PayeeId == 0 && len(actions.Tags) == 0 &&
+		actions.Description == "" && actions.TransferAccountId == 0 {
+		return errors.New("an action is required")
+	}
+
+	return nil
+}
+
+// Run evaluates the rules against a transaction and returns what they change.
+// The changes are only stored when apply is set.
+func (e *Engine) Run(ctx context.Context, transactionId int64, apply bool) (entities.RuleResult, error) {
+	result := entities.RuleResult{TransactionId: int(transactionId)}
+
+	if len(e.rules) == 0 {
+		return result, nil
+	}
+
+	original, err := e.load(ctx, transactionId)
+
+	if err != nil {
+		return result, err
+	}
+
+	current := original
+	current.items = append([]entities.ItemEntity(nil), original.items...)
+	current.tags = append([]string(nil), original.tags...)
+
+	for _, rule := range e.rules {
+		if !rule.matches(&current) {
+			continue
+		}
+
+		logger.Debugf("Rule %d matches transaction %d", rule.Id, transactionId)
+		result.RuleIds = append(result.RuleIds, int(rule.Id))
+
+		if err := e.perform(ctx, rule.Actions, &current); err != nil {
+			return result, err
+		}
+
+		if rule.StopProcessing {
+			break
+		}
+	}
+
+	result.Changes = changes(original, current)
+
+	if apply && len(result.Changes) > 0 {
+		err = e.store(ctx, original, current)
+	}
+
+	return result, err
+}
+
+func (e *Engine) load(ctx context.Context, transactionId int64) (state, error) {
+	var s state
+	var err error
+
+	if s.transaction, err = e.db.GetTransactionById(ctx, transactionId); err != nil {
+		return s, err
+	}
+
+	if s.payeeName, err = e.payeeName(ctx, s.transaction.PayeeId); err != nil {
+		return s, err
+	}
+
+	if s.items, err = e.db.GetItems(ctx, transactionId); err != nil {
+		return s, err
+	}
+
+	s.tags, err = e.db.GetTransactionTags(ctx, transactionId)
+	return s, err
+}
+
+func (e *Engine) payeeName(ctx context.Context, payeeId *int64) (string, error) {
+	if payeeId == nil {
+		return "", nil
+	}
+
+	payee, err := e.db.GetPayeeById(ctx, *payeeId)
+
+	if err == database.ErrorNotFound {
+		return "", nil
+	}
+
+	return payee.Name, err
+}
+
+func (r *compiledRule) matches(s *state) bool {
+	conditions := r.Conditions
+	transaction := s.transaction
+
+	if conditions.PayeeId != 0 && !sameId(transaction.PayeeId, conditions.PayeeId) {
+		return false
+	}
+
+	if conditions.PayeeName != "" && !containsFold(s.payeeName, conditions.PayeeName) {
+		return false
+	}
+
+	if conditions.Memo != "" &&
+		!containsFold(transaction.Description, conditions.Memo) &&
+		!containsFold(transaction.Notes, conditions.Memo) {
+		return false
+	}
+
+	if r.memoRegex != nil &&
+		!r.memoRegex.MatchString(transaction.Description) &&
+		!r.memoRegex.MatchString(transaction.Notes) {
+		return false
+	}
+
+	if conditions.MinAmount != nil && transaction.TotalAmount < *conditions.MinAmount {
+		return false
+	}
+
+	if conditions.MaxAmount != nil && transaction.TotalAmount > *conditions.MaxAmount {
+		return false
+	}
+
+	if conditions.AccountId != 0 &&
+		!sameId(transaction.FromAccountId, conditions.AccountId) &&
+		!sameId(transaction.ToAccountId, conditions.AccountId) {
+		return false
+	}
+
+	if conditions.Direction != "" && direction(transaction) != conditions.Direction {
+		return false
+	}
+
+	return true
+}
+
+func (e *Engine) perform(ctx context.Context, actions entities.RuleActions, s *state) error {
+	if actions.PayeeId != 0 {
+		name, err := e.payeeName(ctx, &actions.PayeeId)
+
+		if err != nil {
+			return err
+		}
+
+		if name != "" {
+			payeeId := actions.PayeeId
+			s.transaction.PayeeId = &payeeId
+			s.payeeName = name
+		}
+	}
+
+	if actions.Description != "" {
+		s.transaction.Description = actions.Description
+	}
+
+	if actions.TransferAccountId != 0 {
+		transferAccountId := actions.TransferAccountId
+
+		if s.transaction.FromAccountId == nil && !sameId(s.transaction.ToAccountId, transferAccountId) {
+			s.transaction.FromAccountId = &transferAccountId
+		} else if s.transaction.ToAccountId == nil && !sameId(s.transaction.FromAccountId, transferAccountId) {
+			s.transaction.ToAccountId = &transferAccountId
+		}
+	}
+
+	if actions.CategoryId != 0 {
+		if len(s.items) == 0 {
+			// categories live on items, a transaction without items gets one
+			// covering the whole amount
+			s.items = append(s.items, entities.ItemEntity{
+				Name:          itemName(s),
+				Price:         s.transaction.TotalAmount,
+				TransactionId: s.transaction.Id,
+			})
+		}
+
+		for i := range s.items {
+			categoryId := actions.CategoryId
+			s.items[i].CategoryId = &categoryId
+		}
+	}
+
+	for _, tag := range actions.Tags {
+		tag = strings.TrimSpace(tag)
+
+		if tag != "" && !hasTag(s.tags, tag) {
+			s.tags = append(s.tags, tag)
+		}
+	}
+
+	return nil
+}
+
+func (e *Engine) store(ctx context.Context, original state, current state) error {
+	if current.transaction != original.transaction {
+		current.transaction.UpdateAt = time.Now()
+
+		if _, err := e.db.EditTransaction(ctx, current.transaction); err != nil {
+			return err
+		}
+	}
+
+	for i, item := range current.items {
+		if i >= len(original.items) {
+			item.CreateAt = time.Now()
+			item.UpdateAt = time.Now()
+
+			if _, err := e.db.CreateItem(ctx, item); err != nil {
+				return err
+			}
+		} else if idValue(item.CategoryId) != idValue(original.items[i].CategoryId) {
+			if err := e.db.SetItemCategory(ctx, item.Id, item.CategoryId); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(current.tags) > len(original.tags) {
+		return e.db.AddTransactionTags(ctx, current.transaction.Id, current.tags[len(original.tags):])
+	}
+
+	return nil
+}
+
+func changes(original state, current state) []entities.RuleChange {
+	var result []entities.RuleChange
+
+	add := func(field string, from string, to string) {
+		if from != to {
+			result = append(result, entities.RuleChange{Field: field, From: from, To: to})
+		}
+	}
+
+	add("payee", original.payeeName, current.payeeName)
+	add("description", original.transaction.Description, current.transaction.Description)
+	add("fromAccountId", idString(original.transaction.FromAccountId), idString(current.transaction.FromAccountId))
+	add("toAccountId", idString(original.transaction.ToAccountId), idString(current.transaction.ToAccountId))
+
+	for i, item := range current.items {
+		from := ""
+
+		if i < len(original.items) {
+			from = idString(original.items[i].CategoryId)
+		}
+
+		if to := idString(item.CategoryId); from != to {
+			result = append(result, entities.RuleChange{
+				ItemId: int(item.Id),
+				Field:  "categoryId",
+				From:   from,
+				To:     to,
+			})
+		}
+	}
+
+	add("tags", strings.Join(original.tags, ", "), strings.Join(current.tags, ", "))
+
+	return result
+}
+
+func direction(transaction entities.TransactionEntity) string {
+	switch {
+	case transaction.FromAccountId == nil:
+		return DirectionIncome
+	case transaction.ToAccountId == nil:
+		return DirectionExpense
+	default:
+		return DirectionTransfer
+	}
+}
+
+func itemName(s *state) string {
+	switch {
+	case s.transaction.Description != "":
+		return s.transaction.Description
+	case s.payeeName != "":
+		return s.payeeName
+	default:
+		return "Transaction"
+	}
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, existing := range tags {
+		if strings.EqualFold(existing, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsFold(s string, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func sameId(id *int64, value int64) bool {
+	return id != nil && *id == value
+}
+
+func idValue(id *int64) int64 {
+	if id == nil {
+		return 0
+	}
+
+	return *id
+}
+
+func idString(id *int64) string {
+	if id == nil {
+		return ""
+	}
+
+	return strconv.FormatInt(*id, 10)
+}
@@ -0,0 +1,250 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/lembata/para/internal/duplicates"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/internal/rules"
+	"github.com/lembata/para/pkg/database"
+	log "github.com/lembata/para/pkg/logger"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+var logger = log.NewLogger()
+
+// how often the scheduler looks for due occurrences
+const checkInterval = time.Hour
+
+// Scheduler turns the due occurrences of scheduled transactions into
+// transactions.
+type Scheduler struct {
+	db      *database.Database
+	trigger chan struct{}
+}
+
+func New(db *database.Database) *Scheduler {
+	return &Scheduler{
+		db:      db,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// Start runs the scheduler in the background until ctx is cancelled. Due
+// occurrences are created right away, then every checkInterval or whenever
+// Trigger is called.
+func (s *Scheduler) Start(ctx context.Context) {
+	logger.Info("Starting transaction scheduler")
+
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.Run(ctx); err != nil {
+				logger.Errorf("failed to create scheduled transactions: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				logger.Info("Stopping transaction scheduler")
+				return
+			case <-ticker.C:
+			case <-s.trigger:
+			}
+		}
+	}()
+}
+
+// Trigger asks the background scheduler to look for due occurrences without
+// waiting for the next check.
+func (s *Scheduler) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run creates a transaction for every occurrence due today or earlier that has
+// not been created yet.
+func (s *Scheduler) Run(ctx context.Context) error {
+	return s.db.WithTxn(ctx, true, func(ctx context.Context) error {
+		schedules, err := s.db.GetScheduledTransactions(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		today := time.Now()
+
+		for _, scheduled := range schedules {
+			rule, err := Rule(scheduled)
+
+			if err != nil {
+				logger.Warnf("skipping scheduled transaction %d: %v", scheduled.Id, err)
+				continue
+			}
+
+			created := scheduled.Occurrences
+
+			for {
+				date, ok := rule.Occurrence(created)
+
+				if !ok || date.After(today) {
+					break
+				}
+
+				if err := s.createOccurrence(ctx, scheduled, date); err != nil {
+					return err
+				}
+
+				created++
+			}
+
+			if created != scheduled.Occurrences {
+				logger.Infof("Created %d occurrences of scheduled transaction %d",
+					created-scheduled.Occurrences, scheduled.Id)
+
+				if err := s.db.SetScheduledOccurrences(ctx, scheduled.Id, created); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *Scheduler) createOccurrence(ctx context.Context, scheduled entities.ScheduledTransactionEntity, date time.Time) error {
+	scheduledId := scheduled.Id
+
+	transactionId, err := s.db.CreateTransaction(ctx, entities.TransactionEntity{
+		FromAccountId:          scheduled.FromAccountId,
+		ToAccountId:            scheduled.ToAccountId,
+		TotalAmount:            scheduled.Amount,
+		Date:                   date.Format(recurrence.DateLayout),
+		Description:            scheduled.Name,
+		Pending:                scheduled.Pending,
+		ScheduledTransactionId: &scheduledId,
+		CreateAt:               time.Now(),
+		UpdateAt:               time.Now(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.CreateItem(ctx, entities.ItemEntity{
+		Name:          scheduled.Name,
+		Price:         scheduled.Amount,
+		TransactionId: transactionId,
+		CategoryId:    scheduled.CategoryId,
+		CreateAt:      time.Now(),
+		UpdateAt:      time.Now(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := rules.Apply(ctx, s.db, transactionId); err != nil {
+		return err
+	}
+
+	return duplicates.Check(ctx, s.db, transactionId)
+}
+
+// Rule builds the recurrence rule of a scheduled transaction.
+func Rule(scheduled entities.ScheduledTransactionEntity) (recurrence.Rule, error) {
+	rule := recurrence.Rule{
+		Frequency:  recurrence.Frequency(scheduled.Frequency),
+		Interval:   scheduled.Interval,
+		Weekday:    time.Weekday(scheduled.Weekday),
+		DayOfMonth: scheduled.DayOfMonth,
+		Count:      scheduled.MaxOccurrences,
+	}
+
+	var err error
+
+	if rule.Start, err = time.ParseInLocation(recurrence.DateLayout, scheduled.StartDate, time.Local); err != nil {
+		return rule, err
+	}
+
+	if scheduled.EndDate != "" {
+		if rule.End, err = time.ParseInLocation(recurrence.DateLayout, scheduled.EndDate, time.Local); err != nil {
+			return rule, err
+		}
+	}
+
+	return rule, rule.Validate()
+}
+
+// Reschedule carries the occurrences current already created over to its
+// edited version. The occurrences are numbered by the rule, so when the rule
+// falls on other dates it restarts on the next due date and counts from
+// there, instead of filling in the past under the new rule.
+func Reschedule(current entities.ScheduledTransactionEntity, edited entities.ScheduledTransactionEntity) (entities.ScheduledTransactionEntity, error) {
+	edited.Occurrences = current.Occurrences
+	previous, err := Rule(current)
+
+	if err != nil {
+		// an invalid rule never created anything
+		return edited, nil
+	}
+
+	rule, err := Rule(edited)
+
+	if err != nil || rule.SameDates(previous) {
+		return edited, err
+	}
+
+	if rule, err = rule.Continue(previous, current.Occurrences); err != nil {
+		return edited, err
+	}
+
+	edited.StartDate = rule.Start.Format(recurrence.DateLayout)
+	edited.MaxOccurrences = rule.Count
+	edited.Occurrences = 0
+
+	return edited, nil
+}
+
+// Upcoming lists the occurrences between from and to that have not been
+// turned into transactions yet.
+func Upcoming(schedules []entities.ScheduledTransactionEntity, from time.Time, to time.Time) []entities.ScheduledOccurrence {
+	var occurrences []entities.ScheduledOccurrence
+
+	for _, scheduled := range schedules {
+		rule, err := Rule(scheduled)
+
+		if err != nil {
+			logger.Warnf("skipping scheduled transaction %d: %v", scheduled.Id, err)
+			continue
+		}
+
+		indexes, dates := rule.Between(from, to)
+
+		for i, date := range dates {
+			if indexes[i] < scheduled.Occurrences {
+				continue
+			}
+
+			occurrences = append(occurrences, entities.ScheduledOccurrence{
+				ScheduledTransactionId: int(scheduled.Id),
+				Name:                   scheduled.Name,
+				Date:                   date.Format(recurrence.DateLayout),
+				FromAccountId:          scheduled.FromAccountId,
+				ToAccountId:            scheduled.ToAccountId,
+				Amount:                 entities.CurrencyValue{Currency: scheduled.Currency, Value: scheduled.Amount},
+			})
+		}
+	}
+
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].Date < occurrences[j].Date
+	})
+
+	return occurrences
+}
@@ -0,0 +1,118 @@
+package valuations
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+// Depreciation methods.
+const (
+	// StraightLine loses the same amount every year until the salvage value
+	// is reached at the end of the useful life.
+	StraightLine = "straightLine"
+	// DecliningBalance loses a fixed share of the remaining value every
+	// year, never dropping below the salvage value.
+	DecliningBalance = "decliningBalance"
+)
+
+// the longest depreciation schedule generated
+const maxDepreciationYears = 50
+
+var (
+	ErrorInvalidMethod = errors.New("invalid depreciation method")
+	ErrorInvalidLife   = errors.New("useful life must be between 1 and 50 years")
+	ErrorInvalidRate   = errors.New("depreciation rate must be between 0 and 100 percent")
+)
+
+// Interpolate returns the value on a day from the valuations of one account,
+// ordered by date. Between two valuations the value changes linearly, after
+// the last one it stays. The second return value is false before the first
+// valuation.
+func Interpolate(valuations []entities.ValuationEntity, date string) (int, bool) {
+	if len(valuations) == 0 || date < valuations[0].Date {
+		return 0, false
+	}
+
+	for i := 1; i < len(valuations); i++ {
+		if date >= valuations[i].Date {
+			continue
+		}
+
+		previous := valuations[i-1]
+		next := valuations[i]
+		from, errFrom := time.Parse(recurrence.DateLayout, previous.Date)
+		to, errTo := time.Parse(recurrence.DateLayout, next.Date)
+		day, errDay := time.Parse(recurrence.DateLayout, date)
+
+		if errFrom != nil || errTo != nil || errDay != nil {
+			return previous.Value, true
+		}
+
+		share := day.Sub(from).Hours() / to.Sub(from).Hours()
+		return previous.Value + int(math.Round(share*float64(next.Value-previous.Value))), true
+	}
+
+	return valuations[len(valuations)-1].Value, true
+}
+
+// Apply replaces the balances of the accounts with valuations by their
+// interpolated value on a day. valuations holds those of every account,
+// ordered by account and date.
+func Apply(accounts []entities.AccountRow, valuations []entities.ValuationEntity, date string) []entities.AccountRow {
+	byAccount := make(map[int][]entities.ValuationEntity)
+
+	for _, valuation := range valuations {
+		byAccount[int(valuation.AccountId)] = append(byAccount[int(valuation.AccountId)], valuation)
+	}
+
+	result := make([]entities.AccountRow, len(accounts))
+
+	for i, account := range accounts {
+		if value, ok := Interpolate(byAccount[account.Id], date); ok {
+			account.Balance.Value = value
+		}
+
+		result[i] = account
+	}
+
+	return result
+}
+
+// Depreciate projects the yearly value of something bought for value on the
+// start date, from the purchase until the end of its useful life.
+// Declining balance uses rate, the yearly loss in percent.
+func Depreciate(value int, start time.Time, method string, lifeYears int, rate float64, salvage int) ([]entities.ValuationEntity, error) {
+	if lifeYears < 1 || lifeYears > maxDepreciationYears {
+		return nil, ErrorInvalidLife
+	}
+
+	salvage = min(max(salvage, 0), value)
+	points := []entities.ValuationEntity{{Date: start.Format(recurrence.DateLayout), Value: value}}
+	current := value
+
+	for year := 1; year <= lifeYears; year++ {
+		switch method {
+		case StraightLine:
+			current = value - int(math.Round(float64(value-salvage)*float64(year)/float64(lifeYears)))
+		case DecliningBalance:
+			if rate <= 0 || rate >= 100 {
+				return nil, ErrorInvalidRate
+			}
+
+			current = max(salvage, int(math.Round(float64(current)*(1-rate/100))))
+		default:
+			return nil, ErrorInvalidMethod
+		}
+
+		points = append(points, entities.ValuationEntity{
+			Date:  start.AddDate(year, 0, 0).Format(recurrence.DateLayout),
+			Value: current,
+		})
+	}
+
+	return points, nil
+}
@@ -0,0 +1,129 @@
+package attachments
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrorInvalidHash = errors.New("invalid hash")
+
+// Store keeps files in a directory named after their SHA-256 hash, so the
+// same content is only stored once. Files are spread over sub directories
+// named after the first two characters of the hash.
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Store{dir: dir}, nil
+}
+
+// Put stores the content of r and returns its hash and size. Content that is
+// already stored is not written again.
+func (s *Store) Put(r io.Reader) (string, int64, error) {
+	temp, err := os.CreateTemp(s.dir, "upload-*")
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	defer func() {
+		_ = temp.Close()
+		_ = os.Remove(temp.Name())
+	}()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(temp, hash), r)
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	if err := temp.Close(); err != nil {
+		return "", 0, err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	path := s.path(sum)
+
+	if _, err := os.Stat(path); err == nil {
+		return sum, size, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", 0, err
+	}
+
+	return sum, size, os.Rename(temp.Name(), path)
+}
+
+func (s *Store) Open(hash string) (*os.File, error) {
+	if !validHash(hash) {
+		return nil, ErrorInvalidHash
+	}
+
+	return os.Open(s.path(hash))
+}
+
+// Remove deletes a stored file, removing a missing file is not an error.
+func (s *Store) Remove(hash string) error {
+	if !validHash(hash) {
+		return ErrorInvalidHash
+	}
+
+	if err := os.Remove(s.path(hash)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// Cleanup removes the stored files whose hash is not in referenced and
+// returns how many were removed.
+func (s *Store) Cleanup(referenced map[string]bool) (int, error) {
+	removed := 0
+
+	err := filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		name := entry.Name()
+
+		if referenced[name] || !validHash(name) {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+
+		removed++
+		return nil
+	})
+
+	return removed, err
+}
+
+func (s *Store) path(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(hash)
+	return err == nil && strings.ToLower(hash) == hash
+}
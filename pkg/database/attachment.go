@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+func (db *Database) CreateAttachment(ctx context.Context, attachment entities.AttachmentEntity) (int64, error) {
+	logger.Debugf("Creating attachment: %v", attachment)
+
+	sqler := squirrel.Insert("attachments").
+		Columns("hash", "name", "mime_type", "size", "transaction_id", "account_id", "created_at").
+		Values(attachment.Hash, attachment.Name, attachment.MimeType, attachment.Size,
+			attachment.TransactionId, attachment.AccountId, attachment.CreateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, auditCreated(ctx, "attachments", id)
+}
+
+// DeleteAttachment removes an attachment and reports whether its content is
+// still used by another attachment or by an operation in the history, which
+// may bring the attachment back.
+func (db *Database) DeleteAttachment(ctx context.Context, id int64) (entities.AttachmentEntity, bool, error) {
+	logger.Debugf("Deleting attachment: %d", id)
+
+	attachment, err := db.GetAttachmentById(ctx, id)
+
+	if err != nil {
+		return attachment, false, err
+	}
+
+	where := squirrel.Eq{"id": id}
+
+	if _, err := execAudited(ctx, "attachments", where, squirrel.Delete("attachments").Where(where)); err != nil {
+		return attachment, false, err
+	}
+
+	rows, err := query(ctx, attachedHashes().Where("hash = ?", attachment.Hash).Limit(1))
+
+	if err != nil {
+		return attachment, false, err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	return attachment, rows.Next(), nil
+}
+
+func (db *Database) GetAttachmentById(ctx context.Context, id int64) (entities.AttachmentEntity, error) {
+	attachments, err := queryAttachments(ctx, selectAttachments().Where("id = ?", id))
+
+	if err != nil {
+		return entities.AttachmentEntity{}, err
+	}
+
+	if len(attachments) == 0 {
+		return entities.AttachmentEntity{}, ErrorNotFound
+	}
+
+	return attachments[0], nil
+}
+
+// GetAttachments returns the attachments of a transaction or of an account.
+func (db *Database) GetAttachments(ctx context.Context, transactionId int64, accountId int64) ([]entities.AttachmentEntity, error) {
+	logger.Debugf("Getting attachments of transaction %d, account %d", transactionId, accountId)
+
+	sqler := selectAttachments().OrderBy("created_at", "id")
+
+	if transactionId != 0 {
+		sqler = sqler.Where("transaction_id = ?", transactionId)
+	}
+
+	if accountId != 0 {
+		sqler = sqler.Where("account_id = ?", accountId)
+	}
+
+	return queryAttachments(ctx, sqler)
+}
+
+// GetAttachmentHashes returns the hashes of all stored content that is still
+// attached to something, or was attached by an operation in the history.
+func (db *Database) GetAttachmentHashes(ctx context.Context) (map[string]bool, error) {
+	rows, err := query(ctx, attachedHashes().Distinct())
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	hashes := make(map[string]bool)
+
+	for rows.Next() {
+		var hash string
+
+		if err := rows.Scan(&hash); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		hashes[hash] = true
+	}
+
+	return hashes, nil
+}
+
+// attachedHashes selects the hashes of the attachments and of the attachments
+// in the audit log of the operations that can still be undone or redone.
+func attachedHashes() squirrel.SelectBuilder {
+	logged := squirrel.Select("json_extract(coalesce(a.after, a.before), '$.hash') as hash").
+		From("audit_log a").
+		Join("history h on h.request_id = a.request_id and h.actor = a.actor").
+		Where("a.entity = ?", auditedTables["attachments"])
+
+	return squirrel.Select("hash").
+		FromSelect(squirrel.Select("hash").From("attachments").SuffixExpr(squirrel.ConcatExpr("union all ", logged)), "u")
+}
+
+func selectAttachments() squirrel.SelectBuilder {
+	return squirrel.Select("id", "hash", "name", "mime_type", "size", "transaction_id", "account_id", "created_at").
+		From("attachments")
+}
+
+func queryAttachments(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.AttachmentEntity, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	attachments := []entities.AttachmentEntity{}
+
+	for rows.Next() {
+		var row entities.AttachmentEntity
+
+		if err := rows.Scan(&row.Id, &row.Hash, &row.Name, &row.MimeType, &row.Size,
+			&row.TransactionId, &row.AccountId, &row.CreateAt); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		attachments = append(attachments, row)
+	}
+
+	return attachments, nil
+}
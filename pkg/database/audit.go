@@ -0,0 +1,378 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+// auditActorSystem is the actor of changes made outside a request, like the
+// scheduler creating transactions.
+const auditActorSystem = "system"
+
+// auditedTables maps the audited tables to the entity they are logged as.
+// Categories belong here once they can be changed.
+var auditedTables = map[string]string{
+	"accounts":        "account",
+	"transactions":    "transaction",
+	"items":           "item",
+	"loan_payments":   "loanPayment",
+	"security_trades": "securityTrade",
+	"duplicate_pairs": "duplicatePair",
+	"attachments":     "attachment",
+}
+
+// tagLink is the join table linking the rows of a table to their tags.
+type tagLink struct {
+	table  string
+	column string
+}
+
+// taggedTables are the audited tables that can be tagged. Their rows are
+// audited with the sorted names of their tags in a tags column.
+var taggedTables = map[string]tagLink{
+	"transactions": {"transaction_tags", "transaction_id"},
+	"items":        {"item_tags", "item_id"},
+}
+
+type auditSource struct {
+	actor     string
+	requestId string
+	// operation names the request in the history of the actor, changes
+	// without it can not be undone.
+	operation string
+}
+
+// auditRow is a row of an audited table by column.
+type auditRow map[string]interface{}
+
+// WithAuditSource tags the changes made with ctx with who made them and the
+// request they were made in. The request is added to the history of actor as
+// operation, so that it can be undone.
+func WithAuditSource(ctx context.Context, actor string, requestId string, operation string) context.Context {
+	return context.WithValue(ctx, auditKey, auditSource{actor: actor, requestId: requestId, operation: operation})
+}
+
+// GetAuditLog returns the audit entries matching filter, the latest first.
+func (db *Database) GetAuditLog(ctx context.Context, filter entities.AuditFilter, offset uint64, limit uint64) ([]entities.AuditEntry, error) {
+	logger.Debugf("Getting audit log: %v", filter)
+
+	sqler := squirrel.Select("id", "created_at", "actor", "request_id", "entity", "entity_id",
+		"action", "before", "after").
+		From("audit_log").
+		OrderBy("id desc").
+		Offset(offset).
+		Limit(limit)
+
+	if filter.Entity != "" {
+		sqler = sqler.Where("entity = ?", filter.Entity)
+	}
+
+	if filter.EntityId != 0 {
+		sqler = sqler.Where("entity_id = ?", filter.EntityId)
+	}
+
+	if filter.Actor != "" {
+		sqler = sqler.Where("actor = ?", filter.Actor)
+	}
+
+	if filter.RequestId != "" {
+		sqler = sqler.Where("request_id = ?", filter.RequestId)
+	}
+
+	if filter.FromDate != "" {
+		sqler = sqler.Where("date(created_at) >= ?", filter.FromDate)
+	}
+
+	if filter.ToDate != "" {
+		sqler = sqler.Where("date(created_at) <= ?", filter.ToDate)
+	}
+
+	return queryAuditLog(ctx, sqler)
+}
+
+func queryAuditLog(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.AuditEntry, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var entries []entities.AuditEntry
+
+	for rows.Next() {
+		var row entities.AuditEntry
+		var before, after *string
+
+		if err := rows.Scan(&row.Id, &row.CreateAt, &row.Actor, &row.RequestId, &row.Entity, &row.EntityId,
+			&row.Action, &before, &after); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		row.Before = rawJSON(before)
+		row.After = rawJSON(after)
+		entries = append(entries, row)
+	}
+
+	return entries, nil
+}
+
+// execAudited executes stmt and records what it did to the rows of table
+// matching where, in the same transaction.
+func execAudited(ctx context.Context, table string, where squirrel.Sqlizer, stmt sqler) (sql.Result, error) {
+	var result sql.Result
+
+	err := auditChange(ctx, table, where, func() error {
+		var err error
+		result, err = exec(ctx, stmt)
+		return err
+	})
+
+	return result, err
+}
+
+// auditChange runs change and records what it did to the rows of table
+// matching where, like the tags of a transaction. Audited changes of the same
+// table must not run inside change, they would be logged twice.
+func auditChange(ctx context.Context, table string, where squirrel.Sqlizer, change func() error) error {
+	if _, ok := auditedTables[table]; !ok {
+		return change()
+	}
+
+	ids, err := queryIds(ctx, squirrel.Select("id").From(table).Where(where))
+
+	if err != nil {
+		return err
+	}
+
+	before, err := snapshot(ctx, table, ids)
+
+	if err != nil {
+		return err
+	}
+
+	if err := change(); err != nil {
+		return err
+	}
+
+	return audit(ctx, table, ids, before)
+}
+
+// execVersioned executes the update of the row of table with id that was
+// read at version, and moves it to the next version. It fails with
+// ErrorConflict when the row changed since it was read.
+func execVersioned(ctx context.Context, table string, id int64, version int, stmt squirrel.UpdateBuilder) (sql.Result, error) {
+	result, err := execAudited(ctx, table, squirrel.Eq{"id": id}, stmt.
+		Set("version", squirrel.Expr("version + 1")).
+		Where("version = ?", version))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		if _, err := queryId(ctx, squirrel.Select("id").From(table).Where("id = ?", id)); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrorConflict
+	}
+
+	return result, nil
+}
+
+// auditCreated records the rows of table just created.
+func auditCreated(ctx context.Context, table string, ids ...int64) error {
+	return audit(ctx, table, ids, nil)
+}
+
+// snapshot reads the rows of table by id, with their tags when the table
+// can be tagged.
+func snapshot(ctx context.Context, table string, ids []int64) (map[int64]auditRow, error) {
+	snapshots := make(map[int64]auditRow, len(ids))
+
+	if len(ids) == 0 {
+		return snapshots, nil
+	}
+
+	sqler := squirrel.Select("*").From(table).Where(squirrel.Eq{"id": ids})
+	link, tagged := taggedTables[table]
+
+	if tagged {
+		sqler = sqler.Column(fmt.Sprintf("ifnull((select group_concat(g.name, char(31)) from %s j "+
+			"join tags g on g.id = j.tag_id where j.%s = %s.id), '') as tags", link.table, link.column, table))
+	}
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	columns, err := rows.Columns()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		row := make(auditRow, len(columns))
+
+		for i, column := range columns {
+			if value, ok := values[i].([]byte); ok {
+				row[column] = string(value)
+			} else {
+				row[column] = values[i]
+			}
+		}
+
+		if tagged {
+			names, _ := row["tags"].(string)
+			tags := splitTags(names)
+			sort.Strings(tags)
+			row["tags"] = tags
+		}
+
+		if id, ok := row["id"].(int64); ok {
+			snapshots[id] = row
+		}
+	}
+
+	return snapshots, nil
+}
+
+// audit compares the rows of table before a change with how they are now and
+// logs an entry for every row that was created, changed or deleted. The
+// updated_at and version columns alone are not a change.
+func audit(ctx context.Context, table string, ids []int64, before map[int64]auditRow) error {
+	entity, ok := auditedTables[table]
+
+	if !ok {
+		return nil
+	}
+
+	after, err := snapshot(ctx, table, ids)
+
+	if err != nil {
+		return err
+	}
+
+	source, ok := ctx.Value(auditKey).(auditSource)
+
+	if !ok {
+		source = auditSource{actor: auditActorSystem}
+	}
+
+	logged := false
+
+	for _, id := range ids {
+		previous, current := before[id], after[id]
+		var action string
+
+		switch {
+		case previous == nil && current == nil:
+			continue
+		case previous == nil:
+			action = entities.AuditCreate
+		case current == nil:
+			action = entities.AuditDelete
+		default:
+			action = entities.AuditUpdate
+			previous, current = diff(previous, current)
+
+			if len(current) == 0 {
+				continue
+			}
+		}
+
+		previousJSON, err := marshalRow(previous)
+
+		if err != nil {
+			return err
+		}
+
+		currentJSON, err := marshalRow(current)
+
+		if err != nil {
+			return err
+		}
+
+		sqler := squirrel.Insert("audit_log").
+			Columns("created_at", "actor", "request_id", "entity", "entity_id", "action", "before", "after").
+			Values(time.Now(), source.actor, source.requestId, entity, id, action, previousJSON, currentJSON)
+
+		if _, err := exec(ctx, sqler); err != nil {
+			return err
+		}
+
+		logged = true
+	}
+
+	if !logged {
+		return nil
+	}
+
+	return recordOperation(ctx, source)
+}
+
+// diff keeps the columns that changed between two versions of a row.
+func diff(previous auditRow, current auditRow) (auditRow, auditRow) {
+	before := make(auditRow)
+	after := make(auditRow)
+
+	for column, value := range current {
+		if column == "updated_at" || column == "version" || reflect.DeepEqual(previous[column], value) {
+			continue
+		}
+
+		before[column] = previous[column]
+		after[column] = value
+	}
+
+	return before, after
+}
+
+func marshalRow(row auditRow) (*string, error) {
+	if row == nil {
+		return nil, nil
+	}
+
+	value, err := json.Marshal(row)
+
+	if err != nil {
+		return nil, err
+	}
+
+	text := string(value)
+	return &text, nil
+}
+
+func rawJSON(value *string) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+
+	return json.RawMessage(*value)
+}
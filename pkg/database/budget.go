@@ -0,0 +1,251 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+const monthLayout = "2006-01"
+
+func (db *Database) CreateBudget(ctx context.Context, budget entities.BudgetEntity) (int64, error) {
+	logger.Debugf("Creating budget: %v", budget)
+
+	sqler := squirrel.Insert("budgets").
+		Columns("category_id", "month", "amount", "currency",
+			"rollover", "created_at", "updated_at").
+		Values(budget.CategoryId, budget.Month, budget.Amount, budget.Currency,
+			budget.Rollover, budget.CreateAt, budget.UpdateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// EditBudget saves a budget read at budget.Version.
+func (db *Database) EditBudget(ctx context.Context, budget entities.BudgetEntity) (int64, error) {
+	logger.Debugf("Editing budget: %v", budget)
+
+	sqler := squirrel.Update("budgets").
+		Set("amount", budget.Amount).
+		Set("currency", budget.Currency).
+		Set("rollover", budget.Rollover).
+		Set("updated_at", budget.UpdateAt).
+		Where("id = ?", budget.Id)
+
+	result, err := execVersioned(ctx, "budgets", budget.Id, budget.Version, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (db *Database) GetBudgetById(ctx context.Context, id int64) (entities.BudgetEntity, error) {
+	sqler := squirrel.Select("id", "category_id", "month", "amount", "currency", "rollover", "version",
+		"created_at", "updated_at").
+		From("budgets").
+		Where("id = ?", id)
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return entities.BudgetEntity{}, err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return entities.BudgetEntity{}, ErrorNotFound
+	}
+
+	var row entities.BudgetEntity
+	err = rows.Scan(&row.Id, &row.CategoryId, &row.Month, &row.Amount, &row.Currency, &row.Rollover, &row.Version,
+		&row.CreateAt, &row.UpdateAt)
+
+	return row, err
+}
+
+func (db *Database) DeleteBudget(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting budget: %d", id)
+
+	sqler := squirrel.Delete("budgets").Where("id = ?", id)
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// CopyBudgets copies every budget of the month before the given one into it.
+// Categories that already have a budget for the month are left untouched.
+func (db *Database) CopyBudgets(ctx context.Context, month string) (int64, error) {
+	logger.Debugf("Copying budgets into %s", month)
+
+	previous, err := addMonths(month, -1)
+
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	existing := squirrel.Select("category_id").From("budgets").Where("month = ?", month)
+
+	sqler := squirrel.Insert("budgets").
+		Columns("category_id", "month", "amount", "currency",
+			"rollover", "created_at", "updated_at").
+		Select(squirrel.Select("category_id").
+			Column("?", month).
+			Columns("amount", "currency", "rollover").
+			Column("?", now).
+			Column("?", now).
+			From("budgets").
+			Where("month = ?", previous).
+			Where(squirrel.Expr("category_id not in (?)", existing)))
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// GetBudgets returns the budgets of a month together with the amount spent in
+// each budgeted category and what is left of it. Budgets with rollover enabled
+// carry their remaining amount, positive or negative, into the following month.
+func (db *Database) GetBudgets(ctx context.Context, month string) ([]entities.BudgetRow, error) {
+	logger.Debugf("Getting budgets for %s", month)
+
+	budgeted := squirrel.Select("category_id").From("budgets").Where("month = ?", month)
+
+	sqler := squirrel.Select("b.id", "b.category_id", "c.name", "b.month",
+		"b.amount", "b.currency", "b.rollover", "b.version").
+		From("budgets b").
+		Join("categories c on c.id = b.category_id").
+		Where("b.month <= ?", month).
+		Where(squirrel.Expr("b.category_id in (?)", budgeted)).
+		OrderBy("c.name", "b.category_id", "b.month")
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var history []entities.BudgetRow
+
+	for rows.Next() {
+		var row entities.BudgetRow
+
+		if err := rows.Scan(&row.Id, &row.CategoryId, &row.CategoryName, &row.Month,
+			&row.Budgeted.Value, &row.Budgeted.Currency, &row.Rollover, &row.Version); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		history = append(history, row)
+	}
+
+	spent, err := getCategorySpending(ctx, month, budgeted)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var budgets []entities.BudgetRow
+	var previous *entities.BudgetRow
+
+	for i := range history {
+		row := &history[i]
+		row.CarriedOver.Currency = row.Budgeted.Currency
+		row.Actual.Currency = row.Budgeted.Currency
+		row.Remaining.Currency = row.Budgeted.Currency
+
+		if previous != nil && previous.CategoryId == row.CategoryId && previous.Rollover &&
+			previous.Budgeted.Currency == row.Budgeted.Currency {
+			if next, err := addMonths(previous.Month, 1); err == nil && next == row.Month {
+				row.CarriedOver.Value = previous.Remaining.Value
+			}
+		}
+
+		row.Actual.Value = spent[categoryMonthKey{row.CategoryId, row.Month, row.Budgeted.Currency}]
+		row.Remaining.Value = row.Budgeted.Value + row.CarriedOver.Value - row.Actual.Value
+
+		if row.Month == month {
+			budgets = append(budgets, *row)
+		}
+
+		previous = row
+	}
+
+	return budgets, nil
+}
+
+type categoryMonthKey struct {
+	categoryId int
+	month      string
+	currency   string
+}
+
+// getCategorySpending sums the items of the given categories per month up to
+// and including month, in the currency of the account the money was paid from.
+func getCategorySpending(ctx context.Context, month string, categories squirrel.SelectBuilder) (map[categoryMonthKey]int, error) {
+	sqler := squirrel.Select("i.category_id", "substr(t.date, 1, 7) as month",
+		"a.currency", "sum(i.price)").
+		From("items i").
+		Join("transactions t on t.id = i.transaction_id").
+		Join("accounts a on a.id = t.from_account_id").
+		Where("substr(t.date, 1, 7) <= ?", month).
+		Where("not t.pending").
+		Where(squirrel.Expr("i.category_id in (?)", categories)).
+		GroupBy("i.category_id", "month", "a.currency")
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	spent := make(map[categoryMonthKey]int)
+
+	for rows.Next() {
+		var key categoryMonthKey
+		var value int
+
+		if err := rows.Scan(&key.categoryId, &key.month, &key.currency, &value); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		spent[key] = value
+	}
+
+	return spent, nil
+}
+
+func addMonths(month string, months int) (string, error) {
+	date, err := time.Parse(monthLayout, month)
+
+	if err != nil {
+		return "", err
+	}
+
+	return date.AddDate(0, months, 0).Format(monthLayout), nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+func TestGetBudgetsRollover(t *testing.T) {
+	db, ctx := testContext(t)
+	accountId := testAccount(t, db, ctx, "Checking")
+	categoryId := testCategory(t, ctx, "Food")
+
+	for _, budget := range []struct {
+		month    string
+		rollover bool
+	}{
+		{"2026-01", true},
+		{"2026-02", true},
+		{"2026-03", false},
+		{"2026-04", true},
+		// May has no budget, nothing is carried into June
+		{"2026-06", true},
+	} {
+		_, err := db.CreateBudget(ctx, entities.BudgetEntity{
+			CategoryId: categoryId,
+			Month:      budget.month,
+			Amount:     10000,
+			Currency:   "EUR",
+			Rollover:   budget.rollover,
+			CreateAt:   time.Now(),
+			UpdateAt:   time.Now(),
+		})
+
+		if err != nil {
+			t.Fatalf("creating budget: %v", err)
+		}
+	}
+
+	testTransaction(t, db, ctx, accountId, 3000, "2026-01-10", &categoryId)
+	testTransaction(t, db, ctx, accountId, 12000, "2026-02-03", &categoryId)
+	testTransaction(t, db, ctx, accountId, 2500, "2026-06-30", &categoryId)
+	testTransaction(t, db, ctx, accountId, 9999, "2026-01-20", nil)
+
+	pendingId := testTransaction(t, db, ctx, accountId, 5000, "2026-01-15", &categoryId)
+
+	if _, err := exec(ctx, squirrel.Update("transactions").Set("pending", true).Where("id = ?", pendingId)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		month       string
+		carriedOver int
+		actual      int
+		remaining   int
+	}{
+		{"2026-01", 0, 3000, 7000},
+		{"2026-02", 7000, 12000, 5000},
+		{"2026-03", 5000, 0, 15000},
+		{"2026-04", 0, 0, 10000},
+		{"2026-06", 0, 2500, 7500},
+	}
+
+	for _, test := range tests {
+		t.Run(test.month, func(t *testing.T) {
+			budgets, err := db.GetBudgets(ctx, test.month)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(budgets) != 1 {
+				t.Fatalf("got %d budgets, want 1", len(budgets))
+			}
+
+			budget := budgets[0]
+
+			if budget.CarriedOver.Value != test.carriedOver || budget.Actual.Value != test.actual ||
+				budget.Remaining.Value != test.remaining {
+				t.Errorf("got carried over %d, actual %d, remaining %d, want %d, %d, %d",
+					budget.CarriedOver.Value, budget.Actual.Value, budget.Remaining.Value,
+					test.carriedOver, test.actual, test.remaining)
+			}
+		})
+	}
+
+	if budgets, err := db.GetBudgets(ctx, "2026-05"); err != nil || len(budgets) != 0 {
+		t.Errorf("got %v, %v for a month without budgets", budgets, err)
+	}
+}
+
+func TestAddMonths(t *testing.T) {
+	tests := []struct {
+		month  string
+		months int
+		want   string
+	}{
+		{"2026-01", 1, "2026-02"},
+		{"2026-12", 1, "2027-01"},
+		{"2026-03", -3, "2025-12"},
+		{"2026-05", 0, "2026-05"},
+	}
+
+	for _, test := range tests {
+		if got, err := addMonths(test.month, test.months); err != nil || got != test.want {
+			t.Errorf("addMonths(%q, %d) = %q, %v, want %q", test.month, test.months, got, err, test.want)
+		}
+	}
+
+	if _, err := addMonths("2026-13", 1); err == nil {
+		t.Error("addMonths accepted an invalid month")
+	}
+}
@@ -17,7 +17,7 @@ import (
 )
 
 var logger = log.NewLogger()
-var appSchemaVersion = uint(1)
+var appSchemaVersion = uint(22)
 
 //go:embed migrations/*.sql
 var migrationsBox embed.FS
@@ -42,6 +42,11 @@ const (
 var (
 	ErrorNotFound       = errors.New("not found")
 	ErrorNotInitialized = errors.New("not initialized")
+	ErrorReconciled     = errors.New("transaction is reconciled, unlock it first")
+	ErrorAccountInUse   = errors.New("account has history and can not be deleted permanently")
+	ErrorConflict       = errors.New("record was changed in the meantime, reload it and try again")
+	ErrorViewExists     = errors.New("a view with this name already exists")
+	ErrorNoFTS5         = errors.New("SQLite was built without FTS5, build para with -tags sqlite_fts5")
 )
 
 type Database struct {
@@ -82,13 +87,18 @@ func (db *Database) Open(connectionString string) error {
 	defer db.unlock()
 
 	db.dbPath = connectionString
+
+	if err := checkFTS5(); err != nil {
+		return err
+	}
+
 	var err error
 
 	db.schemaVersion, _ = db.getSchemaVersion()
 	logger.Infof("Database schema version: %d", db.schemaVersion)
 
-	if db.schemaVersion == 0 {
-		logger.Info("Initial database migration")
+	if db.schemaVersion < appSchemaVersion {
+		logger.Infof("Migrating database to schema version %d", appSchemaVersion)
 		err = db.RunAllMigrations()
 	}
 
@@ -222,6 +232,24 @@ func (db *Database) open(disableForeignKeys bool) (*sqlx.DB, error) {
 	return conn, nil
 }
 
+// checkFTS5 makes sure SQLite can create the search indexes, go-sqlite3 only
+// compiles FTS5 in with the sqlite_fts5 build tag.
+func checkFTS5() error {
+	conn, err := sql.Open(sqlite3Drive, ":memory:")
+
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.Exec("create virtual table fts5_check using fts5(text)"); err != nil {
+		return fmt.Errorf("%w: %v", ErrorNoFTS5, err)
+	}
+
+	return nil
+}
+
 func (db *Database) CreateAccount(ctx context.Context, account entities.AccountEntity) (int64, error) {
 	logger.Debugf("Creating account: %v", account)
 
@@ -230,12 +258,16 @@ func (db *Database) CreateAccount(ctx context.Context, account entities.AccountE
 			"account_number", "opening_balance",
 			"opening_balance_date", "notes",
 			"created_at", "updated_at",
-			"include_in_net_worth").
+			"include_in_net_worth", "account_type",
+			"statement_closing_day", "payment_due_day",
+			"group_id", "order_index").
 		Values(account.Name, account.Currency, account.IBAN, account.BIC,
 			account.AccountNumber, account.OpeningBalance,
 			account.OpeningBalanceDate, account.Notes,
 			account.CreateAt, account.UpdateAt,
-			account.IncludeInNetWorth)
+			account.IncludeInNetWorth, account.Type,
+			account.StatementClosingDay, account.PaymentDueDay,
+			account.GroupId, squirrel.Expr("(select ifnull(max(order_index), 0) + 1 from accounts)"))
 
 	result, err := exec(ctx, sqler)
 
@@ -243,11 +275,19 @@ func (db *Database) CreateAccount(ctx context.Context, account entities.AccountE
 		return 0, err
 	}
 
-	return result.LastInsertId()
+	id, err := result.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, auditCreated(ctx, "accounts", id)
 }
 
+// EditAccount saves an account read at account.Version, see execVersioned.
+// Its group and position are only changed by reordering.
 func (db *Database) EditAccount(ctx context.Context, account entities.AccountEntity) (int64, error) {
-	logger.Debugf("Creating account: %v", account)
+	logger.Debugf("Editing account: %v", account)
 
 	sqler := squirrel.Update("accounts").
 		Set("name", account.Name).
@@ -258,18 +298,20 @@ func (db *Database) EditAccount(ctx context.Context, account entities.AccountEnt
 		Set("opening_balance", account.OpeningBalance).
 		Set("opening_balance_date", account.OpeningBalanceDate).
 		Set("notes", account.Notes).
-		Set("created_at", account.CreateAt).
 		Set("updated_at", account.UpdateAt).
 		Set("include_in_net_worth", account.IncludeInNetWorth).
+		Set("account_type", account.Type).
+		Set("statement_closing_day", account.StatementClosingDay).
+		Set("payment_due_day", account.PaymentDueDay).
 		Where("id = ?", account.Id)
 
-	result, err := exec(ctx, sqler)
+	result, err := execVersioned(ctx, "accounts", account.Id, account.Version, sqler)
 
 	if err != nil {
 		return 0, err
 	}
 
-	return result.LastInsertId()
+	return result.RowsAffected()
 }
 
 func (db *Database) GetAccountById(ctx context.Context, id int64) (entities.AccountEntity, error) {
@@ -279,7 +321,9 @@ func (db *Database) GetAccountById(ctx context.Context, id int64) (entities.Acco
 		"account_number", "opening_balance",
 		"opening_balance_date", "notes",
 		"created_at", "updated_at",
-		"include_in_net_worth").
+		"include_in_net_worth", "account_type",
+		"statement_closing_day", "payment_due_day",
+		"archived", "deleted", "group_id", "order_index", "version").
 		From("accounts").
 		Where("id = ?", id).
 		Limit(1)
@@ -300,7 +344,9 @@ func (db *Database) GetAccountById(ctx context.Context, id int64) (entities.Acco
 			&row.AccountNumber, &row.OpeningBalance,
 			&row.OpeningBalanceDate, &row.Notes,
 			&row.CreateAt, &row.UpdateAt,
-			&row.IncludeInNetWorth); err != nil {
+			&row.IncludeInNetWorth, &row.Type,
+			&row.StatementClosingDay, &row.PaymentDueDay,
+			&row.Archived, &row.Deleted, &row.GroupId, &row.OrderIndex, &row.Version); err != nil {
 			logger.Errorf("Error %v", err)
 			return row, err
 		}
@@ -311,21 +357,184 @@ func (db *Database) GetAccountById(ctx context.Context, id int64) (entities.Acco
 	return entities.AccountEntity{}, ErrorNotFound
 }
 
+// GetAccounts returns the accounts in use, archived and deleted accounts are
+// left out.
 func (db *Database) GetAccounts(ctx context.Context, offset uint64, limit uint64, order string) ([]entities.AccountRow, error) {
 	logger.Debugf("Getting Accounts")
 
-	sqler := squirrel.Select("a.id as id", "a.name as name",
-		"a.currency as currency",
-		"(a.opening_balance + ifnull(sum(t.total_amount), 0) - ifnull(sum(f.total_amount), 0) ) as balance").
-		From("accounts a").
-		LeftJoin("transactions f on f.from_account_id = a.id").
-		LeftJoin("transactions t on t.to_account_id = a.id").
-		GroupBy("a.id", "a.name", "a.currency", "a.opening_balance").
-		Where("deleted = ?", false).
+	return queryAccountRows(ctx, selectAccountRows("").
+		Where("not a.deleted").
+		Where("not a.archived").
 		OrderBy(order).
 		Offset(offset).
-		Limit(limit)
+		Limit(limit))
+}
+
+// GetAccountBalances returns the balance of every account at the end of the
+// given day. Archived accounts are included, they are still part of the
+// history.
+func (db *Database) GetAccountBalances(ctx context.Context, until string) ([]entities.AccountRow, error) {
+	logger.Debugf("Getting account balances until %s", until)
+
+	return queryAccountRows(ctx, selectAccountRows(until).
+		Where("not a.deleted").
+		OrderBy("a.order_index", "a.id"))
+}
+
+// GetArchivedAccounts returns the archived and deleted accounts that can be
+// restored.
+func (db *Database) GetArchivedAccounts(ctx context.Context) ([]entities.AccountRow, error) {
+	logger.Debugf("Getting archived accounts")
+
+	return queryAccountRows(ctx, selectAccountRows("").
+		Where("(a.archived or a.deleted)").
+		OrderBy("a.name", "a.id"))
+}
+
+// DeleteAccount moves an account to the trash, it is hidden everywhere but
+// keeps its transactions until it is restored or purged.
+func (db *Database) DeleteAccount(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting account: %d", id)
+
+	return updateAccountState(ctx, id, squirrel.Update("accounts").
+		Set("deleted", true).
+		Set("deleted_at", time.Now()).
+		Where("not deleted"))
+}
+
+// ArchiveAccount hides an account from the account list, its balance still
+// counts in reports and the net worth.
+func (db *Database) ArchiveAccount(ctx context.Context, id int64) error {
+	logger.Debugf("Archiving account: %d", id)
+
+	return updateAccountState(ctx, id, squirrel.Update("accounts").
+		Set("archived", true).
+		Where("not deleted").
+		Where("not archived"))
+}
+
+// RestoreAccount brings back an archived or deleted account.
+func (db *Database) RestoreAccount(ctx context.Context, id int64) error {
+	logger.Debugf("Restoring account: %d", id)
+
+	return updateAccountState(ctx, id, squirrel.Update("accounts").
+		Set("archived", false).
+		Set("deleted", false).
+		Set("deleted_at", nil).
+		Where("(archived or deleted)"))
+}
+
+// PurgeAccount permanently removes a deleted account. Accounts with history
+// are refused with ErrorAccountInUse: transactions, scheduled transactions,
+// reconciliations, trades, valuations and attachments, or rules using them.
+// Their goals and loans go with them.
+func (db *Database) PurgeAccount(ctx context.Context, id int64) error {
+	logger.Debugf("Purging account: %d", id)
+
+	if _, err := queryId(ctx, squirrel.Select("id").
+		From("accounts").
+		Where("id = ?", id).
+		Where("deleted")); err != nil {
+		return err
+	}
+
+	return removeAccount(ctx, id)
+}
 
+// removeAccount removes an account without history together with its goals
+// and loans.
+func removeAccount(ctx context.Context, id int64) error {
+	if _, err := queryId(ctx, accountHistory(id)); err == nil {
+		return ErrorAccountInUse
+	} else if err != ErrorNotFound {
+		return err
+	}
+
+	for _, table := range []string{"goals", "loans"} {
+		if _, err := exec(ctx, squirrel.Delete(table).Where("account_id = ?", id)); err != nil {
+			return err
+		}
+	}
+
+	_, err := execAudited(ctx, "accounts", squirrel.Eq{"id": id}, squirrel.Delete("accounts").Where("id = ?", id))
+
+	return err
+}
+
+// PurgeAccounts permanently removes the deleted accounts without history and
+// returns their ids.
+func (db *Database) PurgeAccounts(ctx context.Context) ([]int64, error) {
+	logger.Debugf("Purging deleted accounts")
+
+	ids, err := queryIds(ctx, squirrel.Select("id").From("accounts").Where("deleted"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	purged := []int64{}
+
+	for _, id := range ids {
+		if err := db.PurgeAccount(ctx, id); err == ErrorAccountInUse {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		purged = append(purged, id)
+	}
+
+	return purged, nil
+}
+
+// accountHistory selects the account when any row references it, it can
+// not be removed then.
+func accountHistory(id int64) squirrel.SelectBuilder {
+	used := func(table string, where string) squirrel.Sqlizer {
+		return squirrel.Expr("exists (?)", squirrel.Select("1").From(table).Where(where))
+	}
+
+	return squirrel.Select("a.id").
+		From("accounts a").
+		Where("a.id = ?", id).
+		Where(squirrel.Or{
+			used("transactions", "from_account_id = a.id or to_account_id = a.id"),
+			used("scheduled_transactions", "from_account_id = a.id or to_account_id = a.id"),
+			used("reconciliations", "account_id = a.id"),
+			used("security_trades", "account_id = a.id"),
+			used("account_valuations", "account_id = a.id"),
+			used("attachments", "account_id = a.id"),
+			used("rules", "json_extract(conditions, '$.accountId') = a.id"+
+				" or json_extract(actions, '$.transferAccountId') = a.id"),
+		})
+}
+
+func updateAccountState(ctx context.Context, id int64, sqler squirrel.UpdateBuilder) error {
+	result, err := execAudited(ctx, "accounts", squirrel.Eq{"id": id}, sqler.
+		Set("updated_at", time.Now()).
+		Where("id = ?", id))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// selectAccountRows selects the accounts aliased as a with their balance at
+// the end of the day until, or with every transaction when it is empty.
+func selectAccountRows(until string) squirrel.SelectBuilder {
+	return squirrel.Select("a.id", "a.name", "a.account_type", "a.include_in_net_worth",
+		"a.archived", "a.deleted", "ifnull(a.group_id, 0)", "a.currency").
+		Column(accountBalance(until)).
+		From("accounts a")
+}
+
+func queryAccountRows(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.AccountRow, error) {
 	rows, err := query(ctx, sqler)
 
 	if err != nil {
@@ -336,13 +545,12 @@ func (db *Database) GetAccounts(ctx context.Context, offset uint64, limit uint64
 	var accounts []entities.AccountRow
 
 	for rows.Next() {
-		//var alb Album
 		var row entities.AccountRow
 
-		if err := rows.Scan(&row.Id, &row.Name,
-			&row.Balance.Currency, &row.Balance.Value); err != nil {
+		if err := rows.Scan(&row.Id, &row.Name, &row.Type, &row.IncludeInNetWorth,
+			&row.Archived, &row.Deleted, &row.GroupId, &row.Balance.Currency, &row.Balance.Value); err != nil {
 			logger.Errorf("Error %v", err)
-			break
+			return nil, err
 		}
 
 		accounts = append(accounts, row)
@@ -351,6 +559,25 @@ func (db *Database) GetAccounts(ctx context.Context, offset uint64, limit uint64
 	return accounts, nil
 }
 
+// accountBalance sums the opening balance, the confirmed transactions and
+// the value of the securities held by the account aliased as a, up to and
+// including the day until when it is set.
+func accountBalance(until string) squirrel.Sqlizer {
+	filter := "not pending"
+	var args []interface{}
+
+	if until != "" {
+		filter += " and date <= ?"
+		args = []interface{}{until, until}
+	}
+
+	return squirrel.Expr("(a.opening_balance"+
+		" + ifnull((select sum(total_amount) from transactions where to_account_id = a.id and "+filter+"), 0)"+
+		" - ifnull((select sum(total_amount) from transactions where from_account_id = a.id and "+filter+"), 0)"+
+		" + ?)",
+		append(args, holdingsValue(until))...)
+}
+
 func newDatabase() *Database {
 	return &Database{
 		lockChan: make(chan struct{}, 1),
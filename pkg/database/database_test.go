@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+// testContext opens a migrated database in a temporary directory and returns
+// a context in a transaction on it, the transaction is rolled back when the
+// test ends.
+func testContext(t *testing.T) (*Database, context.Context) {
+	t.Helper()
+
+	db := newDatabase()
+
+	if err := db.Open(filepath.Join(t.TempDir(), "para.sqlite")); errors.Is(err, ErrorNoFTS5) {
+		t.Skip(err)
+	} else if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx, err := db.Begin(context.Background(), false)
+
+	if err != nil {
+		t.Fatalf("beginning transaction: %v", err)
+	}
+
+	t.Cleanup(func() { _ = db.Rollback(ctx) })
+
+	return db, ctx
+}
+
+func testAccount(t *testing.T, db *Database, ctx context.Context, name string) int64 {
+	t.Helper()
+
+	id, err := db.CreateAccount(ctx, entities.AccountEntity{
+		Name:               name,
+		Currency:           "EUR",
+		OpeningBalanceDate: "2026-01-01",
+		CreateAt:           time.Now(),
+		UpdateAt:           time.Now(),
+	})
+
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	return id
+}
+
+func testCategory(t *testing.T, ctx context.Context, name string) int64 {
+	t.Helper()
+
+	result, err := exec(ctx, squirrel.Insert("categories").
+		Columns("name", "color_hex", "created_at", "updated_at").
+		Values(name, "000000", time.Now(), time.Now()))
+
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+
+	id, _ := result.LastInsertId()
+	return id
+}
+
+// testTransaction books amount from the account on date with a single item
+// in the category.
+func testTransaction(t *testing.T, db *Database, ctx context.Context, accountId int64, amount int, date string,
+	categoryId *int64) int64 {
+	t.Helper()
+
+	id, err := db.CreateTransaction(ctx, entities.TransactionEntity{
+		FromAccountId: &accountId,
+		TotalAmount:   amount,
+		Date:          date,
+		Description:   "test",
+		CreateAt:      time.Now(),
+		UpdateAt:      time.Now(),
+	})
+
+	if err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+
+	_, err = db.CreateItem(ctx, entities.ItemEntity{
+		Name:          "test",
+		Price:         amount,
+		TransactionId: id,
+		CategoryId:    categoryId,
+		CreateAt:      time.Now(),
+		UpdateAt:      time.Now(),
+	})
+
+	if err != nil {
+		t.Fatalf("creating item: %v", err)
+	}
+
+	return id
+}
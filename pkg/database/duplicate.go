@@ -0,0 +1,190 @@
+package database
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+// CreateDuplicatePair records a suspected duplicate. Pairs that were already
+// recorded, including dismissed ones, are left alone.
+func (db *Database) CreateDuplicatePair(ctx context.Context, pair entities.DuplicatePairEntity) error {
+	logger.Debugf("Creating duplicate pair: %v", pair)
+
+	if pair.TransactionId > pair.DuplicateId {
+		pair.TransactionId, pair.DuplicateId = pair.DuplicateId, pair.TransactionId
+	}
+
+	sqler := squirrel.Insert("duplicate_pairs").
+		Options("or ignore").
+		Columns("transaction_id", "duplicate_id", "score", "dismissed", "created_at").
+		Values(pair.TransactionId, pair.DuplicateId, pair.Score, false, pair.CreateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil
+	}
+
+	id, err := result.LastInsertId()
+
+	if err != nil {
+		return err
+	}
+
+	return auditCreated(ctx, "duplicate_pairs", id)
+}
+
+// DismissDuplicatePair marks a pair as not being a duplicate, it is not
+// suggested again.
+func (db *Database) DismissDuplicatePair(ctx context.Context, id int64) error {
+	logger.Debugf("Dismissing duplicate pair: %d", id)
+
+	where := squirrel.Eq{"id": id}
+	result, err := execAudited(ctx, "duplicate_pairs", where, squirrel.Update("duplicate_pairs").
+		Set("dismissed", true).
+		Where(where))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (db *Database) GetDuplicatePairById(ctx context.Context, id int64) (entities.DuplicatePairEntity, error) {
+	pairs, err := queryDuplicatePairs(ctx, selectDuplicatePairs().Where("id = ?", id))
+
+	if err != nil {
+		return entities.DuplicatePairEntity{}, err
+	}
+
+	if len(pairs) == 0 {
+		return entities.DuplicatePairEntity{}, ErrorNotFound
+	}
+
+	return pairs[0], nil
+}
+
+// GetDuplicatePairs returns the pairs waiting for review, the most likely
+// duplicates first.
+func (db *Database) GetDuplicatePairs(ctx context.Context, offset uint64, limit uint64) ([]entities.DuplicatePairEntity, error) {
+	logger.Debugf("Getting duplicate pairs")
+
+	return queryDuplicatePairs(ctx, selectDuplicatePairs().
+		Where("not dismissed").
+		OrderBy("score desc", "id").
+		Offset(offset).
+		Limit(limit))
+}
+
+// MergeTransactions folds the duplicate into the kept transaction and deletes
+// it. The kept transaction takes over the tags and attachments, the items
+// when it has none, and the payee, description and notes it is missing.
+func (db *Database) MergeTransactions(ctx context.Context, keepId int64, duplicateId int64) error {
+	logger.Debugf("Merging transaction %d into %d", duplicateId, keepId)
+
+	for _, id := range []int64{keepId, duplicateId} {
+		if err := db.CheckTransactionUnlocked(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	duplicate := func(column string) squirrel.Sqlizer {
+		return squirrel.Select(column).From("transactions").Where("id = ?", duplicateId)
+	}
+
+	sqler := squirrel.Update("transactions").
+		Set("payee_id", squirrel.Expr("coalesce(payee_id, (?))", duplicate("payee_id"))).
+		Set("description", squirrel.Expr("case when description = '' then (?) else description end", duplicate("description"))).
+		Set("notes", squirrel.Expr("case when notes = '' then (?) else notes end", duplicate("notes"))).
+		Where("id = ?", keepId)
+
+	tags := squirrel.Insert("transaction_tags").
+		Options("or ignore").
+		Columns("transaction_id", "tag_id").
+		Select(squirrel.Select().
+			Column("?", keepId).
+			Column("tag_id").
+			From("transaction_tags").
+			Where("transaction_id = ?", duplicateId))
+
+	err := auditChange(ctx, "transactions", squirrel.Eq{"id": keepId}, func() error {
+		if _, err := exec(ctx, sqler); err != nil {
+			return err
+		}
+
+		_, err := exec(ctx, tags)
+
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = queryId(ctx, squirrel.Select("id").From("items").Where("transaction_id = ?", keepId))
+
+	if err == ErrorNotFound {
+		where := squirrel.Eq{"transaction_id": duplicateId}
+		sqler = squirrel.Update("items").
+			Set("transaction_id", keepId).
+			Where(where)
+
+		if _, err := execAudited(ctx, "items", where, sqler); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	where := squirrel.Eq{"transaction_id": duplicateId}
+	sqler = squirrel.Update("attachments").
+		Set("transaction_id", keepId).
+		Where(where)
+
+	if _, err := execAudited(ctx, "attachments", where, sqler); err != nil {
+		return err
+	}
+
+	return db.DeleteTransaction(ctx, duplicateId)
+}
+
+func selectDuplicatePairs() squirrel.SelectBuilder {
+	return squirrel.Select("id", "transaction_id", "duplicate_id", "score", "dismissed", "created_at").
+		From("duplicate_pairs")
+}
+
+func queryDuplicatePairs(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.DuplicatePairEntity, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var pairs []entities.DuplicatePairEntity
+
+	for rows.Next() {
+		var row entities.DuplicatePairEntity
+
+		if err := rows.Scan(&row.Id, &row.TransactionId, &row.DuplicateId, &row.Score,
+			&row.Dismissed, &row.CreateAt); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		pairs = append(pairs, row)
+	}
+
+	return pairs, nil
+}
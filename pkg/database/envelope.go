@@ -0,0 +1,221 @@
+package database
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+func (db *Database) CreateEnvelopeTransfer(ctx context.Context, transfer entities.EnvelopeTransferEntity) (int64, error) {
+	logger.Debugf("Creating envelope transfer: %v", transfer)
+
+	sqler := squirrel.Insert("envelope_transfers").
+		Columns("from_category_id", "to_category_id", "month",
+			"amount", "currency", "created_at").
+		Values(transfer.FromCategoryId, transfer.ToCategoryId, transfer.Month,
+			transfer.Amount, transfer.Currency, transfer.CreateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// GetEnvelopes returns the envelope balances of every month between fromMonth
+// and toMonth. Income flowing into accounts from outside of Para fills the
+// "to be assigned" pool, transfers move money between the pool and the
+// envelopes and spending draws the envelopes down. Balances carry over from
+// one month to the next.
+func (db *Database) GetEnvelopes(ctx context.Context, currency string, fromMonth string, toMonth string) ([]entities.EnvelopeMonth, error) {
+	logger.Debugf("Getting envelopes for %s from %s to %s", currency, fromMonth, toMonth)
+
+	categories, err := getCategoryNames(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	income, err := getMonthlyIncome(ctx, currency, toMonth)
+
+	if err != nil {
+		return nil, err
+	}
+
+	spent, err := getCategorySpending(ctx, toMonth, squirrel.Select("id").From("categories"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	sqler := squirrel.Select("from_category_id", "to_category_id", "month", "sum(amount)").
+		From("envelope_transfers").
+		Where("currency = ?", currency).
+		Where("month <= ?", toMonth).
+		GroupBy("from_category_id", "to_category_id", "month")
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	assigned := make(map[categoryMonthKey]int)
+	unassigned := make(map[string]int)
+	firstMonth := fromMonth
+
+	for rows.Next() {
+		var from, to *int
+		var month string
+		var amount int
+
+		if err := rows.Scan(&from, &to, &month, &amount); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		if from == nil {
+			unassigned[month] -= amount
+		} else {
+			assigned[categoryMonthKey{*from, month, currency}] -= amount
+		}
+
+		if to == nil {
+			unassigned[month] += amount
+		} else {
+			assigned[categoryMonthKey{*to, month, currency}] += amount
+		}
+
+		firstMonth = min(firstMonth, month)
+	}
+
+	for month := range income {
+		firstMonth = min(firstMonth, month)
+	}
+
+	for key := range spent {
+		if key.currency == currency {
+			firstMonth = min(firstMonth, key.month)
+		}
+	}
+
+	var months []entities.EnvelopeMonth
+	available := make(map[int]int)
+	toBeAssigned := 0
+
+	for month := firstMonth; month <= toMonth; {
+		toBeAssigned += income[month] + unassigned[month]
+
+		envelopeMonth := entities.EnvelopeMonth{
+			Month:        month,
+			Income:       entities.CurrencyValue{Currency: currency, Value: income[month]},
+			ToBeAssigned: entities.CurrencyValue{Currency: currency, Value: toBeAssigned},
+		}
+
+		for _, category := range categories {
+			monthAssigned := assigned[categoryMonthKey{category.Id, month, currency}]
+			activity := spent[categoryMonthKey{category.Id, month, currency}]
+			available[category.Id] += monthAssigned - activity
+
+			envelopeMonth.Envelopes = append(envelopeMonth.Envelopes, entities.EnvelopeRow{
+				CategoryId:   category.Id,
+				CategoryName: category.Name,
+				Assigned:     entities.CurrencyValue{Currency: currency, Value: monthAssigned},
+				Activity:     entities.CurrencyValue{Currency: currency, Value: activity},
+				Available:    entities.CurrencyValue{Currency: currency, Value: available[category.Id]},
+			})
+		}
+
+		if month >= fromMonth {
+			months = append(months, envelopeMonth)
+		}
+
+		if month, err = addMonths(month, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	return months, nil
+}
+
+// CategoryExists tells whether there is a category with id.
+func (db *Database) CategoryExists(ctx context.Context, id int64) (bool, error) {
+	_, err := queryId(ctx, squirrel.Select("id").From("categories").Where("id = ?", id))
+
+	if err == ErrorNotFound {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+type categoryName struct {
+	Id   int
+	Name string
+}
+
+func getCategoryNames(ctx context.Context) ([]categoryName, error) {
+	sqler := squirrel.Select("id", "name").From("categories").OrderBy("name", "id")
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var categories []categoryName
+
+	for rows.Next() {
+		var row categoryName
+
+		if err := rows.Scan(&row.Id, &row.Name); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		categories = append(categories, row)
+	}
+
+	return categories, nil
+}
+
+// getMonthlyIncome sums the money that entered accounts of the given currency
+// from outside of Para per month, up to and including month.
+func getMonthlyIncome(ctx context.Context, currency string, month string) (map[string]int, error) {
+	sqler := squirrel.Select("substr(t.date, 1, 7) as month", "sum(t.total_amount)").
+		From("transactions t").
+		Join("accounts a on a.id = t.to_account_id").
+		Where("t.from_account_id is null").
+		Where("not t.pending").
+		Where("a.currency = ?", currency).
+		Where("substr(t.date, 1, 7) <= ?", month).
+		GroupBy("month")
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	income := make(map[string]int)
+
+	for rows.Next() {
+		var month string
+		var value int
+
+		if err := rows.Scan(&month, &value); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		income[month] = value
+	}
+
+	return income, nil
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+func (db *Database) CreateGoal(ctx context.Context, goal entities.GoalEntity) (int64, error) {
+	logger.Debugf("Creating goal: %v", goal)
+
+	sqler := squirrel.Insert("goals").
+		Columns("name", "account_id", "target_amount", "target_date", "allocation",
+			"start_date", "start_amount", "created_at", "updated_at").
+		Values(goal.Name, goal.AccountId, goal.TargetAmount, goal.TargetDate, goal.Allocation,
+			goal.StartDate, goal.StartAmount, goal.CreateAt, goal.UpdateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// EditGoal changes a goal read at goal.Version, where it started is kept.
+func (db *Database) EditGoal(ctx context.Context, goal entities.GoalEntity) (int64, error) {
+	logger.Debugf("Editing goal: %v", goal)
+
+	sqler := squirrel.Update("goals").
+		Set("name", goal.Name).
+		Set("account_id", goal.AccountId).
+		Set("target_amount", goal.TargetAmount).
+		Set("target_date", goal.TargetDate).
+		Set("allocation", goal.Allocation).
+		Set("updated_at", goal.UpdateAt).
+		Where("id = ?", goal.Id)
+
+	result, err := execVersioned(ctx, "goals", goal.Id, goal.Version, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (db *Database) DeleteGoal(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting goal: %d", id)
+
+	result, err := exec(ctx, squirrel.Delete("goals").Where("id = ?", id))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (db *Database) GetGoalById(ctx context.Context, id int64) (entities.GoalEntity, error) {
+	goals, err := queryGoals(ctx, selectGoals().Where("id = ?", id))
+
+	if err != nil {
+		return entities.GoalEntity{}, err
+	}
+
+	if len(goals) == 0 {
+		return entities.GoalEntity{}, ErrorNotFound
+	}
+
+	return goals[0], nil
+}
+
+// GetGoals returns every goal, the closest target date first.
+func (db *Database) GetGoals(ctx context.Context) ([]entities.GoalEntity, error) {
+	logger.Debugf("Getting goals")
+
+	return queryGoals(ctx, selectGoals().OrderBy("target_date", "id"))
+}
+
+func selectGoals() squirrel.SelectBuilder {
+	return squirrel.Select("id", "name", "account_id", "target_amount", "target_date", "allocation",
+		"start_date", "start_amount", "version", "created_at", "updated_at").
+		From("goals")
+}
+
+func queryGoals(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.GoalEntity, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var goals []entities.GoalEntity
+
+	for rows.Next() {
+		var row entities.GoalEntity
+
+		if err := rows.Scan(&row.Id, &row.Name, &row.AccountId, &row.TargetAmount, &row.TargetDate,
+			&row.Allocation, &row.StartDate, &row.StartAmount, &row.Version, &row.CreateAt, &row.UpdateAt); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		goals = append(goals, row)
+	}
+
+	return goals, nil
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+// CreateAccountGroup adds a group after the existing ones.
+func (db *Database) CreateAccountGroup(ctx context.Context, group entities.AccountGroupEntity) (int64, error) {
+	logger.Debugf("Creating account group: %v", group)
+
+	sqler := squirrel.Insert("account_groups").
+		Columns("name", "order_index", "created_at", "updated_at").
+		Values(group.Name, squirrel.Expr("(select ifnull(max(order_index), 0) + 1 from account_groups)"),
+			group.CreateAt, group.UpdateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// EditAccountGroup renames a group read at group.Version.
+func (db *Database) EditAccountGroup(ctx context.Context, group entities.AccountGroupEntity) (int64, error) {
+	logger.Debugf("Editing account group: %v", group)
+
+	sqler := squirrel.Update("account_groups").
+		Set("name", group.Name).
+		Set("updated_at", group.UpdateAt).
+		Where("id = ?", group.Id)
+
+	result, err := execVersioned(ctx, "account_groups", group.Id, group.Version, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteAccountGroup deletes a group, its accounts are kept outside any
+// group.
+func (db *Database) DeleteAccountGroup(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting account group: %d", id)
+
+	grouped := squirrel.Eq{"group_id": id}
+
+	if _, err := execAudited(ctx, "accounts", grouped, squirrel.Update("accounts").Set("group_id", nil).Where(grouped)); err != nil {
+		return err
+	}
+
+	result, err := exec(ctx, squirrel.Delete("account_groups").Where("id = ?", id))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (db *Database) GetAccountGroupById(ctx context.Context, id int64) (entities.AccountGroupEntity, error) {
+	groups, err := queryAccountGroups(ctx, selectAccountGroups().Where("id = ?", id))
+
+	if err != nil {
+		return entities.AccountGroupEntity{}, err
+	}
+
+	if len(groups) == 0 {
+		return entities.AccountGroupEntity{}, ErrorNotFound
+	}
+
+	return groups[0], nil
+}
+
+// GetAccountGroups returns the groups in their order.
+func (db *Database) GetAccountGroups(ctx context.Context) ([]entities.AccountGroupEntity, error) {
+	logger.Debugf("Getting account groups")
+
+	return queryAccountGroups(ctx, selectAccountGroups().OrderBy("order_index", "id"))
+}
+
+func selectAccountGroups() squirrel.SelectBuilder {
+	return squirrel.Select("id", "name", "order_index", "version", "created_at", "updated_at").
+		From("account_groups")
+}
+
+func queryAccountGroups(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.AccountGroupEntity, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var groups []entities.AccountGroupEntity
+
+	for rows.Next() {
+		var row entities.AccountGroupEntity
+
+		if err := rows.Scan(&row.Id, &row.Name, &row.OrderIndex, &row.Version, &row.CreateAt, &row.UpdateAt); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		groups = append(groups, row)
+	}
+
+	return groups, nil
+}
+
+// ReorderAccountGroups stores the order of the groups, ids lists every group
+// from the first to the last.
+func (db *Database) ReorderAccountGroups(ctx context.Context, ids []int64) error {
+	logger.Debugf("Reordering account groups: %v", ids)
+
+	return reorder(ctx, "account_groups", ids, nil)
+}
+
+// MoveAccounts puts accounts into a group, or outside any group when groupId
+// is nil, in the order of ids. Dropping an account on the sidebar sends the
+// new order of the group it was dropped into.
+func (db *Database) MoveAccounts(ctx context.Context, groupId *int64, ids []int64) error {
+	logger.Debugf("Moving accounts %v to group %v", ids, groupId)
+
+	if groupId != nil {
+		if _, err := queryId(ctx, squirrel.Select("id").From("account_groups").Where("id = ?", *groupId)); err != nil {
+			return err
+		}
+	}
+
+	return reorder(ctx, "accounts", ids, func(sqler squirrel.UpdateBuilder) squirrel.UpdateBuilder {
+		return sqler.Set("group_id", groupId).Where("not deleted")
+	})
+}
+
+// reorder sets the order_index of the rows of table to their position in
+// ids, update adds to the statement of every row.
+func reorder(ctx context.Context, table string, ids []int64,
+	update func(sqler squirrel.UpdateBuilder) squirrel.UpdateBuilder) error {
+	for i, id := range ids {
+		sqler := squirrel.Update(table).
+			Set("order_index", i+1).
+			Set("updated_at", time.Now()).
+			Where("id = ?", id)
+
+		if update != nil {
+			sqler = update(sqler)
+		}
+
+		result, err := execAudited(ctx, table, squirrel.Eq{"id": id}, sqler)
+
+		if err != nil {
+			return err
+		}
+
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return ErrorNotFound
+		}
+	}
+
+	return nil
+}
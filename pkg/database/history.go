@@ -0,0 +1,457 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+	"github.com/mattn/go-sqlite3"
+)
+
+// historyLimit is the number of operations kept per actor to be undone.
+const historyLimit = 50
+
+// storedRow is a row of an audited table as the audit log stored it, by
+// column.
+type storedRow map[string]json.RawMessage
+
+// GetHistory returns the latest operations of actor, the latest first.
+func (db *Database) GetHistory(ctx context.Context, actor string) ([]entities.Operation, error) {
+	logger.Debugf("Getting history of %s", actor)
+
+	return queryOperations(ctx, selectOperations().
+		Where("h.actor = ?", actor).
+		OrderBy("h.id desc"))
+}
+
+// Undo reverts the last count operations of actor that were not undone yet,
+// the latest first, and returns them.
+func (db *Database) Undo(ctx context.Context, actor string, count uint64) ([]entities.Operation, error) {
+	logger.Debugf("Undoing %d operations of %s", count, actor)
+
+	operations, err := queryOperations(ctx, selectOperations().
+		Where("h.actor = ? and not h.undone", actor).
+		OrderBy("h.id desc").
+		Limit(count))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return operations, replayOperations(ctx, operations, true)
+}
+
+// UndoOperation reverts one operation of actor, like an import, even when
+// later operations were not undone. It fails with ErrorConflict when a later
+// operation changed the same rows.
+func (db *Database) UndoOperation(ctx context.Context, actor string, id int64) (entities.Operation, error) {
+	logger.Debugf("Undoing operation %d of %s", id, actor)
+
+	operations, err := queryOperations(ctx, selectOperations().
+		Where("h.actor = ? and h.id = ? and not h.undone", actor, id))
+
+	if err != nil {
+		return entities.Operation{}, err
+	}
+
+	if len(operations) == 0 {
+		return entities.Operation{}, ErrorNotFound
+	}
+
+	err = replayOperations(ctx, operations, true)
+
+	return operations[0], err
+}
+
+// Redo applies again the first count operations of actor that were undone,
+// the earliest first, and returns them.
+func (db *Database) Redo(ctx context.Context, actor string, count uint64) ([]entities.Operation, error) {
+	logger.Debugf("Redoing %d operations of %s", count, actor)
+
+	operations, err := queryOperations(ctx, selectOperations().
+		Where("h.actor = ? and h.undone", actor).
+		OrderBy("h.id").
+		Limit(count))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return operations, replayOperations(ctx, operations, false)
+}
+
+// recordOperation adds the request of source to the history of its actor
+// the first time it changes something. It drops the undone operations of the
+// actor, they can not be redone over the change, and the operations past
+// historyLimit. Undoing and redoing are not operations and keep them.
+func recordOperation(ctx context.Context, source auditSource) error {
+	if source.requestId == "" || source.operation == "" {
+		return nil
+	}
+
+	if _, err := queryId(ctx, squirrel.Select("id").
+		From("history").
+		Where("request_id = ?", source.requestId)); err != ErrorNotFound {
+		return err
+	}
+
+	sqler := squirrel.Insert("history").
+		Columns("actor", "request_id", "name", "created_at").
+		Values(source.actor, source.requestId, source.operation, time.Now())
+
+	if _, err := exec(ctx, squirrel.Delete("history").Where("actor = ? and undone", source.actor)); err != nil {
+		return err
+	}
+
+	if _, err := exec(ctx, sqler); err != nil {
+		return err
+	}
+
+	_, err := exec(ctx, squirrel.Delete("history").
+		Where("actor = ?", source.actor).
+		Where("id not in (select id from history where actor = ? order by id desc limit ?)", source.actor, historyLimit))
+
+	return err
+}
+
+// replayOperations undoes or redoes operations in the order given. The
+// changes are audited like any other, but they are not operations of their
+// own.
+func replayOperations(ctx context.Context, operations []entities.Operation, undo bool) error {
+	if source, ok := ctx.Value(auditKey).(auditSource); ok {
+		source.operation = ""
+		ctx = context.WithValue(ctx, auditKey, source)
+	}
+
+	for i, operation := range operations {
+		if err := replayOperation(ctx, operation, undo); err != nil {
+			return err
+		}
+
+		sqler := squirrel.Update("history").
+			Set("undone", undo).
+			Where("id = ?", operation.Id)
+
+		if _, err := exec(ctx, sqler); err != nil {
+			return err
+		}
+
+		operations[i].Undone = undo
+	}
+
+	return nil
+}
+
+// replayOperation moves the rows an operation changed back to how they were
+// before it, when undoing, or forward to how it left them. Undoing goes
+// through the changes backwards so that items go before their transaction.
+func replayOperation(ctx context.Context, operation entities.Operation, undo bool) error {
+	order := "id"
+
+	if undo {
+		order = "id desc"
+	}
+
+	entries, err := queryAuditLog(ctx, squirrel.Select("id", "created_at", "actor", "request_id", "entity",
+		"entity_id", "action", "before", "after").
+		From("audit_log").
+		Where("request_id = ? and actor = ?", operation.RequestId, operation.Actor).
+		OrderBy(order))
+
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		table := auditedTable(entry.Entity)
+		from, to := entry.Before, entry.After
+
+		if undo {
+			from, to = to, from
+		}
+
+		fromRow, err := decodeRow(from)
+
+		if err != nil {
+			return err
+		}
+
+		toRow, err := decodeRow(to)
+
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case fromRow == nil:
+			err = insertRow(ctx, table, entry.EntityId, toRow)
+		case toRow == nil:
+			err = removeRow(ctx, table, entry.EntityId, fromRow)
+		default:
+			err = updateRow(ctx, table, entry.EntityId, fromRow, toRow)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertRow brings back a row that was created or deleted, with its tags. It
+// fails with ErrorConflict when a row it refers to is gone.
+func insertRow(ctx context.Context, table string, id int64, row storedRow) error {
+	if _, err := currentRow(ctx, table, id, nil); err != ErrorNotFound {
+		if err == nil {
+			return ErrorConflict
+		}
+
+		return err
+	}
+
+	columns := make([]string, 0, len(row))
+
+	for column := range row {
+		if column != "tags" {
+			columns = append(columns, column)
+		}
+	}
+
+	sort.Strings(columns)
+	values := make([]interface{}, 0, len(columns))
+
+	for _, column := range columns {
+		value, err := rowValue(column, row[column])
+
+		if err != nil {
+			return err
+		}
+
+		values = append(values, value)
+	}
+
+	if _, err := exec(ctx, squirrel.Insert(table).Columns(columns...).Values(values...)); err != nil {
+		var sqliteError sqlite3.Error
+
+		if errors.As(err, &sqliteError) && sqliteError.ExtendedCode == sqlite3.ErrConstraintForeignKey {
+			return ErrorConflict
+		}
+
+		return err
+	}
+
+	if err := restoreTags(ctx, table, id, row); err != nil {
+		return err
+	}
+
+	return auditCreated(ctx, table, id)
+}
+
+// removeRow deletes a row that is still as expected, along with what is
+// linked to it.
+func removeRow(ctx context.Context, table string, id int64, expected storedRow) error {
+	if _, err := currentRow(ctx, table, id, expected); err != nil {
+		return err
+	}
+
+	matching := squirrel.Eq{"id": id}
+
+	switch table {
+	case "accounts":
+		return removeAccount(ctx, id)
+	case "transactions":
+		return deleteTransactions(ctx, matching)
+	case "items":
+		return deleteItems(ctx, matching)
+	}
+
+	_, err := execAudited(ctx, table, matching, squirrel.Delete(table).Where(matching))
+
+	return err
+}
+
+// updateRow sets the columns of a row that are still as expected to their
+// values in target.
+func updateRow(ctx context.Context, table string, id int64, expected storedRow, target storedRow) error {
+	current, err := currentRow(ctx, table, id, expected)
+
+	if err != nil {
+		return err
+	}
+
+	sqler := squirrel.Update(table).Where("id = ?", id)
+
+	for column, raw := range target {
+		if column == "updated_at" || column == "version" || column == "tags" {
+			continue
+		}
+
+		value, err := rowValue(column, raw)
+
+		if err != nil {
+			return err
+		}
+
+		sqler = sqler.Set(column, value)
+	}
+
+	if _, ok := current["updated_at"]; ok {
+		sqler = sqler.Set("updated_at", time.Now())
+	}
+
+	if _, ok := current["version"]; ok {
+		sqler = sqler.Set("version", squirrel.Expr("version + 1"))
+	}
+
+	return auditChange(ctx, table, squirrel.Eq{"id": id}, func() error {
+		if _, err := exec(ctx, sqler); err != nil {
+			return err
+		}
+
+		return restoreTags(ctx, table, id, target)
+	})
+}
+
+// restoreTags sets the tags of a row of a tagged table to the ones stored
+// with it, when there are any.
+func restoreTags(ctx context.Context, table string, id int64, row storedRow) error {
+	link, ok := taggedTables[table]
+	raw, stored := row["tags"]
+
+	if !ok || !stored {
+		return nil
+	}
+
+	var names []string
+
+	if err := json.Unmarshal(raw, &names); err != nil {
+		return err
+	}
+
+	return setTags(ctx, link.table, link.column, id, names)
+}
+
+// currentRow reads a row of table and checks that the columns of expected
+// still hold the same values. It fails with ErrorConflict when they changed
+// since and with ErrorReconciled for a reconciled transaction.
+func currentRow(ctx context.Context, table string, id int64, expected storedRow) (auditRow, error) {
+	rows, err := snapshot(ctx, table, []int64{id})
+
+	if err != nil {
+		return nil, err
+	}
+
+	current, ok := rows[id]
+
+	if !ok {
+		if expected != nil {
+			return nil, ErrorConflict
+		}
+
+		return nil, ErrorNotFound
+	}
+
+	for column, value := range expected {
+		if column == "updated_at" || column == "version" {
+			continue
+		}
+
+		if actual, err := json.Marshal(current[column]); err != nil || !bytes.Equal(actual, value) {
+			return nil, ErrorConflict
+		}
+	}
+
+	if table == "transactions" && current["status"] == int64(entities.StatusReconciled) {
+		return nil, ErrorReconciled
+	}
+
+	return current, nil
+}
+
+// auditedTable is the table of an audited entity.
+func auditedTable(entity string) string {
+	for table, name := range auditedTables {
+		if name == entity {
+			return table
+		}
+	}
+
+	return ""
+}
+
+func decodeRow(value json.RawMessage) (storedRow, error) {
+	var row storedRow
+
+	if err := json.Unmarshal(value, &row); err != nil {
+		return nil, err
+	}
+
+	return row, nil
+}
+
+// rowValue turns a column stored by the audit log back into a value to
+// write. Numbers keep their precision and the *_at columns are times again.
+func rowValue(column string, raw json.RawMessage) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+
+		return v.Float64()
+	case string:
+		if strings.HasSuffix(column, "_at") {
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return t, nil
+			}
+		}
+	}
+
+	return value, nil
+}
+
+func selectOperations() squirrel.SelectBuilder {
+	return squirrel.Select("h.id", "h.actor", "h.request_id", "h.name", "h.undone", "h.created_at").
+		Column("(select count(*) from audit_log a where a.request_id = h.request_id and a.actor = h.actor)").
+		From("history h")
+}
+
+func queryOperations(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.Operation, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	operations := []entities.Operation{}
+
+	for rows.Next() {
+		var row entities.Operation
+
+		if err := rows.Scan(&row.Id, &row.Actor, &row.RequestId, &row.Name, &row.Undone, &row.CreateAt,
+			&row.Changes); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		operations = append(operations, row)
+	}
+
+	return operations, nil
+}
@@ -0,0 +1,253 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lembata/para/internal/entities"
+)
+
+const testActor = "alice"
+
+// testRequest tags ctx like the n-th request of testActor.
+func testRequest(ctx context.Context, n int) context.Context {
+	return WithAuditSource(ctx, testActor, fmt.Sprintf("request-%d", n), "POST /test")
+}
+
+func checkTags(t *testing.T, db *Database, ctx context.Context, transactionId int64, want []string) {
+	t.Helper()
+
+	tags, err := db.GetTransactionTags(ctx, transactionId)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("got tags %v, want %v", tags, want)
+	}
+}
+
+func TestUndoTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(db *Database, ctx context.Context, id int64) error
+		want   []string
+	}{
+		{"add", func(db *Database, ctx context.Context, id int64) error {
+			return db.AddTransactionTags(ctx, id, []string{"work"})
+		}, []string{"home", "trip", "work"}},
+		{"set", func(db *Database, ctx context.Context, id int64) error {
+			return db.SetTransactionTags(ctx, id, []string{"work"})
+		}, []string{"work"}},
+		{"retag", func(db *Database, ctx context.Context, id int64) error {
+			return db.RetagTransaction(ctx, id, []string{"work"}, []string{"trip"})
+		}, []string{"home", "work"}},
+		{"delete tag", func(db *Database, ctx context.Context, id int64) error {
+			tags, err := db.GetTags(ctx)
+
+			if err != nil {
+				return err
+			}
+
+			for _, tag := range tags {
+				if tag.Name == "trip" {
+					return db.DeleteTag(ctx, int64(tag.Id))
+				}
+			}
+
+			return ErrorNotFound
+		}, []string{"home"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, ctx := testContext(t)
+			accountId := testAccount(t, db, ctx, "Checking")
+			id := testTransaction(t, db, ctx, accountId, 1000, "2026-01-10", nil)
+
+			if err := db.AddTransactionTags(ctx, id, []string{"trip", "home"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := test.change(db, testRequest(ctx, 1), id); err != nil {
+				t.Fatal(err)
+			}
+
+			checkTags(t, db, ctx, id, test.want)
+
+			if operations, err := db.Undo(testRequest(ctx, 2), testActor, 1); err != nil || len(operations) != 1 {
+				t.Fatalf("undo returned %v, %v", operations, err)
+			}
+
+			checkTags(t, db, ctx, id, []string{"home", "trip"})
+
+			if operations, err := db.Redo(testRequest(ctx, 3), testActor, 1); err != nil || len(operations) != 1 {
+				t.Fatalf("redo returned %v, %v", operations, err)
+			}
+
+			checkTags(t, db, ctx, id, test.want)
+		})
+	}
+}
+
+func TestUndoDeleteTransaction(t *testing.T) {
+	db, ctx := testContext(t)
+	accountId := testAccount(t, db, ctx, "Checking")
+	id := testTransaction(t, db, ctx, accountId, 1000, "2026-01-10", nil)
+	otherId := testTransaction(t, db, ctx, accountId, 1000, "2026-01-10", nil)
+
+	if err := db.AddTransactionTags(ctx, id, []string{"trip"}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := db.GetItems(ctx, id)
+
+	if err != nil || len(items) != 1 {
+		t.Fatalf("got items %v, %v", items, err)
+	}
+
+	if err := db.AddItemTags(ctx, items[0].Id, []string{"food"}); err != nil {
+		t.Fatal(err)
+	}
+
+	pair := entities.DuplicatePairEntity{TransactionId: id, DuplicateId: otherId, Score: 0.9, CreateAt: time.Now()}
+
+	if err := db.CreateDuplicatePair(ctx, pair); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.CreateAttachment(ctx, entities.AttachmentEntity{Hash: "abc", Name: "receipt.pdf",
+		MimeType: "application/pdf", Size: 3, TransactionId: &id, CreateAt: time.Now()})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.DeleteTransaction(testRequest(ctx, 1), id); err != nil {
+		t.Fatal(err)
+	}
+
+	if hashes, err := db.GetAttachmentHashes(ctx); err != nil || !hashes["abc"] {
+		t.Errorf("got hashes %v, %v, the deleted attachment can still be restored", hashes, err)
+	}
+
+	if _, err := db.Undo(testRequest(ctx, 2), testActor, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	checkTags(t, db, ctx, id, []string{"trip"})
+
+	if items, err = db.GetItems(ctx, id); err != nil || len(items) != 1 {
+		t.Fatalf("got items %v, %v after undo", items, err)
+	}
+
+	if tags, err := db.GetItemTags(ctx, items[0].Id); err != nil || !reflect.DeepEqual(tags, []string{"food"}) {
+		t.Errorf("got item tags %v, %v, want [food]", tags, err)
+	}
+
+	if pairs, err := db.GetDuplicatePairs(ctx, 0, 10); err != nil || len(pairs) != 1 {
+		t.Errorf("got duplicate pairs %v, %v, want one", pairs, err)
+	}
+
+	if attachments, err := db.GetAttachments(ctx, id, 0); err != nil || len(attachments) != 1 {
+		t.Errorf("got attachments %v, %v, want one", attachments, err)
+	}
+
+	if _, err := db.Redo(testRequest(ctx, 3), testActor, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.GetTransactionById(ctx, id); err != ErrorNotFound {
+		t.Errorf("got %v after redo, want %v", err, ErrorNotFound)
+	}
+
+	if pairs, err := db.GetDuplicatePairs(ctx, 0, 10); err != nil || len(pairs) != 0 {
+		t.Errorf("got duplicate pairs %v, %v after redo, want none", pairs, err)
+	}
+}
+
+func TestChangeClearsRedo(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(db *Database, ctx context.Context, id int64) error
+		redone int
+	}{
+		{"no change", nil, 1},
+		{"audited change", func(db *Database, ctx context.Context, id int64) error {
+			return db.AddTransactionTags(ctx, id, []string{"work"})
+		}, 0},
+		{"change that is not audited", func(db *Database, ctx context.Context, id int64) error {
+			_, err := db.CreateTag(ctx, entities.TagEntity{Name: "unused", CreateAt: time.Now()})
+			return err
+		}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, ctx := testContext(t)
+			accountId := testAccount(t, db, ctx, "Checking")
+			id := testTransaction(t, db, ctx, accountId, 1000, "2026-01-10", nil)
+
+			if err := db.AddTransactionTags(testRequest(ctx, 1), id, []string{"trip"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := db.Undo(testRequest(ctx, 2), testActor, 1); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.change != nil {
+				if err := test.change(db, testRequest(ctx, 3), id); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			operations, err := db.Redo(testRequest(ctx, 4), testActor, 10)
+
+			if err != nil || len(operations) != test.redone {
+				t.Errorf("redid %v, %v, want %d operations", operations, err, test.redone)
+			}
+		})
+	}
+}
+
+func TestUndoOperationConflict(t *testing.T) {
+	db, ctx := testContext(t)
+	accountId := testAccount(t, db, ctx, "Checking")
+	id := testTransaction(t, db, ctx, accountId, 1000, "2026-01-10", nil)
+	otherId := testTransaction(t, db, ctx, accountId, 1000, "2026-01-11", nil)
+
+	if err := db.AddTransactionTags(testRequest(ctx, 1), id, []string{"trip"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AddTransactionTags(testRequest(ctx, 2), otherId, []string{"trip"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.RetagTransaction(testRequest(ctx, 3), id, nil, []string{"trip"}); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := db.GetHistory(ctx, testActor)
+
+	if err != nil || len(history) != 3 {
+		t.Fatalf("got history %v, %v", history, err)
+	}
+
+	// the first operation was overwritten by the last one, the second not
+	if _, err := db.UndoOperation(testRequest(ctx, 4), testActor, history[2].Id); err != ErrorConflict {
+		t.Errorf("got %v, want %v", err, ErrorConflict)
+	}
+
+	if _, err := db.UndoOperation(testRequest(ctx, 5), testActor, history[1].Id); err != nil {
+		t.Fatal(err)
+	}
+
+	checkTags(t, db, ctx, otherId, []string{})
+	checkTags(t, db, ctx, id, []string{})
+}
@@ -0,0 +1,341 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+func (db *Database) CreateSecurity(ctx context.Context, security entities.SecurityEntity) (int64, error) {
+	logger.Debugf("Creating security: %v", security)
+
+	sqler := squirrel.Insert("securities").
+		Columns("symbol", "isin", "name", "currency", "created_at", "updated_at").
+		Values(security.Symbol, security.ISIN, security.Name, security.Currency,
+			security.CreateAt, security.UpdateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// EditSecurity saves a security read at security.Version.
+func (db *Database) EditSecurity(ctx context.Context, security entities.SecurityEntity) (int64, error) {
+	logger.Debugf("Editing security: %v", security)
+
+	sqler := squirrel.Update("securities").
+		Set("symbol", security.Symbol).
+		Set("isin", security.ISIN).
+		Set("name", security.Name).
+		Set("currency", security.Currency).
+		Set("updated_at", security.UpdateAt).
+		Where("id = ?", security.Id)
+
+	result, err := execVersioned(ctx, "securities", security.Id, security.Version, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteSecurity removes a security and its price history, it must not be
+// traded anymore.
+func (db *Database) DeleteSecurity(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting security: %d", id)
+
+	if _, err := exec(ctx, squirrel.Delete("security_prices").Where("security_id = ?", id)); err != nil {
+		return err
+	}
+
+	result, err := exec(ctx, squirrel.Delete("securities").Where("id = ?", id))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (db *Database) GetSecurityById(ctx context.Context, id int64) (entities.SecurityEntity, error) {
+	return querySecurity(ctx, selectSecurities().Where("id = ?", id))
+}
+
+// GetSecurityByCode finds a security by its symbol or its ISIN.
+func (db *Database) GetSecurityByCode(ctx context.Context, code string) (entities.SecurityEntity, error) {
+	return querySecurity(ctx, selectSecurities().Where("symbol = ? or (isin != '' and isin = ?)", code, code))
+}
+
+func (db *Database) GetSecurities(ctx context.Context) ([]entities.SecurityEntity, error) {
+	logger.Debugf("Getting securities")
+
+	return querySecurities(ctx, selectSecurities().OrderBy("symbol"))
+}
+
+// SetSecurityPrice records the closing price of a security on a day,
+// replacing an earlier price of that day.
+func (db *Database) SetSecurityPrice(ctx context.Context, price entities.SecurityPrice) error {
+	sqler := squirrel.Insert("security_prices").
+		Options("or replace").
+		Columns("security_id", "date", "price").
+		Values(price.SecurityId, price.Date, price.Price)
+
+	_, err := exec(ctx, sqler)
+
+	return err
+}
+
+// GetSecurityPrices returns the price history of a security between two
+// dates, both optional, the oldest first.
+func (db *Database) GetSecurityPrices(ctx context.Context, securityId int64, fromDate string, toDate string) ([]entities.SecurityPrice, error) {
+	logger.Debugf("Getting prices of security %d from %s to %s", securityId, fromDate, toDate)
+
+	sqler := squirrel.Select("security_id", "date", "price").
+		From("security_prices").
+		Where("security_id = ?", securityId).
+		OrderBy("date")
+
+	if fromDate != "" {
+		sqler = sqler.Where("date >= ?", fromDate)
+	}
+
+	if toDate != "" {
+		sqler = sqler.Where("date <= ?", toDate)
+	}
+
+	return queryPrices(ctx, sqler)
+}
+
+// GetLatestPrices returns the latest price of every security on or before
+// the day until, keyed by security.
+func (db *Database) GetLatestPrices(ctx context.Context, until string) (map[int64]entities.SecurityPrice, error) {
+	sqler := squirrel.Select("p.security_id", "p.date", "p.price").
+		From("security_prices p").
+		Where("p.date = (select max(l.date) from security_prices l where l.security_id = p.security_id and l.date <= ?)", until)
+
+	prices, err := queryPrices(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make(map[int64]entities.SecurityPrice, len(prices))
+
+	for _, price := range prices {
+		latest[price.SecurityId] = price
+	}
+
+	return latest, nil
+}
+
+func (db *Database) CreateTrade(ctx context.Context, trade entities.TradeEntity) (int64, error) {
+	logger.Debugf("Creating trade: %v", trade)
+
+	sqler := squirrel.Insert("security_trades").
+		Columns("account_id", "security_id", "transaction_id", "kind", "date",
+			"quantity", "price", "fee", "amount", "created_at").
+		Values(trade.AccountId, trade.SecurityId, trade.TransactionId, trade.Kind, trade.Date,
+			trade.Quantity, trade.Price, trade.Fee, trade.Amount, trade.CreateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, auditCreated(ctx, "security_trades", id)
+}
+
+// DeleteTrade removes a trade together with the transaction booking it.
+func (db *Database) DeleteTrade(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting trade: %d", id)
+
+	trade, err := db.GetTradeById(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	return db.DeleteTransaction(ctx, trade.TransactionId)
+}
+
+func (db *Database) GetTradeById(ctx context.Context, id int64) (entities.TradeEntity, error) {
+	trades, err := queryTrades(ctx, selectTrades().Where("id = ?", id))
+
+	if err != nil {
+		return entities.TradeEntity{}, err
+	}
+
+	if len(trades) == 0 {
+		return entities.TradeEntity{}, ErrorNotFound
+	}
+
+	return trades[0], nil
+}
+
+// GetTrades returns the trades of an account, or of every account when
+// accountId is 0, in the order they happened. securityId and until narrow
+// them down when set.
+func (db *Database) GetTrades(ctx context.Context, accountId int64, securityId int64, until string) ([]entities.TradeEntity, error) {
+	logger.Debugf("Getting trades of account %d", accountId)
+
+	sqler := selectTrades().OrderBy("date", "id")
+
+	if accountId != 0 {
+		sqler = sqler.Where("account_id = ?", accountId)
+	}
+
+	if securityId != 0 {
+		sqler = sqler.Where("security_id = ?", securityId)
+	}
+
+	if until != "" {
+		sqler = sqler.Where("date <= ?", until)
+	}
+
+	return queryTrades(ctx, sqler)
+}
+
+// holdingsValue values the securities held by the account aliased as a at
+// their latest price, or at the price of their latest trade when there is
+// no price history, on the day until when it is set. Securities are valued
+// in the currency of the account.
+func holdingsValue(until string) squirrel.Sqlizer {
+	priceFilter, tradeFilter, filter := "", "", ""
+	var args []interface{}
+
+	if until != "" {
+		priceFilter = " and p.date <= ?"
+		tradeFilter = " and l.date <= ?"
+		filter = " and st.date <= ?"
+		args = []interface{}{until, until, until}
+	}
+
+	quantity := fmt.Sprintf("case st.kind when %d then st.quantity when %d then -st.quantity else 0 end",
+		entities.TradeBuy, entities.TradeSell)
+
+	price := "coalesce((select p.price from security_prices p where p.security_id = st.security_id" + priceFilter +
+		" order by p.date desc limit 1), " +
+		fmt.Sprintf("(select l.price from security_trades l where l.security_id = st.security_id and l.kind in (%d, %d)",
+			entities.TradeBuy, entities.TradeSell) +
+		tradeFilter + " order by l.date desc, l.id desc limit 1), 0)"
+
+	return squirrel.Expr("cast(round(ifnull((select sum(("+quantity+") * "+price+") from security_trades st"+
+		" where st.account_id = a.id"+filter+"), 0)) as integer)", args...)
+}
+
+func selectSecurities() squirrel.SelectBuilder {
+	return squirrel.Select("id", "symbol", "isin", "name", "currency", "version", "created_at", "updated_at").
+		From("securities")
+}
+
+func querySecurity(ctx context.Context, sqler squirrel.SelectBuilder) (entities.SecurityEntity, error) {
+	securities, err := querySecurities(ctx, sqler.Limit(1))
+
+	if err != nil {
+		return entities.SecurityEntity{}, err
+	}
+
+	if len(securities) == 0 {
+		return entities.SecurityEntity{}, ErrorNotFound
+	}
+
+	return securities[0], nil
+}
+
+func querySecurities(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.SecurityEntity, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var securities []entities.SecurityEntity
+
+	for rows.Next() {
+		var row entities.SecurityEntity
+
+		if err := rows.Scan(&row.Id, &row.Symbol, &row.ISIN, &row.Name, &row.Currency,
+			&row.Version, &row.CreateAt, &row.UpdateAt); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		securities = append(securities, row)
+	}
+
+	return securities, nil
+}
+
+func queryPrices(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.SecurityPrice, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	prices := []entities.SecurityPrice{}
+
+	for rows.Next() {
+		var row entities.SecurityPrice
+
+		if err := rows.Scan(&row.SecurityId, &row.Date, &row.Price); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		prices = append(prices, row)
+	}
+
+	return prices, nil
+}
+
+func selectTrades() squirrel.SelectBuilder {
+	return squirrel.Select("id", "account_id", "security_id", "transaction_id", "kind", "date",
+		"quantity", "price", "fee", "amount", "created_at").
+		From("security_trades")
+}
+
+func queryTrades(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.TradeEntity, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var trades []entities.TradeEntity
+
+	for rows.Next() {
+		var row entities.TradeEntity
+
+		if err := rows.Scan(&row.Id, &row.AccountId, &row.SecurityId, &row.TransactionId, &row.Kind,
+			&row.Date, &row.Quantity, &row.Price, &row.Fee, &row.Amount, &row.CreateAt); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		trades = append(trades, row)
+	}
+
+	return trades, nil
+}
@@ -0,0 +1,218 @@
+package database
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+func (db *Database) CreateLoan(ctx context.Context, loan entities.LoanEntity) (int64, error) {
+	logger.Debugf("Creating loan: %v", loan)
+
+	sqler := squirrel.Insert("loans").
+		Columns("account_id", "principal", "interest_rate", "term_months", "start_date",
+			"method", "interest_category_id", "version", "created_at", "updated_at").
+		Values(loan.AccountId, loan.Principal, loan.InterestRate, loan.TermMonths, loan.StartDate,
+			loan.Method, loan.InterestCategoryId, loan.CreateAt, loan.UpdateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// EditLoan changes the terms of a loan read at loan.Version, payments that
+// were already split keep their split.
+func (db *Database) EditLoan(ctx context.Context, loan entities.LoanEntity) (int64, error) {
+	logger.Debugf("Editing loan: %v", loan)
+
+	sqler := squirrel.Update("loans").
+		Set("principal", loan.Principal).
+		Set("interest_rate", loan.InterestRate).
+		Set("term_months", loan.TermMonths).
+		Set("start_date", loan.StartDate).
+		Set("method", loan.Method).
+		Set("interest_category_id", loan.InterestCategoryId).
+		Set("updated_at", loan.UpdateAt).
+		Where("id = ?", loan.Id)
+
+	result, err := execVersioned(ctx, "loans", loan.Id, loan.Version, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteLoan removes the terms of a loan, the account and its transactions,
+// including the interest charges, are kept.
+func (db *Database) DeleteLoan(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting loan: %d", id)
+
+	if _, err := exec(ctx, squirrel.Delete("loan_payments").Where("loan_id = ?", id)); err != nil {
+		return err
+	}
+
+	result, err := exec(ctx, squirrel.Delete("loans").Where("id = ?", id))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (db *Database) GetLoanById(ctx context.Context, id int64) (entities.LoanEntity, error) {
+	return queryLoan(ctx, selectLoans().Where("id = ?", id))
+}
+
+func (db *Database) GetLoanByAccount(ctx context.Context, accountId int64) (entities.LoanEntity, error) {
+	return queryLoan(ctx, selectLoans().Where("account_id = ?", accountId))
+}
+
+func (db *Database) GetLoans(ctx context.Context) ([]entities.LoanEntity, error) {
+	logger.Debugf("Getting loans")
+
+	return queryLoans(ctx, selectLoans().OrderBy("start_date", "id"))
+}
+
+func (db *Database) CreateLoanPayment(ctx context.Context, payment entities.LoanPaymentEntity) (int64, error) {
+	logger.Debugf("Creating loan payment: %v", payment)
+
+	sqler := squirrel.Insert("loan_payments").
+		Columns("loan_id", "transaction_id", "interest_transaction_id", "principal", "interest").
+		Values(payment.LoanId, payment.TransactionId, payment.InterestTransactionId,
+			payment.Principal, payment.Interest)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, auditCreated(ctx, "loan_payments", id)
+}
+
+// DeleteLoanPayment removes the split of a payment together with its
+// interest charge. It returns ErrorNotFound when the payment was not split.
+func (db *Database) DeleteLoanPayment(ctx context.Context, transactionId int64) error {
+	logger.Debugf("Deleting split of loan payment: %d", transactionId)
+
+	interestId, err := queryId(ctx, squirrel.Select("interest_transaction_id").
+		From("loan_payments").
+		Where("transaction_id = ?", transactionId).
+		Where("interest_transaction_id is not null"))
+
+	if err == nil {
+		// deleting the interest charge also deletes the split
+		return db.DeleteTransaction(ctx, interestId)
+	} else if err != ErrorNotFound {
+		return err
+	}
+
+	where := squirrel.Eq{"transaction_id": transactionId}
+	result, err := execAudited(ctx, "loan_payments", where, squirrel.Delete("loan_payments").Where(where))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// GetLoanPaymentTotals sums the principal and interest of the split payments
+// of a loan up to and including the day until, all of them when until is
+// empty, leaving out the payment excluded. It also returns the date of the
+// latest of these payments, empty when there is none.
+func (db *Database) GetLoanPaymentTotals(ctx context.Context, loanId int64, until string, excluded int64) (int, int, string, error) {
+	sqler := squirrel.Select("ifnull(sum(lp.principal), 0)", "ifnull(sum(lp.interest), 0)", "ifnull(max(t.date), '')").
+		From("loan_payments lp").
+		Join("transactions t on t.id = lp.transaction_id").
+		Where("lp.loan_id = ?", loanId).
+		Where("lp.transaction_id != ?", excluded)
+
+	if until != "" {
+		sqler = sqler.Where("t.date <= ?", until)
+	}
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return 0, 0, "", err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	var principal, interest int
+	var lastDate string
+
+	if rows.Next() {
+		err = rows.Scan(&principal, &interest, &lastDate)
+	}
+
+	return principal, interest, lastDate, err
+}
+
+func selectLoans() squirrel.SelectBuilder {
+	return squirrel.Select("id", "account_id", "principal", "interest_rate", "term_months", "start_date",
+		"method", "interest_category_id", "created_at", "updated_at").
+		From("loans")
+}
+
+func queryLoan(ctx context.Context, sqler squirrel.SelectBuilder) (entities.LoanEntity, error) {
+	loans, err := queryLoans(ctx, sqler.Limit(1))
+
+	if err != nil {
+		return entities.LoanEntity{}, err
+	}
+
+	if len(loans) == 0 {
+		return entities.LoanEntity{}, ErrorNotFound
+	}
+
+	return loans[0], nil
+}
+
+func queryLoans(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.LoanEntity, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var loans []entities.LoanEntity
+
+	for rows.Next() {
+		var row entities.LoanEntity
+
+		if err := rows.Scan(&row.Id, &row.AccountId, &row.Principal, &row.InterestRate, &row.TermMonths,
+			&row.StartDate, &row.Method, &row.InterestCategoryId, &row.Version, &row.CreateAt, &row.UpdateAt); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		loans = append(loans, row)
+	}
+
+	return loans, nil
+}
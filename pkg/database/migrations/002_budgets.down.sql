@@ -0,0 +1,2 @@
+drop index index_budgets_on_category_id_and_month;
+drop table budgets;
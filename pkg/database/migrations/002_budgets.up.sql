@@ -0,0 +1,13 @@
+create table budgets (
+  id integer not null primary key autoincrement,
+  category_id integer not null,
+  month varchar(7) not null,
+  amount integer not null,
+  currency varchar(3) not null default 'EUR',
+  rollover boolean not null default false,
+  created_at datetime not null,
+  updated_at datetime not null,
+  foreign key (category_id) references categories (id)
+);
+
+create unique index index_budgets_on_category_id_and_month on budgets (category_id, month);
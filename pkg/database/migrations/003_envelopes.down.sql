@@ -0,0 +1,20 @@
+drop index index_envelope_transfers_on_month;
+drop table envelope_transfers;
+
+create table transactions_old (
+  id integer not null primary key autoincrement,
+  from_account_id integer not null,
+  to_account_id integer not null,
+  total_amount integer not null,
+  created_at datetime not null,
+  updated_at datetime not null,
+  foreign key (from_account_id) references accounts (id),
+  foreign key (to_account_id) references accounts (id)
+);
+
+insert into transactions_old (id, from_account_id, to_account_id, total_amount, created_at, updated_at)
+  select id, from_account_id, to_account_id, total_amount, created_at, updated_at from transactions
+  where from_account_id is not null and to_account_id is not null;
+
+drop table transactions;
+alter table transactions_old rename to transactions;
@@ -0,0 +1,31 @@
+-- money coming from or going to the outside world has no account on that side
+create table transactions_new (
+  id integer not null primary key autoincrement,
+  from_account_id integer,
+  to_account_id integer,
+  total_amount integer not null,
+  created_at datetime not null,
+  updated_at datetime not null,
+  foreign key (from_account_id) references accounts (id),
+  foreign key (to_account_id) references accounts (id)
+);
+
+insert into transactions_new (id, from_account_id, to_account_id, total_amount, created_at, updated_at)
+  select id, from_account_id, to_account_id, total_amount, created_at, updated_at from transactions;
+
+drop table transactions;
+alter table transactions_new rename to transactions;
+
+create table envelope_transfers (
+  id integer not null primary key autoincrement,
+  from_category_id integer,
+  to_category_id integer,
+  month varchar(7) not null,
+  amount integer not null,
+  currency varchar(3) not null default 'EUR',
+  created_at datetime not null,
+  foreign key (from_category_id) references categories (id),
+  foreign key (to_category_id) references categories (id)
+);
+
+create index index_envelope_transfers_on_month on envelope_transfers (month);
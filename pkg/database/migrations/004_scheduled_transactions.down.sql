@@ -0,0 +1,4 @@
+drop index index_transactions_on_scheduled_transaction_id;
+alter table transactions drop column scheduled_transaction_id;
+alter table transactions drop column pending;
+drop table scheduled_transactions;
@@ -0,0 +1,27 @@
+create table scheduled_transactions (
+  id integer not null primary key autoincrement,
+  name varchar(255) not null,
+  from_account_id integer,
+  to_account_id integer,
+  amount integer not null,
+  category_id integer,
+  frequency integer not null,
+  interval integer not null default 1,
+  weekday integer not null default -1,
+  day_of_month integer not null default 0,
+  start_date varchar(10) not null,
+  end_date varchar(10),
+  max_occurrences integer not null default 0,
+  occurrences integer not null default 0,
+  pending boolean not null default false,
+  created_at datetime not null,
+  updated_at datetime not null,
+  foreign key (from_account_id) references accounts (id),
+  foreign key (to_account_id) references accounts (id),
+  foreign key (category_id) references categories (id)
+);
+
+alter table transactions add column pending boolean not null default false;
+alter table transactions add column scheduled_transaction_id integer references scheduled_transactions (id);
+
+create index index_transactions_on_scheduled_transaction_id on transactions (scheduled_transaction_id);
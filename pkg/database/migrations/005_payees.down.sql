@@ -0,0 +1,9 @@
+drop index index_transactions_on_payee_id;
+drop index index_transactions_on_date;
+alter table transactions drop column payee_id;
+alter table transactions drop column date;
+drop index index_payee_aliases_on_normalized_name;
+drop table payee_aliases;
+drop index index_payees_on_iban;
+drop index index_payees_on_normalized_name;
+drop table payees;
@@ -0,0 +1,32 @@
+create table payees (
+  id integer not null primary key autoincrement,
+  name varchar(255) not null,
+  normalized_name varchar(255) not null,
+  default_category_id integer,
+  iban varchar(34),
+  created_at datetime not null,
+  updated_at datetime not null,
+  foreign key (default_category_id) references categories (id)
+);
+
+create index index_payees_on_normalized_name on payees (normalized_name);
+create index index_payees_on_iban on payees (iban);
+
+create table payee_aliases (
+  id integer not null primary key autoincrement,
+  payee_id integer not null,
+  name varchar(255) not null,
+  normalized_name varchar(255) not null,
+  foreign key (payee_id) references payees (id)
+);
+
+create unique index index_payee_aliases_on_normalized_name on payee_aliases (normalized_name);
+
+-- the booking date of a transaction, created_at only records when the row was added
+alter table transactions add column date varchar(10) not null default '';
+update transactions set date = substr(created_at, 1, 10);
+
+alter table transactions add column payee_id integer references payees (id);
+
+create index index_transactions_on_date on transactions (date);
+create index index_transactions_on_payee_id on transactions (payee_id);
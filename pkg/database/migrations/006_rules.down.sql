@@ -0,0 +1,7 @@
+drop table rules;
+drop index index_transaction_tags_on_tag_id;
+drop table transaction_tags;
+drop index index_tags_on_name;
+drop table tags;
+alter table transactions drop column notes;
+alter table transactions drop column description;
@@ -0,0 +1,32 @@
+alter table transactions add column description varchar(255) not null default '';
+alter table transactions add column notes varchar(1024) not null default '';
+
+create table tags (
+  id integer not null primary key autoincrement,
+  name varchar(255) not null collate nocase,
+  created_at datetime not null
+);
+
+create unique index index_tags_on_name on tags (name);
+
+create table transaction_tags (
+  transaction_id integer not null,
+  tag_id integer not null,
+  primary key (transaction_id, tag_id),
+  foreign key (transaction_id) references transactions (id),
+  foreign key (tag_id) references tags (id)
+);
+
+create index index_transaction_tags_on_tag_id on transaction_tags (tag_id);
+
+create table rules (
+  id integer not null primary key autoincrement,
+  name varchar(255) not null,
+  priority integer not null default 0,
+  enabled boolean not null default true,
+  stop_processing boolean not null default false,
+  conditions text not null,
+  actions text not null,
+  created_at datetime not null,
+  updated_at datetime not null
+);
@@ -0,0 +1,2 @@
+drop index index_item_tags_on_tag_id;
+drop table item_tags;
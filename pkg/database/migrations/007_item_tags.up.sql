@@ -0,0 +1,9 @@
+create table item_tags (
+  item_id integer not null,
+  tag_id integer not null,
+  primary key (item_id, tag_id),
+  foreign key (item_id) references items (id),
+  foreign key (tag_id) references tags (id)
+);
+
+create index index_item_tags_on_tag_id on item_tags (tag_id);
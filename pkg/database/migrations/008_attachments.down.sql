@@ -0,0 +1,4 @@
+drop index index_attachments_on_account_id;
+drop index index_attachments_on_transaction_id;
+drop index index_attachments_on_hash;
+drop table attachments;
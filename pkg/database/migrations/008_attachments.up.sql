@@ -0,0 +1,16 @@
+create table attachments (
+  id integer not null primary key autoincrement,
+  hash varchar(64) not null,
+  name varchar(255) not null,
+  mime_type varchar(255) not null,
+  size integer not null,
+  transaction_id integer,
+  account_id integer,
+  created_at datetime not null,
+  foreign key (transaction_id) references transactions (id),
+  foreign key (account_id) references accounts (id)
+);
+
+create index index_attachments_on_hash on attachments (hash);
+create index index_attachments_on_transaction_id on attachments (transaction_id);
+create index index_attachments_on_account_id on attachments (account_id);
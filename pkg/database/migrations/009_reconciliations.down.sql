@@ -0,0 +1,3 @@
+drop index index_reconciliations_on_account_id;
+drop table reconciliations;
+alter table transactions drop column status;
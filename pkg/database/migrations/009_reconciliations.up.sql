@@ -0,0 +1,15 @@
+alter table transactions add column status integer not null default 0;
+
+create table reconciliations (
+  id integer not null primary key autoincrement,
+  account_id integer not null,
+  statement_date varchar(10) not null,
+  statement_balance integer not null,
+  source varchar(16) not null default 'manual',
+  finished_at datetime,
+  created_at datetime not null,
+  updated_at datetime not null,
+  foreign key (account_id) references accounts (id)
+);
+
+create index index_reconciliations_on_account_id on reconciliations (account_id);
@@ -0,0 +1,3 @@
+drop index index_duplicate_pairs_on_duplicate_id;
+drop index index_duplicate_pairs_on_transaction_id_and_duplicate_id;
+drop table duplicate_pairs;
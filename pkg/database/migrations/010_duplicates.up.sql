@@ -0,0 +1,13 @@
+create table duplicate_pairs (
+  id integer not null primary key autoincrement,
+  transaction_id integer not null,
+  duplicate_id integer not null,
+  score real not null,
+  dismissed boolean not null default false,
+  created_at datetime not null,
+  foreign key (transaction_id) references transactions (id),
+  foreign key (duplicate_id) references transactions (id)
+);
+
+create unique index index_duplicate_pairs_on_transaction_id_and_duplicate_id on duplicate_pairs (transaction_id, duplicate_id);
+create index index_duplicate_pairs_on_duplicate_id on duplicate_pairs (duplicate_id);
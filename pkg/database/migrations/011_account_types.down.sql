@@ -0,0 +1,2 @@
+alter table accounts drop column payment_due_day;
+alter table accounts drop column statement_closing_day;
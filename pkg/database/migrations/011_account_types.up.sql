@@ -0,0 +1,2 @@
+alter table accounts add column statement_closing_day integer not null default 0;
+alter table accounts add column payment_due_day integer not null default 0;
@@ -0,0 +1,5 @@
+drop index index_loan_payments_on_loan_id;
+drop index index_loan_payments_on_transaction_id;
+drop table loan_payments;
+drop index index_loans_on_account_id;
+drop table loans;
@@ -0,0 +1,31 @@
+create table loans (
+  id integer not null primary key autoincrement,
+  account_id integer not null,
+  principal integer not null,
+  interest_rate real not null,
+  term_months integer not null,
+  start_date varchar(10) not null,
+  method integer not null default 0,
+  interest_category_id integer,
+  created_at datetime not null,
+  updated_at datetime not null,
+  foreign key (account_id) references accounts (id),
+  foreign key (interest_category_id) references categories (id)
+);
+
+create unique index index_loans_on_account_id on loans (account_id);
+
+create table loan_payments (
+  id integer not null primary key autoincrement,
+  loan_id integer not null,
+  transaction_id integer not null,
+  interest_transaction_id integer,
+  principal integer not null,
+  interest integer not null,
+  foreign key (loan_id) references loans (id),
+  foreign key (transaction_id) references transactions (id),
+  foreign key (interest_transaction_id) references transactions (id)
+);
+
+create unique index index_loan_payments_on_transaction_id on loan_payments (transaction_id);
+create index index_loan_payments_on_loan_id on loan_payments (loan_id);
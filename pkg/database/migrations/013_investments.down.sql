@@ -0,0 +1,6 @@
+drop table security_prices;
+drop index index_security_trades_on_transaction_id;
+drop index index_security_trades_on_account_id;
+drop table security_trades;
+drop index index_securities_on_symbol;
+drop table securities;
@@ -0,0 +1,39 @@
+create table securities (
+  id integer not null primary key autoincrement,
+  symbol varchar(32) not null collate nocase,
+  isin varchar(12) not null default '',
+  name varchar(255) not null,
+  currency varchar(3) not null,
+  created_at datetime not null,
+  updated_at datetime not null
+);
+
+create unique index index_securities_on_symbol on securities (symbol);
+
+create table security_trades (
+  id integer not null primary key autoincrement,
+  account_id integer not null,
+  security_id integer not null,
+  transaction_id integer not null,
+  kind integer not null,
+  date varchar(10) not null,
+  quantity real not null default 0,
+  price integer not null default 0,
+  fee integer not null default 0,
+  amount integer not null,
+  created_at datetime not null,
+  foreign key (account_id) references accounts (id),
+  foreign key (security_id) references securities (id),
+  foreign key (transaction_id) references transactions (id)
+);
+
+create index index_security_trades_on_account_id on security_trades (account_id, security_id, date);
+create index index_security_trades_on_transaction_id on security_trades (transaction_id);
+
+create table security_prices (
+  security_id integer not null,
+  date varchar(10) not null,
+  price integer not null,
+  primary key (security_id, date),
+  foreign key (security_id) references securities (id)
+);
@@ -0,0 +1,2 @@
+drop index index_account_valuations_on_account_id_and_date;
+drop table account_valuations;
@@ -0,0 +1,11 @@
+create table account_valuations (
+  id integer not null primary key autoincrement,
+  account_id integer not null,
+  date varchar(10) not null,
+  value integer not null,
+  notes varchar(1024) not null default '',
+  created_at datetime not null,
+  foreign key (account_id) references accounts (id)
+);
+
+create unique index index_account_valuations_on_account_id_and_date on account_valuations (account_id, date);
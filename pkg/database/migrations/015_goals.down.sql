@@ -0,0 +1,2 @@
+drop index index_goals_on_account_id;
+drop table goals;
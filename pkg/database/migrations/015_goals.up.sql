@@ -0,0 +1,15 @@
+create table goals (
+  id integer not null primary key autoincrement,
+  name varchar(255) not null,
+  account_id integer not null,
+  target_amount integer not null,
+  target_date varchar(10) not null,
+  allocation integer,
+  start_date varchar(10) not null,
+  start_amount integer not null default 0,
+  created_at datetime not null,
+  updated_at datetime not null,
+  foreign key (account_id) references accounts (id)
+);
+
+create index index_goals_on_account_id on goals (account_id);
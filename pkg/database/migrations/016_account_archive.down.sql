@@ -0,0 +1,2 @@
+alter table accounts drop column deleted_at;
+alter table accounts drop column archived;
@@ -0,0 +1,2 @@
+alter table accounts add column archived boolean not null default false;
+alter table accounts add column deleted_at datetime;
@@ -0,0 +1,4 @@
+drop index index_accounts_on_group_id;
+alter table accounts drop column group_id;
+drop index index_account_groups_on_name;
+drop table account_groups;
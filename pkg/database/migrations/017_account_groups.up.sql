@@ -0,0 +1,15 @@
+create table account_groups (
+  id integer not null primary key autoincrement,
+  name varchar(255) not null collate nocase,
+  order_index integer not null default 0,
+  created_at datetime not null,
+  updated_at datetime not null
+);
+
+create unique index index_account_groups_on_name on account_groups (name);
+
+alter table accounts add column group_id integer references account_groups (id);
+
+create index index_accounts_on_group_id on accounts (group_id);
+
+update accounts set order_index = id;
@@ -0,0 +1,3 @@
+drop index index_audit_log_on_created_at;
+drop index index_audit_log_on_entity;
+drop table audit_log;
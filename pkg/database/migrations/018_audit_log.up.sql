@@ -0,0 +1,14 @@
+create table audit_log (
+  id integer not null primary key autoincrement,
+  created_at datetime not null,
+  actor varchar(255) not null,
+  request_id varchar(255) not null default '',
+  entity varchar(32) not null,
+  entity_id integer not null,
+  action varchar(16) not null,
+  before text,
+  after text
+);
+
+create index index_audit_log_on_entity on audit_log (entity, entity_id);
+create index index_audit_log_on_created_at on audit_log (created_at);
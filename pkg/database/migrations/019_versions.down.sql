@@ -0,0 +1,12 @@
+alter table goals drop column version;
+alter table account_valuations drop column version;
+alter table securities drop column version;
+alter table loans drop column version;
+alter table tags drop column version;
+alter table rules drop column version;
+alter table payees drop column version;
+alter table transactions drop column version;
+alter table scheduled_transactions drop column version;
+alter table budgets drop column version;
+alter table account_groups drop column version;
+alter table accounts drop column version;
@@ -0,0 +1,12 @@
+alter table accounts add column version integer not null default 1;
+alter table account_groups add column version integer not null default 1;
+alter table budgets add column version integer not null default 1;
+alter table scheduled_transactions add column version integer not null default 1;
+alter table transactions add column version integer not null default 1;
+alter table payees add column version integer not null default 1;
+alter table rules add column version integer not null default 1;
+alter table tags add column version integer not null default 1;
+alter table loans add column version integer not null default 1;
+alter table securities add column version integer not null default 1;
+alter table account_valuations add column version integer not null default 1;
+alter table goals add column version integer not null default 1;
@@ -0,0 +1,4 @@
+drop index index_audit_log_on_request_id;
+drop index index_history_on_actor;
+drop index index_history_on_request_id;
+drop table history;
@@ -0,0 +1,12 @@
+create table history (
+  id integer not null primary key autoincrement,
+  actor varchar(255) not null,
+  request_id varchar(255) not null,
+  name varchar(255) not null,
+  undone boolean not null default false,
+  created_at datetime not null
+);
+
+create unique index index_history_on_request_id on history (request_id);
+create index index_history_on_actor on history (actor, id);
+create index index_audit_log_on_request_id on audit_log (request_id);
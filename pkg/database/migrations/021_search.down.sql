@@ -0,0 +1,16 @@
+drop trigger search_tags_delete;
+drop trigger search_tags_update;
+drop trigger search_tags_insert;
+drop table search_tags;
+drop trigger search_payees_delete;
+drop trigger search_payees_update;
+drop trigger search_payees_insert;
+drop table search_payees;
+drop trigger search_items_delete;
+drop trigger search_items_update;
+drop trigger search_items_insert;
+drop table search_items;
+drop trigger search_transactions_delete;
+drop trigger search_transactions_update;
+drop trigger search_transactions_insert;
+drop table search_transactions;
@@ -0,0 +1,90 @@
+-- the search indexes need SQLite with FTS5, build with -tags sqlite_fts5
+
+create virtual table search_transactions using fts5(
+  description,
+  notes,
+  content = 'transactions',
+  content_rowid = 'id',
+  tokenize = 'unicode61 remove_diacritics 2'
+);
+
+insert into search_transactions (search_transactions) values ('rebuild');
+
+create trigger search_transactions_insert after insert on transactions begin
+  insert into search_transactions (rowid, description, notes) values (new.id, new.description, new.notes);
+end;
+
+create trigger search_transactions_update after update of description, notes on transactions begin
+  insert into search_transactions (search_transactions, rowid, description, notes) values ('delete', old.id, old.description, old.notes);
+  insert into search_transactions (rowid, description, notes) values (new.id, new.description, new.notes);
+end;
+
+create trigger search_transactions_delete after delete on transactions begin
+  insert into search_transactions (search_transactions, rowid, description, notes) values ('delete', old.id, old.description, old.notes);
+end;
+
+create virtual table search_items using fts5(
+  name,
+  content = 'items',
+  content_rowid = 'id',
+  tokenize = 'unicode61 remove_diacritics 2'
+);
+
+insert into search_items (search_items) values ('rebuild');
+
+create trigger search_items_insert after insert on items begin
+  insert into search_items (rowid, name) values (new.id, new.name);
+end;
+
+create trigger search_items_update after update of name on items begin
+  insert into search_items (search_items, rowid, name) values ('delete', old.id, old.name);
+  insert into search_items (rowid, name) values (new.id, new.name);
+end;
+
+create trigger search_items_delete after delete on items begin
+  insert into search_items (search_items, rowid, name) values ('delete', old.id, old.name);
+end;
+
+create virtual table search_payees using fts5(
+  name,
+  content = 'payees',
+  content_rowid = 'id',
+  tokenize = 'unicode61 remove_diacritics 2'
+);
+
+insert into search_payees (search_payees) values ('rebuild');
+
+create trigger search_payees_insert after insert on payees begin
+  insert into search_payees (rowid, name) values (new.id, new.name);
+end;
+
+create trigger search_payees_update after update of name on payees begin
+  insert into search_payees (search_payees, rowid, name) values ('delete', old.id, old.name);
+  insert into search_payees (rowid, name) values (new.id, new.name);
+end;
+
+create trigger search_payees_delete after delete on payees begin
+  insert into search_payees (search_payees, rowid, name) values ('delete', old.id, old.name);
+end;
+
+create virtual table search_tags using fts5(
+  name,
+  content = 'tags',
+  content_rowid = 'id',
+  tokenize = 'unicode61 remove_diacritics 2'
+);
+
+insert into search_tags (search_tags) values ('rebuild');
+
+create trigger search_tags_insert after insert on tags begin
+  insert into search_tags (rowid, name) values (new.id, new.name);
+end;
+
+create trigger search_tags_update after update of name on tags begin
+  insert into search_tags (search_tags, rowid, name) values ('delete', old.id, old.name);
+  insert into search_tags (rowid, name) values (new.id, new.name);
+end;
+
+create trigger search_tags_delete after delete on tags begin
+  insert into search_tags (search_tags, rowid, name) values ('delete', old.id, old.name);
+end;
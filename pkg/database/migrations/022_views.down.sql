@@ -0,0 +1,2 @@
+drop index index_views_on_owner_list_name;
+drop table views;
@@ -0,0 +1,16 @@
+create table views (
+  id integer not null primary key autoincrement,
+  owner varchar(255) not null,
+  list varchar(32) not null,
+  name varchar(255) not null,
+  filters text not null,
+  query varchar(1024) not null default '',
+  order_by varchar(64) not null default '',
+  sort_order varchar(4) not null default '',
+  columns text not null,
+  version integer not null default 1,
+  created_at datetime not null,
+  updated_at datetime not null
+);
+
+create unique index index_views_on_owner_list_name on views (owner, list, name);
@@ -52,9 +52,14 @@ func (m *Migrator) RunMigration(ctx context.Context, newVersion uint) error {
 	// }
 
 	var err error
-	if err = m.migrate.Up(); err != nil {
-		// migration failed
-		m.migrate.Down()
+	if err = m.migrate.Migrate(newVersion); err != nil {
+		// migration failed, the migration itself is rolled back with its
+		// transaction so only the dirty version has to be reset
+		forceVersion := int(databaseSchemaVersion)
+		if forceVersion == 0 {
+			forceVersion = -1
+		}
+		_ = m.migrate.Force(forceVersion)
 		return err
 	}
 
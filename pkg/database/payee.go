@@ -0,0 +1,391 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+func (db *Database) CreatePayee(ctx context.Context, payee entities.PayeeEntity) (int64, error) {
+	logger.Debugf("Creating payee: %v", payee)
+
+	sqler := squirrel.Insert("payees").
+		Columns("name", "normalized_name", "default_category_id", "iban",
+			"created_at", "updated_at").
+		Values(payee.Name, normalizeName(payee.Name), payee.DefaultCategoryId,
+			squirrel.Expr("nullif(?, '')", normalizeIBAN(payee.IBAN)),
+			payee.CreateAt, payee.UpdateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, addPayeeAliases(ctx, id, payee.Aliases)
+}
+
+// EditPayee updates a payee read at payee.Version and replaces its aliases.
+func (db *Database) EditPayee(ctx context.Context, payee entities.PayeeEntity) (int64, error) {
+	logger.Debugf("Editing payee: %v", payee)
+
+	sqler := squirrel.Update("payees").
+		Set("name", payee.Name).
+		Set("normalized_name", normalizeName(payee.Name)).
+		Set("default_category_id", payee.DefaultCategoryId).
+		Set("iban", squirrel.Expr("nullif(?, '')", normalizeIBAN(payee.IBAN))).
+		Set("updated_at", payee.UpdateAt).
+		Where("id = ?", payee.Id)
+
+	result, err := execVersioned(ctx, "payees", payee.Id, payee.Version, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := exec(ctx, squirrel.Delete("payee_aliases").Where("payee_id = ?", payee.Id)); err != nil {
+		return 0, err
+	}
+
+	if err := addPayeeAliases(ctx, payee.Id, payee.Aliases); err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeletePayee removes a payee, its transactions are kept without a payee.
+func (db *Database) DeletePayee(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting payee: %d", id)
+
+	where := squirrel.Eq{"payee_id": id}
+	sqler := squirrel.Update("transactions").
+		Set("payee_id", nil).
+		Where(where)
+
+	if _, err := execAudited(ctx, "transactions", where, sqler); err != nil {
+		return err
+	}
+
+	if _, err := exec(ctx, squirrel.Delete("payee_aliases").Where("payee_id = ?", id)); err != nil {
+		return err
+	}
+
+	result, err := exec(ctx, squirrel.Delete("payees").Where("id = ?", id))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (db *Database) GetPayeeById(ctx context.Context, id int64) (entities.PayeeEntity, error) {
+	logger.Debugf("Getting payee: %d", id)
+
+	sqler := squirrel.Select("id", "name", "default_category_id", "ifnull(iban, '')",
+		"version", "created_at", "updated_at").
+		From("payees").
+		Where("id = ?", id).
+		Limit(1)
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return entities.PayeeEntity{}, err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return entities.PayeeEntity{}, ErrorNotFound
+	}
+
+	var payee entities.PayeeEntity
+
+	if err := rows.Scan(&payee.Id, &payee.Name, &payee.DefaultCategoryId, &payee.IBAN,
+		&payee.Version, &payee.CreateAt, &payee.UpdateAt); err != nil {
+		logger.Errorf("Error %v", err)
+		return payee, err
+	}
+
+	_ = rows.Close()
+
+	aliases, err := query(ctx, squirrel.Select("name").
+		From("payee_aliases").
+		Where("payee_id = ?", id).
+		OrderBy("name"))
+
+	if err != nil {
+		return payee, err
+	}
+
+	defer func() { _ = aliases.Close() }()
+
+	for aliases.Next() {
+		var alias string
+
+		if err := aliases.Scan(&alias); err != nil {
+			logger.Errorf("Error %v", err)
+			return payee, err
+		}
+
+		payee.Aliases = append(payee.Aliases, alias)
+	}
+
+	return payee, nil
+}
+
+// GetPayees returns the payees whose name or one of whose aliases contains
+// search, names starting with it first and then the most used payees.
+func (db *Database) GetPayees(ctx context.Context, search string, offset uint64, limit uint64) ([]entities.PayeeRow, error) {
+	logger.Debugf("Getting payees matching %q", search)
+
+	normalized := normalizeName(search)
+
+	// a search of punctuation only has nothing left to match
+	if normalized == "" && strings.TrimSpace(search) != "" {
+		return nil, nil
+	}
+
+	prefix := escapeLike(normalized) + "%"
+	contains := "%" + escapeLike(normalized) + "%"
+	aliased := squirrel.Select("payee_id").From("payee_aliases").
+		Where(`normalized_name like ? escape '\'`, contains)
+
+	sqler := squirrel.Select("p.id", "p.name", "p.default_category_id", "ifnull(p.iban, '')").
+		Column("(select count(*) from transactions t where t.payee_id = p.id) as usage").
+		From("payees p").
+		Where(squirrel.Or{
+			squirrel.Expr(`p.normalized_name like ? escape '\'`, contains),
+			squirrel.Expr("p.id in (?)", aliased),
+		}).
+		OrderByClause(`p.normalized_name like ? escape '\' desc`, prefix).
+		OrderBy("usage desc", "p.name").
+		Offset(offset).
+		Limit(limit)
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var payees []entities.PayeeRow
+
+	for rows.Next() {
+		var row entities.PayeeRow
+
+		if err := rows.Scan(&row.Id, &row.Name, &row.DefaultCategoryId, &row.IBAN,
+			&row.Transactions); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		payees = append(payees, row)
+	}
+
+	return payees, nil
+}
+
+// MergePayees folds the source payees into the target. Their transactions
+// and aliases move to the target and their names become aliases of it.
+func (db *Database) MergePayees(ctx context.Context, targetId int64, sourceIds []int64) error {
+	logger.Debugf("Merging payees %v into %d", sourceIds, targetId)
+
+	target, err := db.GetPayeeById(ctx, targetId)
+
+	if err != nil {
+		return err
+	}
+
+	for _, sourceId := range sourceIds {
+		if sourceId == targetId {
+			continue
+		}
+
+		source, err := db.GetPayeeById(ctx, sourceId)
+
+		if err != nil {
+			return err
+		}
+
+		where := squirrel.Eq{"payee_id": sourceId}
+		sqler := squirrel.Update("transactions").
+			Set("payee_id", targetId).
+			Where(where)
+
+		if _, err := execAudited(ctx, "transactions", where, sqler); err != nil {
+			return err
+		}
+
+		sqler = squirrel.Update("payee_aliases").
+			Set("payee_id", targetId).
+			Where("payee_id = ?", sourceId)
+
+		if _, err := exec(ctx, sqler); err != nil {
+			return err
+		}
+
+		if target.DefaultCategoryId == nil && source.DefaultCategoryId != nil {
+			target.DefaultCategoryId = source.DefaultCategoryId
+
+			sqler = squirrel.Update("payees").
+				Set("default_category_id", source.DefaultCategoryId).
+				Where("id = ?", targetId)
+
+			if _, err := exec(ctx, sqler); err != nil {
+				return err
+			}
+		}
+
+		if err := db.DeletePayee(ctx, sourceId); err != nil {
+			return err
+		}
+
+		if normalizeName(source.Name) != normalizeName(target.Name) {
+			if err := addPayeeAliases(ctx, targetId, []string{source.Name}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// FindOrCreatePayee returns the payee matching a counterparty, first by IBAN,
+// then by normalized name or alias. A new payee is created when none matches.
+// Statement importers use it to link imported transactions to payees.
+func (db *Database) FindOrCreatePayee(ctx context.Context, name string, iban string) (int64, error) {
+	iban = normalizeIBAN(iban)
+	normalized := normalizeName(name)
+
+	if iban != "" {
+		if id, err := queryId(ctx, squirrel.Select("id").From("payees").Where("iban = ?", iban)); err != ErrorNotFound {
+			return id, err
+		}
+	}
+
+	if normalized == "" {
+		return 0, ErrorNotFound
+	}
+
+	if id, err := queryId(ctx, squirrel.Select("id").From("payees").Where("normalized_name = ?", normalized)); err != ErrorNotFound {
+		return id, err
+	}
+
+	if id, err := queryId(ctx, squirrel.Select("payee_id").From("payee_aliases").Where("normalized_name = ?", normalized)); err != ErrorNotFound {
+		return id, err
+	}
+
+	return db.CreatePayee(ctx, entities.PayeeEntity{
+		Name:     strings.TrimSpace(name),
+		IBAN:     iban,
+		CreateAt: time.Now(),
+		UpdateAt: time.Now(),
+	})
+}
+
+func addPayeeAliases(ctx context.Context, payeeId int64, aliases []string) error {
+	for _, alias := range aliases {
+		normalized := normalizeName(alias)
+
+		if normalized == "" {
+			continue
+		}
+
+		sqler := squirrel.Insert("payee_aliases").
+			Options("or ignore").
+			Columns("payee_id", "name", "normalized_name").
+			Values(payeeId, strings.TrimSpace(alias), normalized)
+
+		if _, err := exec(ctx, sqler); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryId returns the first id selected by sqler or ErrorNotFound.
+func queryId(ctx context.Context, sqler squirrel.SelectBuilder) (int64, error) {
+	rows, err := query(ctx, sqler.Limit(1))
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return 0, ErrorNotFound
+	}
+
+	var id int64
+	err = rows.Scan(&id)
+
+	return id, err
+}
+
+// queryIds returns every id selected by sqler.
+func queryIds(ctx context.Context, sqler squirrel.SelectBuilder) ([]int64, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var ids []int64
+
+	for rows.Next() {
+		var id int64
+
+		if err := rows.Scan(&id); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// normalizeName lower cases a name and reduces everything but letters and
+// digits to single spaces, so "ACME Corp." and "acme corp" match.
+func normalizeName(name string) string {
+	mapped := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, name)
+
+	return strings.Join(strings.Fields(mapped), " ")
+}
+
+// escapeLike escapes the wildcards of a like pattern, the pattern has to use
+// escape '\'.
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+}
+
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
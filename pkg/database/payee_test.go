@@ -0,0 +1,50 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetPayees(t *testing.T) {
+	db, ctx := testContext(t)
+
+	for _, name := range []string{"Corner Bakery", "Bakery Express", "Fuel 50%"} {
+		if _, err := db.FindOrCreatePayee(ctx, name, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		search string
+		want   []string
+	}{
+		{"", []string{"Bakery Express", "Corner Bakery", "Fuel 50%"}},
+		{"bak", []string{"Bakery Express", "Corner Bakery"}},
+		{"ery", []string{"Bakery Express", "Corner Bakery"}},
+		{"FUEL", []string{"Fuel 50%"}},
+		{"%", nil},
+		{"_", nil},
+		{"50%", []string{"Fuel 50%"}},
+		{"b_k", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.search, func(t *testing.T) {
+			payees, err := db.GetPayees(ctx, test.search, 0, 10)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+
+			for _, payee := range payees {
+				names = append(names, payee.Name)
+			}
+
+			if !reflect.DeepEqual(names, test.want) {
+				t.Errorf("got %v, want %v", names, test.want)
+			}
+		})
+	}
+}
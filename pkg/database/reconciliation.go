@@ -0,0 +1,297 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+func (db *Database) CreateReconciliation(ctx context.Context, reconciliation entities.ReconciliationEntity) (int64, error) {
+	logger.Debugf("Creating reconciliation: %v", reconciliation)
+
+	sqler := squirrel.Insert("reconciliations").
+		Columns("account_id", "statement_date", "statement_balance", "source",
+			"created_at", "updated_at").
+		Values(reconciliation.AccountId, reconciliation.StatementDate, reconciliation.StatementBalance,
+			reconciliation.Source, reconciliation.CreateAt, reconciliation.UpdateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// RecordStatementBalance feeds a closing balance of a bank statement into
+// reconciliation: the open reconciliation of the account takes the new
+// statement, or a new one is started. The statement import calls it with the
+// closing balance of the imported camt, MT940 or OFX file.
+func (db *Database) RecordStatementBalance(ctx context.Context, accountId int64, date string, balance int, source string) (int64, error) {
+	logger.Debugf("Recording %s statement balance %d on %s for account %d", source, balance, date, accountId)
+
+	open, err := db.GetOpenReconciliation(ctx, accountId)
+
+	if err == ErrorNotFound {
+		return db.CreateReconciliation(ctx, entities.ReconciliationEntity{
+			AccountId:        accountId,
+			StatementDate:    date,
+			StatementBalance: balance,
+			Source:           source,
+			CreateAt:         time.Now(),
+			UpdateAt:         time.Now(),
+		})
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	sqler := squirrel.Update("reconciliations").
+		Set("statement_date", date).
+		Set("statement_balance", balance).
+		Set("source", source).
+		Set("updated_at", time.Now()).
+		Where("id = ?", open.Id)
+
+	_, err = exec(ctx, sqler)
+
+	return open.Id, err
+}
+
+// DeleteReconciliation cancels an open reconciliation, finished ones are kept
+// as history.
+func (db *Database) DeleteReconciliation(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting reconciliation: %d", id)
+
+	result, err := exec(ctx, squirrel.Delete("reconciliations").
+		Where("id = ?", id).
+		Where("finished_at is null"))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// FinishReconciliation locks the cleared transactions of the account up to
+// the statement date as reconciled and closes the reconciliation.
+func (db *Database) FinishReconciliation(ctx context.Context, reconciliation entities.ReconciliationEntity) error {
+	logger.Debugf("Finishing reconciliation: %d", reconciliation.Id)
+
+	where := squirrel.And{
+		squirrel.Or{
+			squirrel.Eq{"from_account_id": reconciliation.AccountId},
+			squirrel.Eq{"to_account_id": reconciliation.AccountId},
+		},
+		squirrel.Eq{"status": entities.StatusCleared, "pending": false},
+		squirrel.LtOrEq{"date": reconciliation.StatementDate},
+	}
+
+	sqler := squirrel.Update("transactions").
+		Set("status", entities.StatusReconciled).
+		Where(where)
+
+	if _, err := execAudited(ctx, "transactions", where, sqler); err != nil {
+		return err
+	}
+
+	result, err := exec(ctx, squirrel.Update("reconciliations").
+		Set("finished_at", time.Now()).
+		Set("updated_at", time.Now()).
+		Where("id = ?", reconciliation.Id).
+		Where("finished_at is null"))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (db *Database) GetReconciliationById(ctx context.Context, id int64) (entities.ReconciliationEntity, error) {
+	reconciliations, err := queryReconciliations(ctx, selectReconciliations().Where("id = ?", id))
+
+	if err != nil {
+		return entities.ReconciliationEntity{}, err
+	}
+
+	if len(reconciliations) == 0 {
+		return entities.ReconciliationEntity{}, ErrorNotFound
+	}
+
+	return reconciliations[0], nil
+}
+
+func (db *Database) GetOpenReconciliation(ctx context.Context, accountId int64) (entities.ReconciliationEntity, error) {
+	reconciliations, err := queryReconciliations(ctx, selectReconciliations().
+		Where("account_id = ?", accountId).
+		Where("finished_at is null"))
+
+	if err != nil {
+		return entities.ReconciliationEntity{}, err
+	}
+
+	if len(reconciliations) == 0 {
+		return entities.ReconciliationEntity{}, ErrorNotFound
+	}
+
+	return reconciliations[0], nil
+}
+
+// GetReconciliations returns the reconciliations of an account, the latest
+// statement first.
+func (db *Database) GetReconciliations(ctx context.Context, accountId int64) ([]entities.ReconciliationEntity, error) {
+	logger.Debugf("Getting reconciliations of account %d", accountId)
+
+	return queryReconciliations(ctx, selectReconciliations().
+		Where("account_id = ?", accountId).
+		OrderBy("statement_date desc", "id desc"))
+}
+
+// GetClearedBalance returns the opening balance of an account plus its
+// cleared and reconciled transactions up to and including the day until.
+func (db *Database) GetClearedBalance(ctx context.Context, accountId int64, until string) (entities.CurrencyValue, error) {
+	filter := "not pending and status != ? and date <= ?"
+
+	sqler := squirrel.Select("a.currency").
+		Column(squirrel.Expr("a.opening_balance"+
+			" + ifnull((select sum(total_amount) from transactions where to_account_id = a.id and "+filter+"), 0)"+
+			" - ifnull((select sum(total_amount) from transactions where from_account_id = a.id and "+filter+"), 0)",
+			entities.StatusUncleared, until, entities.StatusUncleared, until)).
+		From("accounts a").
+		Where("a.id = ?", accountId)
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return entities.CurrencyValue{}, err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return entities.CurrencyValue{}, ErrorNotFound
+	}
+
+	var balance entities.CurrencyValue
+	err = rows.Scan(&balance.Currency, &balance.Value)
+
+	return balance, err
+}
+
+// SetTransactionStatus marks transactions as uncleared or cleared. Reconciled
+// transactions have to be unlocked first.
+func (db *Database) SetTransactionStatus(ctx context.Context, ids []int64, status entities.TransactionStatus) error {
+	logger.Debugf("Setting status of transactions %v to %v", ids, status)
+
+	locked, err := queryId(ctx, squirrel.Select("id").
+		From("transactions").
+		Where(squirrel.Eq{"id": ids, "status": entities.StatusReconciled}))
+
+	if err == nil {
+		logger.Debugf("Transaction %d is reconciled", locked)
+		return ErrorReconciled
+	} else if err != ErrorNotFound {
+		return err
+	}
+
+	sqler := squirrel.Update("transactions").
+		Set("status", status).
+		Set("updated_at", time.Now()).
+		Where(squirrel.Eq{"id": ids})
+
+	result, err := execAudited(ctx, "transactions", squirrel.Eq{"id": ids}, sqler)
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected != int64(len(ids)) {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// UnlockTransaction turns a reconciled transaction back into a cleared one
+// so it can be edited again.
+func (db *Database) UnlockTransaction(ctx context.Context, id int64) error {
+	logger.Debugf("Unlocking transaction: %d", id)
+
+	where := squirrel.Eq{"id": id, "status": entities.StatusReconciled}
+
+	result, err := execAudited(ctx, "transactions", where, squirrel.Update("transactions").
+		Set("status", entities.StatusCleared).
+		Set("updated_at", time.Now()).
+		Where(where))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// CheckTransactionUnlocked returns ErrorReconciled when a transaction is
+// reconciled and ErrorNotFound when it does not exist.
+func (db *Database) CheckTransactionUnlocked(ctx context.Context, id int64) error {
+	transaction, err := db.GetTransactionById(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	if transaction.Status == entities.StatusReconciled {
+		return ErrorReconciled
+	}
+
+	return nil
+}
+
+func selectReconciliations() squirrel.SelectBuilder {
+	return squirrel.Select("id", "account_id", "statement_date", "statement_balance", "source",
+		"finished_at", "created_at", "updated_at").
+		From("reconciliations")
+}
+
+func queryReconciliations(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.ReconciliationEntity, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var reconciliations []entities.ReconciliationEntity
+
+	for rows.Next() {
+		var row entities.ReconciliationEntity
+
+		if err := rows.Scan(&row.Id, &row.AccountId, &row.StatementDate, &row.StatementBalance, &row.Source,
+			&row.FinishedAt, &row.CreateAt, &row.UpdateAt); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		reconciliations = append(reconciliations, row)
+	}
+
+	return reconciliations, nil
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+func (db *Database) CreateRule(ctx context.Context, rule entities.RuleEntity) (int64, error) {
+	logger.Debugf("Creating rule: %v", rule)
+
+	conditions, actions, err := marshalRule(rule)
+
+	if err != nil {
+		return 0, err
+	}
+
+	sqler := squirrel.Insert("rules").
+		Columns("name", "priority", "enabled", "stop_processing", "conditions", "actions",
+			"created_at", "updated_at").
+		Values(rule.Name, rule.Priority, rule.Enabled, rule.StopProcessing, conditions, actions,
+			rule.CreateAt, rule.UpdateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// EditRule saves a rule read at rule.Version.
+func (db *Database) EditRule(ctx context.Context, rule entities.RuleEntity) (int64, error) {
+	logger.Debugf("Editing rule: %v", rule)
+
+	conditions, actions, err := marshalRule(rule)
+
+	if err != nil {
+		return 0, err
+	}
+
+	sqler := squirrel.Update("rules").
+		Set("name", rule.Name).
+		Set("priority", rule.Priority).
+		Set("enabled", rule.Enabled).
+		Set("stop_processing", rule.StopProcessing).
+		Set("conditions", conditions).
+		Set("actions", actions).
+		Set("updated_at", rule.UpdateAt).
+		Where("id = ?", rule.Id)
+
+	result, err := execVersioned(ctx, "rules", rule.Id, rule.Version, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (db *Database) DeleteRule(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting rule: %d", id)
+
+	result, err := exec(ctx, squirrel.Delete("rules").Where("id = ?", id))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (db *Database) GetRuleById(ctx context.Context, id int64) (entities.RuleEntity, error) {
+	logger.Debugf("Getting rule: %d", id)
+
+	rules, err := queryRules(ctx, selectRules().Where("id = ?", id))
+
+	if err != nil {
+		return entities.RuleEntity{}, err
+	}
+
+	if len(rules) == 0 {
+		return entities.RuleEntity{}, ErrorNotFound
+	}
+
+	return rules[0], nil
+}
+
+// GetRules returns the rules in the order they are evaluated, lowest priority
+// first.
+func (db *Database) GetRules(ctx context.Context, enabledOnly bool) ([]entities.RuleEntity, error) {
+	logger.Debugf("Getting rules")
+
+	sqler := selectRules().OrderBy("priority", "id")
+
+	if enabledOnly {
+		sqler = sqler.Where("enabled = ?", true)
+	}
+
+	return queryRules(ctx, sqler)
+}
+
+func selectRules() squirrel.SelectBuilder {
+	return squirrel.Select("id", "name", "priority", "enabled", "stop_processing",
+		"conditions", "actions", "version", "created_at", "updated_at").
+		From("rules")
+}
+
+func queryRules(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.RuleEntity, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var rules []entities.RuleEntity
+
+	for rows.Next() {
+		var row entities.RuleEntity
+		var conditions, actions string
+
+		if err := rows.Scan(&row.Id, &row.Name, &row.Priority, &row.Enabled, &row.StopProcessing,
+			&conditions, &actions, &row.Version, &row.CreateAt, &row.UpdateAt); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(conditions), &row.Conditions); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(actions), &row.Actions); err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, row)
+	}
+
+	return rules, nil
+}
+
+// marshalRule encodes the conditions and actions of a rule, they are stored
+// as JSON.
+func marshalRule(rule entities.RuleEntity) (string, string, error) {
+	conditions, err := json.Marshal(rule.Conditions)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	actions, err := json.Marshal(rule.Actions)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(conditions), string(actions), nil
+}
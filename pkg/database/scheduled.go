@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+func (db *Database) CreateScheduledTransaction(ctx context.Context, scheduled entities.ScheduledTransactionEntity) (int64, error) {
+	logger.Debugf("Creating scheduled transaction: %v", scheduled)
+
+	sqler := squirrel.Insert("scheduled_transactions").
+		Columns("name", "from_account_id", "to_account_id", "amount", "category_id",
+			"frequency", "interval", "weekday", "day_of_month",
+			"start_date", "end_date", "max_occurrences", "pending",
+			"created_at", "updated_at").
+		Values(scheduled.Name, scheduled.FromAccountId, scheduled.ToAccountId, scheduled.Amount, scheduled.CategoryId,
+			scheduled.Frequency, scheduled.Interval, scheduled.Weekday, scheduled.DayOfMonth,
+			scheduled.StartDate, squirrel.Expr("nullif(?, '')", scheduled.EndDate), scheduled.MaxOccurrences, scheduled.Pending,
+			scheduled.CreateAt, scheduled.UpdateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// EditScheduledTransaction updates a scheduled transaction read at
+// scheduled.Version, including the number of occurrences created. Rule
+// changes have to be carried over with scheduler.Reschedule first.
+func (db *Database) EditScheduledTransaction(ctx context.Context, scheduled entities.ScheduledTransactionEntity) (int64, error) {
+	logger.Debugf("Editing scheduled transaction: %v", scheduled)
+
+	sqler := squirrel.Update("scheduled_transactions").
+		Set("name", scheduled.Name).
+		Set("from_account_id", scheduled.FromAccountId).
+		Set("to_account_id", scheduled.ToAccountId).
+		Set("amount", scheduled.Amount).
+		Set("category_id", scheduled.CategoryId).
+		Set("frequency", scheduled.Frequency).
+		Set("interval", scheduled.Interval).
+		Set("weekday", scheduled.Weekday).
+		Set("day_of_month", scheduled.DayOfMonth).
+		Set("start_date", scheduled.StartDate).
+		Set("end_date", squirrel.Expr("nullif(?, '')", scheduled.EndDate)).
+		Set("max_occurrences", scheduled.MaxOccurrences).
+		Set("occurrences", scheduled.Occurrences).
+		Set("pending", scheduled.Pending).
+		Set("updated_at", scheduled.UpdateAt).
+		Where("id = ?", scheduled.Id)
+
+	result, err := execVersioned(ctx, "scheduled_transactions", scheduled.Id, scheduled.Version, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteScheduledTransaction removes a scheduled transaction. Transactions it
+// already created are kept.
+func (db *Database) DeleteScheduledTransaction(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting scheduled transaction: %d", id)
+
+	where := squirrel.Eq{"scheduled_transaction_id": id}
+	sqler := squirrel.Update("transactions").
+		Set("scheduled_transaction_id", nil).
+		Where(where)
+
+	if _, err := execAudited(ctx, "transactions", where, sqler); err != nil {
+		return err
+	}
+
+	result, err := exec(ctx, squirrel.Delete("scheduled_transactions").Where("id = ?", id))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// SetScheduledOccurrences records how many occurrences of a scheduled
+// transaction have been turned into transactions.
+func (db *Database) SetScheduledOccurrences(ctx context.Context, id int64, occurrences int) error {
+	sqler := squirrel.Update("scheduled_transactions").
+		Set("occurrences", occurrences).
+		Where("id = ?", id)
+
+	_, err := exec(ctx, sqler)
+
+	return err
+}
+
+func (db *Database) GetScheduledTransactions(ctx context.Context) ([]entities.ScheduledTransactionEntity, error) {
+	logger.Debugf("Getting scheduled transactions")
+
+	return queryScheduledTransactions(ctx, selectScheduledTransactions().OrderBy("s.name", "s.id"))
+}
+
+func (db *Database) GetScheduledTransactionById(ctx context.Context, id int64) (entities.ScheduledTransactionEntity, error) {
+	scheduled, err := queryScheduledTransactions(ctx, selectScheduledTransactions().Where("s.id = ?", id))
+
+	if err != nil {
+		return entities.ScheduledTransactionEntity{}, err
+	}
+
+	if len(scheduled) == 0 {
+		return entities.ScheduledTransactionEntity{}, ErrorNotFound
+	}
+
+	return scheduled[0], nil
+}
+
+func selectScheduledTransactions() squirrel.SelectBuilder {
+	return squirrel.Select("s.id", "s.name", "s.from_account_id", "s.to_account_id",
+		"s.amount", "s.category_id", "s.frequency", "s.interval", "s.weekday",
+		"s.day_of_month", "s.start_date", "ifnull(s.end_date, '')", "s.max_occurrences",
+		"s.occurrences", "s.pending", "s.version", "s.created_at", "s.updated_at",
+		"coalesce(f.currency, t.currency)").
+		From("scheduled_transactions s").
+		LeftJoin("accounts f on f.id = s.from_account_id").
+		LeftJoin("accounts t on t.id = s.to_account_id")
+}
+
+func queryScheduledTransactions(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.ScheduledTransactionEntity, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var scheduled []entities.ScheduledTransactionEntity
+
+	for rows.Next() {
+		var row entities.ScheduledTransactionEntity
+
+		if err := rows.Scan(&row.Id, &row.Name, &row.FromAccountId, &row.ToAccountId,
+			&row.Amount, &row.CategoryId, &row.Frequency, &row.Interval, &row.Weekday,
+			&row.DayOfMonth, &row.StartDate, &row.EndDate, &row.MaxOccurrences,
+			&row.Occurrences, &row.Pending, &row.Version, &row.CreateAt, &row.UpdateAt,
+			&row.Currency); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		scheduled = append(scheduled, row)
+	}
+
+	return scheduled, nil
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+// Search returns the transactions, items, payees and tags matching terms, at
+// most limit of each and the best first. Transactions also match by their
+// items, payee and tags. Words match as prefixes, text in double quotes as a
+// phrase.
+func (db *Database) Search(ctx context.Context, terms string, limit uint64) (entities.SearchResults, error) {
+	logger.Debugf("Searching for %q", terms)
+
+	results := entities.SearchResults{
+		Transactions: []entities.SearchHit{},
+		Items:        []entities.SearchHit{},
+		Payees:       []entities.SearchHit{},
+		Tags:         []entities.SearchHit{},
+	}
+
+	match := searchQuery(terms)
+
+	if match == "" {
+		return results, nil
+	}
+
+	items := squirrel.Select("i.id", "i.name", "''", "i.transaction_id", "t.date", "bm25(search_items) as rank").
+		From("search_items").
+		Join("items i on i.id = search_items.rowid").
+		Join("transactions t on t.id = i.transaction_id")
+
+	payees := squirrel.Select("p.id", "p.name", "''", "null", "''", "bm25(search_payees) as rank").
+		From("search_payees").
+		Join("payees p on p.id = search_payees.rowid")
+
+	tags := squirrel.Select("g.id", "g.name", "''", "null", "''", "bm25(search_tags) as rank").
+		From("search_tags").
+		Join("tags g on g.id = search_tags.rowid")
+
+	for table, search := range map[string]struct {
+		sqler squirrel.SelectBuilder
+		hits  *[]entities.SearchHit
+	}{
+		"search_items":  {items, &results.Items},
+		"search_payees": {payees, &results.Payees},
+		"search_tags":   {tags, &results.Tags},
+	} {
+		hits, err := querySearchHits(ctx, search.sqler.
+			Where(table+" match ?", match).
+			OrderBy("rank").
+			Limit(limit))
+
+		if err != nil {
+			return results, err
+		}
+
+		*search.hits = hits
+	}
+
+	hits, err := querySearchHits(ctx, transactionMatches(match).OrderBy("rank").Limit(limit))
+
+	if err != nil {
+		return results, err
+	}
+
+	results.Transactions = hits
+
+	return results, nil
+}
+
+// transactionMatches selects the transactions whose description, notes,
+// items, payee or tags match, each with its best match as the snippet.
+func transactionMatches(match string) squirrel.SelectBuilder {
+	// the description counts twice as much as the notes
+	texts := squirrel.Select("rowid as transaction_id", "snippet(search_transactions, -1, '', '', '…', 16) as snippet",
+		"bm25(search_transactions, 2.0, 1.0) as rank").
+		From("search_transactions").
+		Where("search_transactions match ?", match)
+
+	items := squirrel.Select("i.transaction_id", "i.name", "bm25(search_items)").
+		From("search_items").
+		Join("items i on i.id = search_items.rowid").
+		Where("search_items match ?", match)
+
+	payees := squirrel.Select("t.id", "p.name", "bm25(search_payees)").
+		From("search_payees").
+		Join("payees p on p.id = search_payees.rowid").
+		Join("transactions t on t.payee_id = p.id").
+		Where("search_payees match ?", match)
+
+	tags := squirrel.Select("j.transaction_id", "g.name", "bm25(search_tags)").
+		From("search_tags").
+		Join("tags g on g.id = search_tags.rowid").
+		Join("transaction_tags j on j.tag_id = g.id").
+		Where("search_tags match ?", match)
+
+	itemTags := squirrel.Select("i.transaction_id", "g.name", "bm25(search_tags)").
+		From("search_tags").
+		Join("tags g on g.id = search_tags.rowid").
+		Join("item_tags j on j.tag_id = g.id").
+		Join("items i on i.id = j.item_id").
+		Where("search_tags match ?", match)
+
+	matches := texts.SuffixExpr(squirrel.ConcatExpr("union all ", items, " union all ", payees,
+		" union all ", tags, " union all ", itemTags))
+
+	// with min() sqlite takes the snippet from the best match
+	return squirrel.Select("t.id", "t.description", "m.snippet", "null", "t.date", "min(m.rank) as rank").
+		FromSelect(matches, "m").
+		Join("transactions t on t.id = m.transaction_id").
+		GroupBy("t.id")
+}
+
+// searchQuery turns what a user typed into an FTS5 query. Every word must
+// match, as the start of a word or, inside double quotes, as a phrase. The
+// FTS5 operators are not let through, they are matched like any other word.
+func searchQuery(terms string) string {
+	var parts []string
+
+	for i, part := range strings.Split(terms, `"`) {
+		if i%2 == 1 {
+			if phrase := strings.Join(strings.Fields(part), " "); hasWord(phrase) {
+				parts = append(parts, `"`+phrase+`"`)
+			}
+
+			continue
+		}
+
+		for _, word := range strings.Fields(part) {
+			if hasWord(word) {
+				parts = append(parts, `"`+strings.TrimSuffix(word, "*")+`"*`)
+			}
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// hasWord tells whether text has something to search for, FTS5 ignores
+// punctuation.
+func hasWord(text string) bool {
+	return strings.IndexFunc(text, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) >= 0
+}
+
+func querySearchHits(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.SearchHit, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	hits := []entities.SearchHit{}
+
+	for rows.Next() {
+		var row entities.SearchHit
+
+		if err := rows.Scan(&row.Id, &row.Title, &row.Snippet, &row.TransactionId, &row.Date, &row.Rank); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		// bm25 scores the best match lowest
+		row.Rank = -row.Rank
+		hits = append(hits, row)
+	}
+
+	return hits, nil
+}
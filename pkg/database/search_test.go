@@ -0,0 +1,112 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Masterminds/squirrel"
+)
+
+func TestSearchTransactions(t *testing.T) {
+	db, ctx := testContext(t)
+	accountId := testAccount(t, db, ctx, "Checking")
+
+	transaction := func(description string, item string) int64 {
+		id := testTransaction(t, db, ctx, accountId, 1000, "2026-01-10", nil)
+
+		if _, err := exec(ctx, squirrel.Update("transactions").Set("description", description).Where("id = ?", id)); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := exec(ctx, squirrel.Update("items").Set("name", item).Where("transaction_id = ?", id)); err != nil {
+			t.Fatal(err)
+		}
+
+		return id
+	}
+
+	dinner := transaction("Dinner at Luigi", "Pizza")
+	coffee := transaction("Breakfast", "Espresso")
+	bread := transaction("Shopping", "Rye")
+	trip := transaction("Train", "Ticket")
+	milk := transaction("Shopping", "Milk")
+
+	payeeId, err := db.FindOrCreatePayee(ctx, "Corner Bakery", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := exec(ctx, squirrel.Update("transactions").Set("payee_id", payeeId).Where("id = ?", bread)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AddTransactionTags(ctx, trip, []string{"vacation"}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := db.GetItems(ctx, milk)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AddItemTags(ctx, items[0].Id, []string{"groceries"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query   string
+		ids     []int64
+		snippet string
+	}{
+		{"luig", []int64{dinner}, "Dinner at Luigi"},
+		{"espresso", []int64{coffee}, "Espresso"},
+		{"bakery", []int64{bread}, "Corner Bakery"},
+		{"vacation", []int64{trip}, "vacation"},
+		{"groceries", []int64{milk}, "groceries"},
+		{`"at luigi"`, []int64{dinner}, "Dinner at Luigi"},
+		{"nothing", []int64{}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			results, err := db.Search(ctx, test.query, 10)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := []int64{}
+
+			for _, hit := range results.Transactions {
+				ids = append(ids, hit.Id)
+			}
+
+			if !reflect.DeepEqual(ids, test.ids) {
+				t.Fatalf("got transactions %v, want %v", ids, test.ids)
+			}
+
+			if len(ids) > 0 && results.Transactions[0].Snippet != test.snippet {
+				t.Errorf("got snippet %q, want %q", results.Transactions[0].Snippet, test.snippet)
+			}
+		})
+	}
+}
+
+func TestSearchTransactionOnce(t *testing.T) {
+	db, ctx := testContext(t)
+	accountId := testAccount(t, db, ctx, "Checking")
+	id := testTransaction(t, db, ctx, accountId, 1000, "2026-01-10", nil)
+
+	if err := db.AddTransactionTags(ctx, id, []string{"test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the description, the item and the tag all match
+	results, err := db.Search(ctx, "test", 10)
+
+	if err != nil || len(results.Transactions) != 1 || results.Transactions[0].Id != id {
+		t.Errorf("got %+v, %v, want transaction %d once", results.Transactions, err, id)
+	}
+}
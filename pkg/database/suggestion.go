@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+// GetCategorizedItems returns the items that changed after since, together
+// with the description and payee of their transaction.
+func (db *Database) GetCategorizedItems(ctx context.Context, since time.Time) ([]entities.CategorizedItem, error) {
+	logger.Debugf("Getting items changed after %v", since)
+
+	sqler := squirrel.Select("i.id", "i.category_id", "ifnull(p.name, '')", "t.description",
+		"i.name", "i.price").
+		From("items i").
+		Join("transactions t on t.id = i.transaction_id").
+		LeftJoin("payees p on p.id = t.payee_id").
+		Where(squirrel.Or{
+			squirrel.Gt{"i.updated_at": since},
+			squirrel.Gt{"t.updated_at": since},
+			squirrel.Gt{"p.updated_at": since},
+		})
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var items []entities.CategorizedItem
+
+	for rows.Next() {
+		var row entities.CategorizedItem
+
+		if err := rows.Scan(&row.ItemId, &row.CategoryId, &row.PayeeName, &row.Description,
+			&row.ItemName, &row.Amount); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		items = append(items, row)
+	}
+
+	return items, nil
+}
+
+// CountCategorizedItems returns the number of items with a category.
+func (db *Database) CountCategorizedItems(ctx context.Context) (int, error) {
+	rows, err := query(ctx, squirrel.Select("count(*)").From("items").Where("category_id is not null"))
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var count int
+
+	if rows.Next() {
+		err = rows.Scan(&count)
+	}
+
+	return count, err
+}
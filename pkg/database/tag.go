@@ -0,0 +1,381 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+// tagSeparator joins tag names in a single column, it can not be typed in a
+// tag name.
+const tagSeparator = "\x1f"
+
+func (db *Database) CreateTag(ctx context.Context, tag entities.TagEntity) (int64, error) {
+	logger.Debugf("Creating tag: %v", tag)
+
+	sqler := squirrel.Insert("tags").
+		Columns("name", "created_at").
+		Values(tag.Name, tag.CreateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// EditTag renames a tag read at tag.Version.
+func (db *Database) EditTag(ctx context.Context, tag entities.TagEntity) (int64, error) {
+	logger.Debugf("Editing tag: %v", tag)
+
+	sqler := squirrel.Update("tags").
+		Set("name", tag.Name).
+		Where("id = ?", tag.Id)
+
+	result, err := execVersioned(ctx, "tags", tag.Id, tag.Version, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteTag removes a tag from every transaction and item and deletes it.
+func (db *Database) DeleteTag(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting tag: %d", id)
+
+	transactions := squirrel.Expr("id in (?)", squirrel.Select("transaction_id").
+		From("transaction_tags").
+		Where("tag_id = ?", id))
+	items := squirrel.Expr("id in (?)", squirrel.Select("item_id").
+		From("item_tags").
+		Where("tag_id = ?", id))
+
+	return auditChange(ctx, "transactions", transactions, func() error {
+		return auditChange(ctx, "items", items, func() error {
+			for _, table := range []string{"transaction_tags", "item_tags"} {
+				if _, err := exec(ctx, squirrel.Delete(table).Where("tag_id = ?", id)); err != nil {
+					return err
+				}
+			}
+
+			result, err := exec(ctx, squirrel.Delete("tags").Where("id = ?", id))
+
+			if err != nil {
+				return err
+			}
+
+			if affected, _ := result.RowsAffected(); affected == 0 {
+				return ErrorNotFound
+			}
+
+			return nil
+		})
+	})
+}
+
+func (db *Database) GetTagById(ctx context.Context, id int64) (entities.TagEntity, error) {
+	sqler := squirrel.Select("id", "name", "version", "created_at").
+		From("tags").
+		Where("id = ?", id)
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return entities.TagEntity{}, err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return entities.TagEntity{}, ErrorNotFound
+	}
+
+	var tag entities.TagEntity
+	err = rows.Scan(&tag.Id, &tag.Name, &tag.Version, &tag.CreateAt)
+
+	return tag, err
+}
+
+// GetTags returns every tag with the number of transactions and items
+// tagged with it.
+func (db *Database) GetTags(ctx context.Context) ([]entities.TagRow, error) {
+	logger.Debugf("Getting tags")
+
+	sqler := squirrel.Select("g.id", "g.name", "g.version").
+		Column("(select count(*) from transaction_tags tt where tt.tag_id = g.id)").
+		Column("(select count(*) from item_tags it where it.tag_id = g.id)").
+		From("tags g").
+		OrderBy("g.name")
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var tags []entities.TagRow
+
+	for rows.Next() {
+		var row entities.TagRow
+
+		if err := rows.Scan(&row.Id, &row.Name, &row.Version, &row.Transactions, &row.Items); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		tags = append(tags, row)
+	}
+
+	return tags, nil
+}
+
+// AddTransactionTags tags a transaction, tags that do not exist yet are
+// created. Tag names are case insensitive.
+func (db *Database) AddTransactionTags(ctx context.Context, transactionId int64, names []string) error {
+	logger.Debugf("Tagging transaction %d with %v", transactionId, names)
+
+	return auditChange(ctx, "transactions", squirrel.Eq{"id": transactionId}, func() error {
+		return addTags(ctx, "transaction_tags", "transaction_id", transactionId, names)
+	})
+}
+
+// SetTransactionTags replaces the tags of a transaction.
+func (db *Database) SetTransactionTags(ctx context.Context, transactionId int64, names []string) error {
+	logger.Debugf("Setting tags of transaction %d to %v", transactionId, names)
+
+	return auditChange(ctx, "transactions", squirrel.Eq{"id": transactionId}, func() error {
+		return setTags(ctx, "transaction_tags", "transaction_id", transactionId, names)
+	})
+}
+
+func (db *Database) AddItemTags(ctx context.Context, itemId int64, names []string) error {
+	logger.Debugf("Tagging item %d with %v", itemId, names)
+
+	return auditChange(ctx, "items", squirrel.Eq{"id": itemId}, func() error {
+		return addTags(ctx, "item_tags", "item_id", itemId, names)
+	})
+}
+
+func (db *Database) GetTransactionTags(ctx context.Context, transactionId int64) ([]string, error) {
+	return queryTags(ctx, "transaction_tags", "transaction_id", transactionId)
+}
+
+func (db *Database) GetItemTags(ctx context.Context, itemId int64) ([]string, error) {
+	return queryTags(ctx, "item_tags", "item_id", itemId)
+}
+
+// GetTagTotals sums the confirmed income and expenses per tag and currency
+// of the transactions matching filter. An item only counts on its own when its transaction
+// does not carry the same tag. Transfers between accounts are left out.
+func (db *Database) GetTagTotals(ctx context.Context, filter entities.TransactionFilter) ([]entities.TagTotal, error) {
+	logger.Debugf("Getting tag totals: %v", filter)
+
+	transactions := squirrel.Select("tt.tag_id", "t.id", "t.from_account_id is null", "t.total_amount",
+		"coalesce(f.currency, a.currency)").
+		From("transaction_tags tt").
+		Join("transactions t on t.id = tt.transaction_id")
+
+	items := squirrel.Select("it.tag_id", "t.id", "t.from_account_id is null", "i.price",
+		"coalesce(f.currency, a.currency)").
+		From("item_tags it").
+		Join("items i on i.id = it.item_id").
+		Join("transactions t on t.id = i.transaction_id").
+		Where("not exists (select 1 from transaction_tags tt where tt.tag_id = it.tag_id and tt.transaction_id = t.id)")
+
+	type key struct {
+		tagId    int
+		currency string
+	}
+
+	totals := make(map[key]*entities.TagTotal)
+	counted := make(map[key]map[int]bool)
+	var order []key
+
+	for _, sqler := range []squirrel.SelectBuilder{transactions, items} {
+		sqler = sqler.
+			LeftJoin("accounts f on f.id = t.from_account_id").
+			LeftJoin("accounts a on a.id = t.to_account_id").
+			Where("not t.pending").
+			Where("(t.from_account_id is null or t.to_account_id is null)").
+			Where(transactionFilter(filter))
+
+		rows, err := query(ctx, sqler)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var tagId, transactionId, amount int
+			var income bool
+			var currency string
+
+			if err := rows.Scan(&tagId, &transactionId, &income, &amount, &currency); err != nil {
+				logger.Errorf("Error %v", err)
+				_ = rows.Close()
+				return nil, err
+			}
+
+			k := key{tagId, currency}
+			total, ok := totals[k]
+
+			if !ok {
+				total = &entities.TagTotal{
+					TagId:   tagId,
+					Income:  entities.CurrencyValue{Currency: currency},
+					Expense: entities.CurrencyValue{Currency: currency},
+				}
+				totals[k] = total
+				counted[k] = make(map[int]bool)
+				order = append(order, k)
+			}
+
+			if income {
+				total.Income.Value += amount
+			} else {
+				total.Expense.Value += amount
+			}
+
+			if !counted[k][transactionId] {
+				counted[k][transactionId] = true
+				total.Transactions++
+			}
+		}
+
+		_ = rows.Close()
+	}
+
+	tags, err := db.GetTags(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int]string, len(tags))
+
+	for _, tag := range tags {
+		names[tag.Id] = tag.Name
+	}
+
+	result := make([]entities.TagTotal, 0, len(order))
+
+	for _, k := range order {
+		total := totals[k]
+		total.Name = names[k.tagId]
+		result = append(result, *total)
+	}
+
+	return result, nil
+}
+
+// tagsColumn selects the tag names of the transactions aliased as t, joined
+// with tagSeparator.
+func tagsColumn() string {
+	return "ifnull((select group_concat(g.name, char(31)) from transaction_tags tt " +
+		"join tags g on g.id = tt.tag_id where tt.transaction_id = t.id), '')"
+}
+
+func splitTags(tags string) []string {
+	if tags == "" {
+		return []string{}
+	}
+
+	return strings.Split(tags, tagSeparator)
+}
+
+// taggedWith matches the transactions aliased as t that are tagged with name
+// or have an item tagged with it.
+func taggedWith(name string) squirrel.Sqlizer {
+	tagId := squirrel.Select("id").From("tags").Where("name = ?", name)
+
+	return squirrel.Or{
+		squirrel.Expr("t.id in (?)", squirrel.Select("transaction_id").
+			From("transaction_tags").
+			Where(squirrel.Expr("tag_id in (?)", tagId))),
+		squirrel.Expr("t.id in (?)", squirrel.Select("i.transaction_id").
+			From("items i").
+			Join("item_tags it on it.item_id = i.id").
+			Where(squirrel.Expr("it.tag_id in (?)", tagId))),
+	}
+}
+
+// addTags links the row id of a join table to the tags called names,
+// creating missing tags.
+func addTags(ctx context.Context, table string, column string, id int64, names []string) error {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+
+		if name == "" {
+			continue
+		}
+
+		sqler := squirrel.Insert("tags").
+			Options("or ignore").
+			Columns("name", "created_at").
+			Values(name, time.Now())
+
+		if _, err := exec(ctx, sqler); err != nil {
+			return err
+		}
+
+		tagId := squirrel.Select("id").From("tags").Where("name = ?", name)
+
+		sqler = squirrel.Insert(table).
+			Options("or ignore").
+			Columns(column, "tag_id").
+			Select(squirrel.Select().Column("?", id).Column(squirrel.Alias(tagId, "tag_id")))
+
+		if _, err := exec(ctx, sqler); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setTags replaces the tags linked to the row id of a join table.
+func setTags(ctx context.Context, table string, column string, id int64, names []string) error {
+	if _, err := exec(ctx, squirrel.Delete(table).Where(column+" = ?", id)); err != nil {
+		return err
+	}
+
+	return addTags(ctx, table, column, id, names)
+}
+
+func queryTags(ctx context.Context, table string, column string, id int64) ([]string, error) {
+	sqler := squirrel.Select("g.name").
+		From(table+" j").
+		Join("tags g on g.id = j.tag_id").
+		Where("j."+column+" = ?", id).
+		OrderBy("g.name")
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	tags := []string{}
+
+	for rows.Next() {
+		var tag string
+
+		if err := rows.Scan(&tag); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
@@ -24,6 +24,7 @@ const (
 	txnKey key = iota + 1
 	dbKey
 	exclusiveKey
+	auditKey
 )
 
 
@@ -109,6 +110,24 @@ func (db *Database) Rollback(ctx context.Context) error {
 	return nil
 }
 
+// WithTxn runs fn inside a new transaction. The transaction is committed when
+// fn succeeds and rolled back otherwise.
+func (db *Database) WithTxn(ctx context.Context, exclusive bool, fn func(ctx context.Context) error) error {
+	txCtx, err := db.Begin(ctx, exclusive)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(txCtx); err != nil {
+		if rollbackErr := db.Rollback(txCtx); rollbackErr != nil {
+			logger.Errorf("failed to rollback transaction: %v", rollbackErr)
+		}
+		return err
+	}
+
+	return db.Commit(txCtx)
+}
+
 func (db *Database) txnComplete(ctx context.Context) {
 	if exclusive := ctx.Value(exclusiveKey).(bool); exclusive {
 		db.unlock()
@@ -0,0 +1,552 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+	"github.com/lembata/para/pkg/filter"
+)
+
+// TransactionFields are the fields a filter expression on transactions can
+// use. The ones matching related records, like category, match when one of
+// them does.
+var TransactionFields = filter.Fields{
+	"id":          {Column: "t.id", Kind: filter.Number},
+	"amount":      {Column: "t.total_amount", Kind: filter.Money},
+	"date":        {Column: "t.date", Kind: filter.Date},
+	"description": {Column: "t.description", Kind: filter.Text},
+	"notes":       {Column: "t.notes", Kind: filter.Text},
+	"status": {Column: "t.status", Kind: filter.Enum, Value: func(name string) (interface{}, error) {
+		status, err := entities.ParseTransactionStatus(name)
+		return int(status), err
+	}},
+	"pending": {Column: "t.pending", Kind: filter.Enum, Value: func(name string) (interface{}, error) {
+		return strconv.ParseBool(name)
+	}},
+	"type": {Column: "case when t.from_account_id is null then 'income' " +
+		"when t.to_account_id is null then 'expense' else 'transfer' end",
+		Kind: filter.Enum, Value: func(name string) (interface{}, error) {
+			if name != "income" && name != "expense" && name != "transfer" {
+				return nil, fmt.Errorf("invalid transaction type %q", name)
+			}
+
+			return name, nil
+		}},
+	"account": {Column: "fa.name", Kind: filter.Text,
+		Within: []string{"accounts fa where fa.id in (t.from_account_id, t.to_account_id)"}},
+	"currency": {Column: "fa.currency", Kind: filter.Text,
+		Within: []string{"accounts fa where fa.id in (t.from_account_id, t.to_account_id)"}},
+	"payee": {Column: "fp.name", Kind: filter.Text,
+		Within: []string{"payees fp where fp.id = t.payee_id"}},
+	"item": {Column: "fi.name", Kind: filter.Text,
+		Within: []string{"items fi where fi.transaction_id = t.id"}},
+	"category": {Column: "fc.name", Kind: filter.Text,
+		Within: []string{"items fi join categories fc on fc.id = fi.category_id where fi.transaction_id = t.id"}},
+	"tag": {Column: "fg.name", Kind: filter.Text, Within: []string{
+		"transaction_tags ft join tags fg on fg.id = ft.tag_id where ft.transaction_id = t.id",
+		"items fi join item_tags ft on ft.item_id = fi.id join tags fg on fg.id = ft.tag_id " +
+			"where fi.transaction_id = t.id",
+	}},
+}
+
+func (db *Database) CreateTransaction(ctx context.Context, transaction entities.TransactionEntity) (int64, error) {
+	logger.Debugf("Creating transaction: %v", transaction)
+
+	sqler := squirrel.Insert("transactions").
+		Columns("from_account_id", "to_account_id", "total_amount",
+			"date", "payee_id", "description", "notes", "pending", "status", "scheduled_transaction_id",
+			"created_at", "updated_at").
+		Values(transaction.FromAccountId, transaction.ToAccountId, transaction.TotalAmount,
+			transaction.Date, transaction.PayeeId, transaction.Description, transaction.Notes,
+			transaction.Pending, transaction.Status, transaction.ScheduledTransactionId,
+			transaction.CreateAt, transaction.UpdateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, auditCreated(ctx, "transactions", id)
+}
+
+// EditTransaction saves a transaction read at transaction.Version.
+func (db *Database) EditTransaction(ctx context.Context, transaction entities.TransactionEntity) (int64, error) {
+	logger.Debugf("Editing transaction: %v", transaction)
+
+	sqler := squirrel.Update("transactions").
+		Set("from_account_id", transaction.FromAccountId).
+		Set("to_account_id", transaction.ToAccountId).
+		Set("total_amount", transaction.TotalAmount).
+		Set("date", transaction.Date).
+		Set("payee_id", transaction.PayeeId).
+		Set("description", transaction.Description).
+		Set("notes", transaction.Notes).
+		Set("updated_at", transaction.UpdateAt).
+		Where("id = ?", transaction.Id)
+
+	result, err := execVersioned(ctx, "transactions", transaction.Id, transaction.Version, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// MoveTransaction books the side of a transaction that is on the account
+// fromAccountId on toAccountId instead.
+func (db *Database) MoveTransaction(ctx context.Context, id int64, fromAccountId int64, toAccountId int64) error {
+	logger.Debugf("Moving transaction %d from account %d to %d", id, fromAccountId, toAccountId)
+
+	move := func(column string) squirrel.Sqlizer {
+		return squirrel.Expr("case when "+column+" = ? then ? else "+column+" end", fromAccountId, toAccountId)
+	}
+
+	sqler := squirrel.Update("transactions").
+		Set("from_account_id", move("from_account_id")).
+		Set("to_account_id", move("to_account_id")).
+		Set("updated_at", time.Now()).
+		Set("version", squirrel.Expr("version + 1")).
+		Where("id = ?", id)
+
+	_, err := execAudited(ctx, "transactions", squirrel.Eq{"id": id}, sqler)
+
+	return err
+}
+
+// SetTransactionCategory puts every item of a transaction in a category.
+func (db *Database) SetTransactionCategory(ctx context.Context, id int64, categoryId *int64) error {
+	logger.Debugf("Setting category of the items of transaction %d to %v", id, categoryId)
+
+	where := squirrel.Eq{"transaction_id": id}
+	sqler := squirrel.Update("items").
+		Set("category_id", categoryId).
+		Set("updated_at", time.Now()).
+		Where(where)
+
+	if _, err := execAudited(ctx, "items", where, sqler); err != nil {
+		return err
+	}
+
+	return touchTransaction(ctx, id)
+}
+
+// RetagTransaction adds and removes tags of a transaction.
+func (db *Database) RetagTransaction(ctx context.Context, id int64, add []string, remove []string) error {
+	logger.Debugf("Retagging transaction %d, adding %v and removing %v", id, add, remove)
+
+	err := auditChange(ctx, "transactions", squirrel.Eq{"id": id}, func() error {
+		if err := addTags(ctx, "transaction_tags", "transaction_id", id, add); err != nil {
+			return err
+		}
+
+		for _, name := range remove {
+			tagId := squirrel.Select("id").From("tags").Where("name = ?", strings.TrimSpace(name))
+			sqler := squirrel.Delete("transaction_tags").
+				Where("transaction_id = ?", id).
+				Where(squirrel.Expr("tag_id in (?)", tagId))
+
+			if _, err := exec(ctx, sqler); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return touchTransaction(ctx, id)
+}
+
+// touchTransaction moves a transaction to its next version when something
+// that belongs to it changed.
+func touchTransaction(ctx context.Context, id int64) error {
+	sqler := squirrel.Update("transactions").
+		Set("updated_at", time.Now()).
+		Set("version", squirrel.Expr("version + 1")).
+		Where("id = ?", id)
+
+	_, err := execAudited(ctx, "transactions", squirrel.Eq{"id": id}, sqler)
+
+	return err
+}
+
+// DeleteTransaction removes a transaction together with its items.
+func (db *Database) DeleteTransaction(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting transaction: %d", id)
+
+	return deleteTransactions(ctx, squirrel.Eq{"id": id})
+}
+
+// DeletePendingTransaction removes a pending transaction and its items.
+func (db *Database) DeletePendingTransaction(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting pending transaction: %d", id)
+
+	return deleteTransactions(ctx, squirrel.Eq{"id": id, "pending": true})
+}
+
+func deleteTransactions(ctx context.Context, where squirrel.Sqlizer) error {
+	ids, err := queryIds(ctx, squirrel.Select("id").From("transactions").Where(where))
+
+	if err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return ErrorNotFound
+	}
+
+	// the interest charged on a loan payment goes with the payment
+	interestIds, err := queryIds(ctx, squirrel.Select("interest_transaction_id").
+		From("loan_payments").
+		Where(squirrel.Eq{"transaction_id": ids}).
+		Where("interest_transaction_id is not null"))
+
+	if err != nil {
+		return err
+	}
+
+	ids = append(ids, interestIds...)
+	matching := squirrel.Eq{"id": ids}
+
+	// the tags and linked rows are audited with the transactions, so that
+	// undoing the deletion brings them back
+	return auditChange(ctx, "transactions", matching, func() error {
+		if err := deleteItems(ctx, squirrel.Eq{"transaction_id": ids}); err != nil {
+			return err
+		}
+
+		for table, columns := range map[string][]string{
+			"duplicate_pairs": {"transaction_id", "duplicate_id"},
+			"loan_payments":   {"transaction_id", "interest_transaction_id"},
+		} {
+			where := squirrel.Or{
+				squirrel.Eq{columns[0]: ids},
+				squirrel.Eq{columns[1]: ids},
+			}
+
+			if _, err := execAudited(ctx, table, where, squirrel.Delete(table).Where(where)); err != nil {
+				return err
+			}
+		}
+
+		// the content of the attachments stays until the orphaned files are
+		// cleaned up
+		for _, table := range []string{"transaction_tags", "attachments", "security_trades"} {
+			where := squirrel.Eq{"transaction_id": ids}
+
+			if _, err := execAudited(ctx, table, where, squirrel.Delete(table).Where(where)); err != nil {
+				return err
+			}
+		}
+
+		_, err := exec(ctx, squirrel.Delete("transactions").Where(matching))
+
+		return err
+	})
+}
+
+func (db *Database) CreateItem(ctx context.Context, item entities.ItemEntity) (int64, error) {
+	logger.Debugf("Creating item: %v", item)
+
+	sqler := squirrel.Insert("items").
+		Columns("name", "price", "transaction_id", "category_id",
+			"created_at", "updated_at").
+		Values(item.Name, item.Price, item.TransactionId, item.CategoryId,
+			item.CreateAt, item.UpdateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, auditCreated(ctx, "items", id)
+}
+
+// SetItemCategory moves an item to another category.
+func (db *Database) SetItemCategory(ctx context.Context, id int64, categoryId *int64) error {
+	logger.Debugf("Setting category of item %d to %v", id, categoryId)
+
+	sqler := squirrel.Update("items").
+		Set("category_id", categoryId).
+		Set("updated_at", time.Now()).
+		Where("id = ?", id)
+
+	_, err := execAudited(ctx, "items", squirrel.Eq{"id": id}, sqler)
+
+	return err
+}
+
+// DeleteItems removes every item of a transaction.
+func (db *Database) DeleteItems(ctx context.Context, transactionId int64) error {
+	return deleteItems(ctx, squirrel.Eq{"transaction_id": transactionId})
+}
+
+// deleteItems removes the items matching where together with their tags.
+func deleteItems(ctx context.Context, where squirrel.Sqlizer) error {
+	return auditChange(ctx, "items", where, func() error {
+		items := squirrel.Select("id").From("items").Where(where)
+
+		if _, err := exec(ctx, squirrel.Delete("item_tags").Where(squirrel.Expr("item_id in (?)", items))); err != nil {
+			return err
+		}
+
+		_, err := exec(ctx, squirrel.Delete("items").Where(where))
+
+		return err
+	})
+}
+
+func (db *Database) GetItems(ctx context.Context, transactionId int64) ([]entities.ItemEntity, error) {
+	sqler := squirrel.Select("id", "name", "price", "transaction_id", "category_id",
+		"created_at", "updated_at").
+		From("items").
+		Where("transaction_id = ?", transactionId).
+		OrderBy("id")
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var items []entities.ItemEntity
+
+	for rows.Next() {
+		var row entities.ItemEntity
+
+		if err := rows.Scan(&row.Id, &row.Name, &row.Price, &row.TransactionId, &row.CategoryId,
+			&row.CreateAt, &row.UpdateAt); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		items = append(items, row)
+	}
+
+	return items, nil
+}
+
+// GetTransactionIds returns the ids of every transaction matching filter, the
+// oldest first.
+func (db *Database) GetTransactionIds(ctx context.Context, filter entities.TransactionFilter) ([]int64, error) {
+	logger.Debugf("Getting ids of transactions: %v", filter)
+
+	return queryIds(ctx, squirrel.Select("t.id").
+		From("transactions t").
+		Where(transactionFilter(filter)).
+		OrderBy("t.date", "t.id"))
+}
+
+func (db *Database) GetTransactionById(ctx context.Context, id int64) (entities.TransactionEntity, error) {
+	logger.Debugf("Getting transaction: %d", id)
+
+	transactions, err := queryTransactions(ctx, selectTransactions().Where("id = ?", id))
+
+	if err != nil {
+		return entities.TransactionEntity{}, err
+	}
+
+	if len(transactions) == 0 {
+		return entities.TransactionEntity{}, ErrorNotFound
+	}
+
+	return transactions[0], nil
+}
+
+// GetTransactions returns a page of transactions matching filter together with
+// the names of their accounts and payee.
+func (db *Database) GetTransactions(ctx context.Context, filter entities.TransactionFilter, offset uint64, limit uint64, order string) ([]entities.TransactionRow, error) {
+	logger.Debugf("Getting transactions: %v", filter)
+
+	sqler := squirrel.Select("t.id", "t.date", "t.from_account_id", "ifnull(f.name, '')",
+		"t.to_account_id", "ifnull(a.name, '')", "t.payee_id", "ifnull(p.name, '')",
+		"t.description", "coalesce(f.currency, a.currency)", "t.total_amount", "t.pending", "t.status").
+		Column(tagsColumn()).
+		From("transactions t").
+		LeftJoin("accounts f on f.id = t.from_account_id").
+		LeftJoin("accounts a on a.id = t.to_account_id").
+		LeftJoin("payees p on p.id = t.payee_id").
+		Where(transactionFilter(filter)).
+		OrderBy(order, "t.id desc").
+		Offset(offset).
+		Limit(limit)
+
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var transactions []entities.TransactionRow
+
+	for rows.Next() {
+		var row entities.TransactionRow
+		var tags string
+
+		if err := rows.Scan(&row.Id, &row.Date, &row.FromAccountId, &row.FromAccountName,
+			&row.ToAccountId, &row.ToAccountName, &row.PayeeId, &row.PayeeName,
+			&row.Description, &row.Amount.Currency, &row.Amount.Value, &row.Pending, &row.Status, &tags); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		row.Tags = splitTags(tags)
+
+		transactions = append(transactions, row)
+	}
+
+	return transactions, nil
+}
+
+// transactionFilter turns a filter into a condition on the transactions
+// aliased as t.
+func transactionFilter(filter entities.TransactionFilter) squirrel.And {
+	where := squirrel.And{}
+
+	if len(filter.Ids) > 0 {
+		where = append(where, squirrel.Eq{"t.id": filter.Ids})
+	}
+
+	if filter.AccountId != 0 {
+		where = append(where, squirrel.Or{
+			squirrel.Eq{"t.from_account_id": filter.AccountId},
+			squirrel.Eq{"t.to_account_id": filter.AccountId},
+		})
+	}
+
+	if filter.PayeeId != 0 {
+		where = append(where, squirrel.Eq{"t.payee_id": filter.PayeeId})
+	}
+
+	if filter.FromDate != "" {
+		where = append(where, squirrel.GtOrEq{"t.date": filter.FromDate})
+	}
+
+	if filter.ToDate != "" {
+		where = append(where, squirrel.LtOrEq{"t.date": filter.ToDate})
+	}
+
+	if filter.Tag != "" {
+		where = append(where, taggedWith(filter.Tag))
+	}
+
+	if len(filter.Statuses) > 0 {
+		where = append(where, squirrel.Eq{"t.status": filter.Statuses})
+	}
+
+	if filter.Query != nil {
+		where = append(where, filter.Query.Predicate(time.Now()))
+	}
+
+	return where
+}
+
+// GetPendingTransactions returns the transactions waiting for confirmation.
+func (db *Database) GetPendingTransactions(ctx context.Context) ([]entities.TransactionEntity, error) {
+	logger.Debugf("Getting pending transactions")
+
+	sqler := selectTransactions().
+		Where("pending = ?", true).
+		OrderBy("date", "id")
+
+	return queryTransactions(ctx, sqler)
+}
+
+// GetExpectedTransactions returns the transactions that still have to change
+// account balances after the day after: confirmed transactions dated later,
+// up to and including until, and every pending transaction up to until.
+func (db *Database) GetExpectedTransactions(ctx context.Context, after string, until string) ([]entities.TransactionEntity, error) {
+	logger.Debugf("Getting expected transactions from %s to %s", after, until)
+
+	sqler := selectTransactions().
+		Where("date <= ?", until).
+		Where(squirrel.Or{
+			squirrel.Eq{"pending": true},
+			squirrel.Expr("date > ?", after),
+		}).
+		OrderBy("date", "id")
+
+	return queryTransactions(ctx, sqler)
+}
+
+// ConfirmTransaction turns a pending transaction into a regular one.
+func (db *Database) ConfirmTransaction(ctx context.Context, id int64) error {
+	logger.Debugf("Confirming transaction: %d", id)
+
+	where := squirrel.Eq{"id": id, "pending": true}
+
+	sqler := squirrel.Update("transactions").
+		Set("pending", false).
+		Set("updated_at", time.Now()).
+		Where(where)
+
+	result, err := execAudited(ctx, "transactions", where, sqler)
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func selectTransactions() squirrel.SelectBuilder {
+	return squirrel.Select("id", "from_account_id", "to_account_id", "total_amount",
+		"date", "payee_id", "description", "notes", "pending", "status", "scheduled_transaction_id",
+		"version", "created_at", "updated_at").
+		From("transactions")
+}
+
+func queryTransactions(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.TransactionEntity, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	var transactions []entities.TransactionEntity
+
+	for rows.Next() {
+		var row entities.TransactionEntity
+
+		if err := rows.Scan(&row.Id, &row.FromAccountId, &row.ToAccountId, &row.TotalAmount,
+			&row.Date, &row.PayeeId, &row.Description, &row.Notes, &row.Pending, &row.Status, &row.ScheduledTransactionId,
+			&row.Version, &row.CreateAt, &row.UpdateAt); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		transactions = append(transactions, row)
+	}
+
+	return transactions, nil
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+// CreateValuation records the value of an account on a day, replacing an
+// earlier valuation of the same day.
+func (db *Database) CreateValuation(ctx context.Context, valuation entities.ValuationEntity) (int64, error) {
+	logger.Debugf("Creating valuation: %v", valuation)
+
+	sqler := squirrel.Insert("account_valuations").
+		Options("or replace").
+		Columns("account_id", "date", "value", "notes", "created_at").
+		Values(valuation.AccountId, valuation.Date, valuation.Value, valuation.Notes, valuation.CreateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// EditValuation saves a valuation read at valuation.Version.
+func (db *Database) EditValuation(ctx context.Context, valuation entities.ValuationEntity) (int64, error) {
+	logger.Debugf("Editing valuation: %v", valuation)
+
+	sqler := squirrel.Update("account_valuations").
+		Set("date", valuation.Date).
+		Set("value", valuation.Value).
+		Set("notes", valuation.Notes).
+		Where("id = ?", valuation.Id)
+
+	result, err := execVersioned(ctx, "account_valuations", valuation.Id, valuation.Version, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (db *Database) DeleteValuation(ctx context.Context, id int64) error {
+	logger.Debugf("Deleting valuation: %d", id)
+
+	result, err := exec(ctx, squirrel.Delete("account_valuations").Where("id = ?", id))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (db *Database) GetValuationById(ctx context.Context, id int64) (entities.ValuationEntity, error) {
+	valuations, err := queryValuations(ctx, selectValuations().Where("id = ?", id))
+
+	if err != nil {
+		return entities.ValuationEntity{}, err
+	}
+
+	if len(valuations) == 0 {
+		return entities.ValuationEntity{}, ErrorNotFound
+	}
+
+	return valuations[0], nil
+}
+
+// GetValuations returns the valuations of an account, or of every account
+// when accountId is 0, the oldest first.
+func (db *Database) GetValuations(ctx context.Context, accountId int64) ([]entities.ValuationEntity, error) {
+	logger.Debugf("Getting valuations of account %d", accountId)
+
+	sqler := selectValuations().OrderBy("account_id", "date")
+
+	if accountId != 0 {
+		sqler = sqler.Where("account_id = ?", accountId)
+	}
+
+	return queryValuations(ctx, sqler)
+}
+
+func selectValuations() squirrel.SelectBuilder {
+	return squirrel.Select("id", "account_id", "date", "value", "notes", "version", "created_at").
+		From("account_valuations")
+}
+
+func queryValuations(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.ValuationEntity, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	valuations := []entities.ValuationEntity{}
+
+	for rows.Next() {
+		var row entities.ValuationEntity
+
+		if err := rows.Scan(&row.Id, &row.AccountId, &row.Date, &row.Value, &row.Notes, &row.Version,
+			&row.CreateAt); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		valuations = append(valuations, row)
+	}
+
+	return valuations, nil
+}
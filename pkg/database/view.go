@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/internal/entities"
+)
+
+func (db *Database) CreateView(ctx context.Context, view entities.ViewEntity) (int64, error) {
+	logger.Debugf("Creating view: %v", view)
+
+	if err := checkViewName(ctx, view); err != nil {
+		return 0, err
+	}
+
+	filters, columns, err := marshalView(view)
+
+	if err != nil {
+		return 0, err
+	}
+
+	sqler := squirrel.Insert("views").
+		Columns("owner", "list", "name", "filters", "query", "order_by", "sort_order", "columns",
+			"created_at", "updated_at").
+		Values(view.Owner, view.List, view.Name, filters, view.Query, view.OrderBy, view.Order, columns,
+			view.CreateAt, view.UpdateAt)
+
+	result, err := exec(ctx, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// EditView saves a view read at view.Version. The owner and the list of a
+// view do not change.
+func (db *Database) EditView(ctx context.Context, view entities.ViewEntity) (int64, error) {
+	logger.Debugf("Editing view: %v", view)
+
+	if err := checkViewName(ctx, view); err != nil {
+		return 0, err
+	}
+
+	filters, columns, err := marshalView(view)
+
+	if err != nil {
+		return 0, err
+	}
+
+	sqler := squirrel.Update("views").
+		Set("name", view.Name).
+		Set("filters", filters).
+		Set("query", view.Query).
+		Set("order_by", view.OrderBy).
+		Set("sort_order", view.Order).
+		Set("columns", columns).
+		Set("updated_at", view.UpdateAt).
+		Where("id = ?", view.Id)
+
+	result, err := execVersioned(ctx, "views", view.Id, view.Version, sqler)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (db *Database) DeleteView(ctx context.Context, owner string, id int64) error {
+	logger.Debugf("Deleting view: %d", id)
+
+	result, err := exec(ctx, squirrel.Delete("views").Where("id = ? and owner = ?", id, owner))
+
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// GetViewById returns a view of owner, the views of other users are not
+// found.
+func (db *Database) GetViewById(ctx context.Context, owner string, id int64) (entities.ViewEntity, error) {
+	logger.Debugf("Getting view: %d", id)
+
+	views, err := queryViews(ctx, selectViews().Where("id = ? and owner = ?", id, owner))
+
+	if err != nil {
+		return entities.ViewEntity{}, err
+	}
+
+	if len(views) == 0 {
+		return entities.ViewEntity{}, ErrorNotFound
+	}
+
+	return views[0], nil
+}
+
+// GetViews returns the views of owner by name, only those of list when it is
+// set.
+func (db *Database) GetViews(ctx context.Context, owner string, list string) ([]entities.ViewEntity, error) {
+	logger.Debugf("Getting views of %s", owner)
+
+	sqler := selectViews().Where("owner = ?", owner).OrderBy("list", "name")
+
+	if list != "" {
+		sqler = sqler.Where("list = ?", list)
+	}
+
+	return queryViews(ctx, sqler)
+}
+
+// checkViewName fails with ErrorViewExists when the owner has another view
+// of the list with the same name.
+func checkViewName(ctx context.Context, view entities.ViewEntity) error {
+	_, err := queryId(ctx, squirrel.Select("id").
+		From("views").
+		Where("owner = ? and list = ? and name = ? and id != ?", view.Owner, view.List, view.Name, view.Id))
+
+	switch err {
+	case nil:
+		return ErrorViewExists
+	case ErrorNotFound:
+		return nil
+	}
+
+	return err
+}
+
+func selectViews() squirrel.SelectBuilder {
+	return squirrel.Select("id", "owner", "list", "name", "filters", "query", "order_by", "sort_order",
+		"columns", "version", "created_at", "updated_at").
+		From("views")
+}
+
+func queryViews(ctx context.Context, sqler squirrel.SelectBuilder) ([]entities.ViewEntity, error) {
+	rows, err := query(ctx, sqler)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+	views := []entities.ViewEntity{}
+
+	for rows.Next() {
+		var row entities.ViewEntity
+		var filters, columns string
+
+		if err := rows.Scan(&row.Id, &row.Owner, &row.List, &row.Name, &filters, &row.Query, &row.OrderBy,
+			&row.Order, &columns, &row.Version, &row.CreateAt, &row.UpdateAt); err != nil {
+			logger.Errorf("Error %v", err)
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(filters), &row.Filters); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(columns), &row.Columns); err != nil {
+			return nil, err
+		}
+
+		views = append(views, row)
+	}
+
+	return views, nil
+}
+
+// marshalView encodes the filters and columns of a view, they are stored as
+// JSON.
+func marshalView(view entities.ViewEntity) (string, string, error) {
+	if view.Filters == nil {
+		view.Filters = map[string]string{}
+	}
+
+	if view.Columns == nil {
+		view.Columns = []string{}
+	}
+
+	filters, err := json.Marshal(view.Filters)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	columns, err := json.Marshal(view.Columns)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(filters), string(columns), nil
+}
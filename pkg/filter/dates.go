@@ -0,0 +1,184 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lembata/para/pkg/recurrence"
+)
+
+// period is the first and last day of a date value, taken from now when it
+// is relative.
+type period func(now time.Time) (from string, to string)
+
+type unit int
+
+const (
+	day unit = iota
+	week
+	month
+	year
+)
+
+var unitNames = map[string]unit{
+	"day": day, "days": day,
+	"week": week, "weeks": week,
+	"month": month, "months": month,
+	"year": year, "years": year,
+}
+
+// period reads a date value:
+//
+//	2024-03-15, 2024-03 or 2024
+//	today, yesterday or tomorrow
+//	this, last or next day, week, month or year
+//	last or next 30 days, weeks, months or years, today included
+//	3 days, weeks, months or years ago
+//
+// Weeks start on Monday.
+func (p *parser) period() (period, error) {
+	token := p.next()
+
+	if token.kind != tokenWord && token.kind != tokenString {
+		return nil, token.unexpected()
+	}
+
+	text := strings.ToLower(token.text)
+
+	switch text {
+	case "today":
+		return calendar(day, 0), nil
+	case "yesterday":
+		return calendar(day, -1), nil
+	case "tomorrow":
+		return calendar(day, 1), nil
+	case "this", "last", "next":
+		offset := map[string]int{"this": 0, "last": -1, "next": 1}[text]
+
+		if count, err := strconv.Atoi(p.peek().text); err == nil && text != "this" && count > 0 {
+			p.next()
+			unit, err := p.unit()
+
+			if err != nil {
+				return nil, err
+			}
+
+			return span(unit, count*offset), nil
+		}
+
+		unit, err := p.unit()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return calendar(unit, offset), nil
+	}
+
+	if count, err := strconv.Atoi(text); err == nil && count >= 0 && p.peek().kind == tokenWord {
+		if unit, ok := unitNames[strings.ToLower(p.peek().text)]; ok {
+			p.next()
+
+			if err := p.expect(tokenWord, "ago"); err != nil {
+				return nil, err
+			}
+
+			return func(now time.Time) (string, string) {
+				date := format(add(today(now), unit, -count))
+				return date, date
+			}, nil
+		}
+	}
+
+	return literal(token.text)
+}
+
+func (p *parser) unit() (unit, error) {
+	token := p.next()
+	unit, ok := unitNames[strings.ToLower(token.text)]
+
+	if token.kind != tokenWord || !ok {
+		return 0, token.unexpected()
+	}
+
+	return unit, nil
+}
+
+// literal reads a day, a month or a year.
+func literal(text string) (period, error) {
+	for _, layout := range []struct {
+		layout string
+		unit   unit
+	}{{recurrence.DateLayout, day}, {"2006-01", month}, {"2006", year}} {
+		if date, err := time.Parse(layout.layout, text); err == nil {
+			from, to := format(date), format(add(date, layout.unit, 1).AddDate(0, 0, -1))
+
+			return func(time.Time) (string, string) {
+				return from, to
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%q is not a date", text)
+}
+
+// calendar is the day, week, month or year offset from the current one.
+func calendar(unit unit, offset int) period {
+	return func(now time.Time) (string, string) {
+		start := today(now)
+
+		switch unit {
+		case week:
+			start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+		case month:
+			start = start.AddDate(0, 0, 1-start.Day())
+		case year:
+			start = start.AddDate(0, 0, 1-start.YearDay())
+		}
+
+		start = add(start, unit, offset)
+
+		return format(start), format(add(start, unit, 1).AddDate(0, 0, -1))
+	}
+}
+
+// span is the count of days, weeks, months or years up to today, or from
+// today when count is positive.
+func span(unit unit, count int) period {
+	return func(now time.Time) (string, string) {
+		start := today(now)
+
+		if count < 0 {
+			return format(add(start, unit, count).AddDate(0, 0, 1)), format(start)
+		}
+
+		return format(start), format(add(start, unit, count).AddDate(0, 0, -1))
+	}
+}
+
+func today(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// add moves a date by count units. Moving by months or years keeps the day
+// within the month, a month before March 31 is the last day of February.
+func add(date time.Time, unit unit, count int) time.Time {
+	switch unit {
+	case day:
+		return date.AddDate(0, 0, count)
+	case week:
+		return date.AddDate(0, 0, 7*count)
+	case year:
+		count *= 12
+	}
+
+	first := time.Date(date.Year(), date.Month()+time.Month(count), 1, 0, 0, 0, 0, date.Location())
+
+	return first.AddDate(0, 0, min(date.Day(), first.AddDate(0, 1, -1).Day())-1)
+}
+
+func format(date time.Time) string {
+	return date.Format(recurrence.DateLayout)
+}
@@ -0,0 +1,560 @@
+// Package filter parses filter expressions like
+//
+//	amount > 100 and category in (Food, Travel) and date in last month
+//
+// into SQL predicates. Comparisons are combined with and, or, not and
+// parentheses. A field is compared with =, !=, <, <=, >, >=, ~ (contains),
+// !~, in (a list or, for dates, a period) and between. Values are bare
+// words, numbers or quoted text.
+package filter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lembata/para/pkg/currency"
+)
+
+type Kind int
+
+const (
+	Text Kind = iota
+	Number
+	// Money is a number of currency units stored as coins.
+	Money
+	Date
+	// Enum is a set of names, the field parses them with Value.
+	Enum
+)
+
+// Field is a name a filter can use. Column is the SQL expression compared.
+// When Within is set, Column is compared in the rows of those subqueries,
+// given as "table ... where ...", and the field matches when one of the rows
+// does.
+type Field struct {
+	Column string
+	Kind   Kind
+	Within []string
+	Value  func(name string) (interface{}, error)
+}
+
+type Fields map[string]Field
+
+// Expr is a parsed filter.
+type Expr interface {
+	// Predicate is the condition of the filter, relative dates are taken
+	// from now.
+	Predicate(now time.Time) squirrel.Sqlizer
+}
+
+// Parse reads a filter on fields. The field names are not case sensitive.
+func Parse(text string, fields Fields) (Expr, error) {
+	tokens, err := lex(text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, fields: fields}
+
+	if p.peek().kind == tokenEnd {
+		return nil, fmt.Errorf("filter is empty")
+	}
+
+	expr, err := p.or()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if token := p.peek(); token.kind != tokenEnd {
+		return nil, token.unexpected()
+	}
+
+	return expr, nil
+}
+
+// And is a filter matching when all of exprs do.
+func And(exprs ...Expr) Expr {
+	return and(exprs)
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenWord
+	tokenString
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) is(kind tokenKind, text string) bool {
+	return t.kind == kind && strings.EqualFold(t.text, text)
+}
+
+func (t token) unexpected() error {
+	if t.kind == tokenEnd {
+		return fmt.Errorf("unexpected end of filter")
+	}
+
+	return fmt.Errorf("unexpected %q at %d", t.text, t.pos+1)
+}
+
+const symbols = "=!<>~(),"
+
+// lex splits text into words, quoted text and symbols. A word runs until a
+// space, a quote or a symbol, so dates and negative numbers are words.
+func lex(text string) ([]token, error) {
+	var tokens []token
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var value strings.Builder
+
+			for i++; ; i++ {
+				if i == len(runes) {
+					return nil, fmt.Errorf("unterminated text at %d", start+1)
+				}
+
+				// a doubled quote stands for the quote itself
+				if runes[i] == r {
+					if i+1 < len(runes) && runes[i+1] == r {
+						i++
+					} else {
+						break
+					}
+				}
+
+				value.WriteRune(runes[i])
+			}
+
+			i++
+			tokens = append(tokens, token{tokenString, value.String(), start})
+		case strings.ContainsRune(symbols, r):
+			start := i
+			i++
+
+			if i < len(runes) && (runes[i] == '=' || r == '<' && runes[i] == '>' || r == '!' && runes[i] == '~') {
+				i++
+			}
+
+			tokens = append(tokens, token{tokenSymbol, string(runes[start:i]), start})
+		default:
+			start := i
+
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' && runes[i] != '\'' &&
+				!strings.ContainsRune(symbols, runes[i]) {
+				i++
+			}
+
+			tokens = append(tokens, token{tokenWord, string(runes[start:i]), start})
+		}
+	}
+
+	return append(tokens, token{kind: tokenEnd, pos: len(runes)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	fields Fields
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	token := p.tokens[p.pos]
+
+	if token.kind != tokenEnd {
+		p.pos++
+	}
+
+	return token
+}
+
+// accept skips the next token when it is the keyword or symbol text.
+func (p *parser) accept(kind tokenKind, text string) bool {
+	if p.peek().is(kind, text) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	if !p.accept(kind, text) {
+		return p.peek().unexpected()
+	}
+
+	return nil
+}
+
+func (p *parser) or() (Expr, error) {
+	return p.list("or", p.and, func(exprs []Expr) Expr { return or(exprs) })
+}
+
+func (p *parser) and() (Expr, error) {
+	return p.list("and", p.not, func(exprs []Expr) Expr { return and(exprs) })
+}
+
+// list reads operands separated by the keyword.
+func (p *parser) list(keyword string, operand func() (Expr, error), join func([]Expr) Expr) (Expr, error) {
+	var exprs []Expr
+
+	for {
+		expr, err := operand()
+
+		if err != nil {
+			return nil, err
+		}
+
+		exprs = append(exprs, expr)
+
+		if !p.accept(tokenWord, keyword) {
+			break
+		}
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+
+	return join(exprs), nil
+}
+
+func (p *parser) not() (Expr, error) {
+	if p.accept(tokenWord, "not") {
+		expr, err := p.not()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return not{expr}, nil
+	}
+
+	if p.accept(tokenSymbol, "(") {
+		expr, err := p.or()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return expr, p.expect(tokenSymbol, ")")
+	}
+
+	return p.comparison()
+}
+
+// comparison reads a field followed by an operator and its values. The
+// negated operators are read as the negation of the positive ones.
+func (p *parser) comparison() (Expr, error) {
+	name := p.next()
+
+	if name.kind != tokenWord {
+		return nil, name.unexpected()
+	}
+
+	field, ok := p.fields[strings.ToLower(name.text)]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", name.text)
+	}
+
+	c := comparison{field: field}
+	negated := p.accept(tokenWord, "not")
+	operator := p.next()
+
+	switch {
+	case operator.is(tokenWord, "in"):
+		c.operator = "in"
+
+		if err := p.values(&c); err != nil {
+			return nil, err
+		}
+	case operator.is(tokenWord, "between"):
+		c.operator = "between"
+
+		if err := p.value(&c); err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(tokenWord, "and"); err != nil {
+			return nil, err
+		}
+
+		if err := p.value(&c); err != nil {
+			return nil, err
+		}
+	case operator.kind == tokenSymbol && !negated:
+		switch operator.text {
+		case "=", "<", "<=", ">", ">=", "~":
+			c.operator = operator.text
+		case "!=", "<>":
+			c.operator, negated = "=", true
+		case "!~":
+			c.operator, negated = "~", true
+		default:
+			return nil, operator.unexpected()
+		}
+
+		if err := p.value(&c); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, operator.unexpected()
+	}
+
+	if err := c.check(name.text); err != nil {
+		return nil, err
+	}
+
+	if negated {
+		return not{c}, nil
+	}
+
+	return c, nil
+}
+
+// values reads the list of an in, or a period for a date.
+func (p *parser) values(c *comparison) error {
+	if c.field.Kind == Date && !p.peek().is(tokenSymbol, "(") {
+		return p.value(c)
+	}
+
+	if err := p.expect(tokenSymbol, "("); err != nil {
+		return err
+	}
+
+	for {
+		if err := p.value(c); err != nil {
+			return err
+		}
+
+		if !p.accept(tokenSymbol, ",") {
+			break
+		}
+	}
+
+	return p.expect(tokenSymbol, ")")
+}
+
+func (p *parser) value(c *comparison) error {
+	if c.field.Kind == Date {
+		period, err := p.period()
+
+		if err != nil {
+			return err
+		}
+
+		c.periods = append(c.periods, period)
+		return nil
+	}
+
+	token := p.next()
+
+	if token.kind != tokenWord && token.kind != tokenString {
+		return token.unexpected()
+	}
+
+	var value interface{} = token.text
+	var err error
+
+	switch c.field.Kind {
+	case Number, Money:
+		var number float64
+
+		// ParseFloat takes NaN and Inf, they can not be compared
+		number, err = strconv.ParseFloat(token.text, 64)
+
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return fmt.Errorf("%q is not a number", token.text)
+		}
+
+		value = number
+
+		if c.field.Kind == Money {
+			if math.Abs(number) >= currency.FromCoins(math.MaxInt64) {
+				return fmt.Errorf("%q is out of range", token.text)
+			}
+
+			value = currency.ToCoins(number)
+		}
+	case Enum:
+		if value, err = c.field.Value(strings.ToLower(token.text)); err != nil {
+			return err
+		}
+	}
+
+	c.values = append(c.values, value)
+
+	return nil
+}
+
+// check tells whether the operator can compare the field.
+func (c comparison) check(name string) error {
+	switch {
+	case c.field.Kind == Text && strings.ContainsAny(c.operator, "<>"):
+		return fmt.Errorf("%s is text and can not be compared with %s", name, c.operator)
+	case c.field.Kind != Text && c.operator == "~":
+		return fmt.Errorf("%s is not text and can not be searched with ~", name)
+	case c.field.Kind == Enum && c.operator != "=" && c.operator != "in":
+		return fmt.Errorf("%s can only be compared with = and in", name)
+	}
+
+	return nil
+}
+
+type and []Expr
+
+func (a and) Predicate(now time.Time) squirrel.Sqlizer {
+	predicate := squirrel.And{}
+
+	for _, expr := range a {
+		predicate = append(predicate, expr.Predicate(now))
+	}
+
+	return predicate
+}
+
+type or []Expr
+
+func (o or) Predicate(now time.Time) squirrel.Sqlizer {
+	predicate := squirrel.Or{}
+
+	for _, expr := range o {
+		predicate = append(predicate, expr.Predicate(now))
+	}
+
+	return predicate
+}
+
+type not struct {
+	Expr
+}
+
+func (n not) Predicate(now time.Time) squirrel.Sqlizer {
+	return negation{n.Expr.Predicate(now)}
+}
+
+type negation struct {
+	squirrel.Sqlizer
+}
+
+func (n negation) ToSql() (string, []interface{}, error) {
+	sql, args, err := n.Sqlizer.ToSql()
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	return "not (" + sql + ")", args, nil
+}
+
+// comparison compares a field with values, or with periods for a date.
+type comparison struct {
+	field    Field
+	operator string
+	values   []interface{}
+	periods  []period
+}
+
+func (c comparison) Predicate(now time.Time) squirrel.Sqlizer {
+	sql, args := c.condition(now)
+
+	if len(c.field.Within) == 0 {
+		return squirrel.Expr(sql, args...)
+	}
+
+	predicate := squirrel.Or{}
+
+	for _, within := range c.field.Within {
+		predicate = append(predicate, squirrel.Expr("exists (select 1 from "+within+" and "+sql+")", args...))
+	}
+
+	return predicate
+}
+
+// condition is the comparison of the column, text is compared ignoring case.
+func (c comparison) condition(now time.Time) (string, []interface{}) {
+	column := c.field.Column
+	collate := ""
+
+	if c.field.Kind == Text {
+		collate = " collate nocase"
+	}
+
+	if c.field.Kind == Date {
+		return c.dateCondition(now)
+	}
+
+	switch c.operator {
+	case "in":
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(c.values)), ", ")
+		return column + collate + " in (" + placeholders + ")", c.values
+	case "between":
+		return column + " between ? and ?", c.values
+	case "~":
+		return column + ` like ? escape '\'`, []interface{}{"%" + escapeLike(c.values[0].(string)) + "%"}
+	}
+
+	return column + " " + c.operator + " ?" + collate, c.values
+}
+
+// dateCondition compares a date column with periods, a single date is a
+// period of one day. A date is before a period when it is before its first
+// day and after it when it is after its last.
+func (c comparison) dateCondition(now time.Time) (string, []interface{}) {
+	column := c.field.Column
+	from, to := c.periods[0](now)
+
+	switch c.operator {
+	case "<":
+		return column + " < ?", []interface{}{from}
+	case "<=":
+		return column + " <= ?", []interface{}{to}
+	case ">":
+		return column + " > ?", []interface{}{to}
+	case ">=":
+		return column + " >= ?", []interface{}{from}
+	case "between":
+		_, to = c.periods[1](now)
+		return column + " between ? and ?", []interface{}{from, to}
+	}
+
+	var conditions []string
+	var args []interface{}
+
+	for _, period := range c.periods {
+		from, to := period(now)
+		conditions = append(conditions, column+" between ? and ?")
+		args = append(args, from, to)
+	}
+
+	return "(" + strings.Join(conditions, " or ") + ")", args
+}
+
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+}
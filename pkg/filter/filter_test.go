@@ -0,0 +1,151 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testFields = Fields{
+	"description": {Column: "description", Kind: Text},
+	"amount":      {Column: "amount", Kind: Money},
+	"count":       {Column: "count", Kind: Number},
+	"date":        {Column: "date", Kind: Date},
+	"status": {Column: "status", Kind: Enum, Value: func(name string) (interface{}, error) {
+		if name == "cleared" {
+			return 1, nil
+		}
+
+		return nil, fmt.Errorf("unknown status %q", name)
+	}},
+	"category": {Column: "c.name", Kind: Text, Within: []string{"categories c where c.id = category_id"}},
+}
+
+// testNow is a Wednesday.
+var testNow = time.Date(2026, 3, 18, 15, 0, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		sql  string
+		args []interface{}
+	}{
+		{"amount > 100", "amount > ?", []interface{}{1000000}},
+		{"Amount >= -12.5", "amount >= ?", []interface{}{-125000}},
+		{"count = 3", "count = ?", []interface{}{3.0}},
+		{"amount between 1 and 2", "amount between ? and ?", []interface{}{10000, 20000}},
+		{"description != Rent", "not (description = ? collate nocase)", []interface{}{"Rent"}},
+		{"description = 'it''s'", "description = ? collate nocase", []interface{}{"it's"}},
+		{"description ~ '50%_off'", `description like ? escape '\'`, []interface{}{`%50\%\_off%`}},
+		{`description not in (a, "b c")`, "not (description collate nocase in (?, ?))", []interface{}{"a", "b c"}},
+		{"not (count < 1 or count > 5) and status = Cleared", "(not ((count < ? OR count > ?)) AND status = ?)",
+			[]interface{}{1.0, 5.0, 1}},
+		{"category = food",
+			"(exists (select 1 from categories c where c.id = category_id and c.name = ? collate nocase))",
+			[]interface{}{"food"}},
+		{"date < this month", "date < ?", []interface{}{"2026-03-01"}},
+		{"date <= this month", "date <= ?", []interface{}{"2026-03-31"}},
+		{"date > last week", "date > ?", []interface{}{"2026-03-15"}},
+		{"date >= 2026", "date >= ?", []interface{}{"2026-01-01"}},
+		{"date between 2026-01 and 2026-02", "date between ? and ?", []interface{}{"2026-01-01", "2026-02-28"}},
+		{"date in (2026-01-01, today)", "(date between ? and ? or date between ? and ?)",
+			[]interface{}{"2026-01-01", "2026-01-01", "2026-03-18", "2026-03-18"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			expr, err := Parse(test.text, testFields)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sql, args, err := expr.Predicate(testNow).ToSql()
+
+			if err != nil || sql != test.sql || !reflect.DeepEqual(args, test.args) {
+				t.Errorf("got %s %v, %v, want %s %v", sql, args, err, test.sql, test.args)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", "filter is empty"},
+		{"amount", "unexpected end of filter"},
+		{"amount >", "unexpected end of filter"},
+		{"(count = 1", "unexpected end of filter"},
+		{"count = 1 count", `unexpected "count" at 11`},
+		{"description = 'abc", "unterminated text at 15"},
+		{"foo = 1", `unknown field "foo"`},
+		{"amount > abc", `"abc" is not a number`},
+		{"amount > NaN", `"NaN" is not a number`},
+		{"amount > inf", `"inf" is not a number`},
+		{"count < -Infinity", `"-Infinity" is not a number`},
+		{"count > 1e400", `"1e400" is not a number`},
+		{"amount > 1e300", `"1e300" is out of range`},
+		{"description < a", "description is text and can not be compared with <"},
+		{"amount !~ 1", "amount is not text and can not be searched with ~"},
+		{"status > cleared", "status can only be compared with = and in"},
+		{"status = x", `unknown status "x"`},
+		{"date = 2026-13-01", `"2026-13-01" is not a date`},
+		{"date = 3 weeks", "unexpected end of filter"},
+		{"date in this 3 days", `unexpected "3" at 14`},
+		{"date in last week days", `unexpected "days" at 19`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			if _, err := Parse(test.text, testFields); err == nil || err.Error() != test.want {
+				t.Errorf("got %v, want %s", err, test.want)
+			}
+		})
+	}
+}
+
+func TestPeriods(t *testing.T) {
+	endOfMonth := time.Date(2026, 3, 31, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		text string
+		now  time.Time
+		from string
+		to   string
+	}{
+		{"2026-02-03", testNow, "2026-02-03", "2026-02-03"},
+		{"2024-02", testNow, "2024-02-01", "2024-02-29"},
+		{"2025", testNow, "2025-01-01", "2025-12-31"},
+		{"today", testNow, "2026-03-18", "2026-03-18"},
+		{"yesterday", testNow, "2026-03-17", "2026-03-17"},
+		{"tomorrow", testNow, "2026-03-19", "2026-03-19"},
+		{"this week", testNow, "2026-03-16", "2026-03-22"},
+		{"last month", testNow, "2026-02-01", "2026-02-28"},
+		{"next year", testNow, "2027-01-01", "2027-12-31"},
+		{"last 30 days", testNow, "2026-02-17", "2026-03-18"},
+		{"last 1 month", testNow, "2026-02-19", "2026-03-18"},
+		{"next 2 weeks", testNow, "2026-03-18", "2026-03-31"},
+		{"3 days ago", testNow, "2026-03-15", "2026-03-15"},
+		{"1 month ago", endOfMonth, "2026-02-28", "2026-02-28"},
+		{"last month", endOfMonth, "2026-02-01", "2026-02-28"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			expr, err := Parse("date in "+test.text, testFields)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, args, err := expr.Predicate(test.now).ToSql()
+
+			if want := []interface{}{test.from, test.to}; err != nil || !reflect.DeepEqual(args, want) {
+				t.Errorf("got %v, %v, want %v", args, err, want)
+			}
+		})
+	}
+}
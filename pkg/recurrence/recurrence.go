@@ -0,0 +1,204 @@
+package recurrence
+
+import (
+	"errors"
+	"time"
+)
+
+const DateLayout = "2006-01-02"
+
+type Frequency int
+
+const (
+	Daily Frequency = iota
+	Weekly
+	Monthly
+	Yearly
+)
+
+var (
+	ErrorInvalidFrequency = errors.New("invalid frequency")
+	ErrorInvalidInterval  = errors.New("interval must be at least 1")
+	ErrorInvalidWeekday   = errors.New("invalid weekday")
+	ErrorInvalidDay       = errors.New("invalid day of month")
+	ErrorCountReached     = errors.New("count must be above the occurrences that already happened")
+)
+
+var frequencyNames = []string{"daily", "weekly", "monthly", "yearly"}
+
+func ParseFrequency(name string) (Frequency, error) {
+	for i, frequencyName := range frequencyNames {
+		if frequencyName == name {
+			return Frequency(i), nil
+		}
+	}
+
+	return 0, ErrorInvalidFrequency
+}
+
+func (f Frequency) String() string {
+	if f < Daily || f > Yearly {
+		return ""
+	}
+
+	return frequencyNames[f]
+}
+
+// Rule describes when a scheduled transaction repeats. Occurrences start at
+// Start and repeat every Interval periods until End or until Count occurrences
+// happened, whichever comes first. A zero End or Count means no limit.
+type Rule struct {
+	Frequency Frequency
+	Interval  int
+	// Weekday pins weekly occurrences to a day of the week, -1 keeps the
+	// weekday of Start.
+	Weekday time.Weekday
+	// DayOfMonth pins monthly and yearly occurrences to a day of the month,
+	// 0 keeps the day of Start. Days past the end of a month fall on its
+	// last day.
+	DayOfMonth int
+	Start      time.Time
+	End        time.Time
+	Count      int
+}
+
+func (r Rule) Validate() error {
+	if r.Frequency < Daily || r.Frequency > Yearly {
+		return ErrorInvalidFrequency
+	}
+
+	if r.Interval < 1 {
+		return ErrorInvalidInterval
+	}
+
+	if r.Weekday < -1 || r.Weekday > time.Saturday {
+		return ErrorInvalidWeekday
+	}
+
+	if r.DayOfMonth < 0 || r.DayOfMonth > 31 {
+		return ErrorInvalidDay
+	}
+
+	return nil
+}
+
+// Occurrence returns the date of the n-th occurrence, counting from zero.
+// The second return value is false once the rule has ended.
+func (r Rule) Occurrence(n int) (time.Time, bool) {
+	if n < 0 || (r.Count > 0 && n >= r.Count) {
+		return time.Time{}, false
+	}
+
+	start := truncate(r.Start)
+
+	// the first period can fall before the start date when the rule is pinned
+	// to a day of the month, in which case it is skipped
+	if r.period(start, 0).Before(start) {
+		n++
+	}
+
+	date := r.period(start, n)
+
+	if !r.End.IsZero() && date.After(truncate(r.End)) {
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+// Between returns the occurrences falling between from and to, both inclusive,
+// together with their indexes.
+func (r Rule) Between(from time.Time, to time.Time) ([]int, []time.Time) {
+	from = truncate(from)
+	to = truncate(to)
+
+	var indexes []int
+	var dates []time.Time
+
+	for n := 0; ; n++ {
+		date, ok := r.Occurrence(n)
+
+		if !ok || date.After(to) {
+			break
+		}
+
+		if !date.Before(from) {
+			indexes = append(indexes, n)
+			dates = append(dates, date)
+		}
+	}
+
+	return indexes, dates
+}
+
+// SameDates tells whether r falls on the same dates as other, leaving aside
+// when they end.
+func (r Rule) SameDates(other Rule) bool {
+	return r.Frequency == other.Frequency && r.Interval == other.Interval && r.Weekday == other.Weekday &&
+		r.DayOfMonth == other.DayOfMonth && truncate(r.Start).Equal(truncate(other.Start))
+}
+
+// Continue moves r to pick up where previous left off after its first done
+// occurrences: r starts on the date previous was due next, or the day after
+// its last occurrence when it ended, unless r starts later. The occurrences
+// of r are counted from the new start, Count leaves out the done ones.
+func (r Rule) Continue(previous Rule, done int) (Rule, error) {
+	if done <= 0 {
+		return r, nil
+	}
+
+	if r.Count > 0 {
+		if r.Count <= done {
+			return r, ErrorCountReached
+		}
+
+		r.Count -= done
+	}
+
+	next, ok := previous.Occurrence(done)
+
+	if !ok {
+		last, _ := previous.Occurrence(done - 1)
+		next = last.AddDate(0, 0, 1)
+	}
+
+	if truncate(r.Start).Before(next) {
+		r.Start = next
+	}
+
+	return r, nil
+}
+
+func (r Rule) period(start time.Time, n int) time.Time {
+	switch r.Frequency {
+	case Daily:
+		return start.AddDate(0, 0, n*r.Interval)
+	case Weekly:
+		if r.Weekday >= 0 {
+			start = start.AddDate(0, 0, (int(r.Weekday)-int(start.Weekday())+7)%7)
+		}
+		return start.AddDate(0, 0, 7*n*r.Interval)
+	case Monthly:
+		return r.dayOfMonth(start.Year(), start.Month()+time.Month(n*r.Interval), start)
+	default:
+		return r.dayOfMonth(start.Year()+n*r.Interval, start.Month(), start)
+	}
+}
+
+func (r Rule) dayOfMonth(year int, month time.Month, start time.Time) time.Time {
+	day := r.DayOfMonth
+
+	if day == 0 {
+		day = start.Day()
+	}
+
+	// normalise the month first so that the day can be clamped to its length
+	first := time.Date(year, month, 1, 0, 0, 0, 0, start.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+
+	return first.AddDate(0, 0, min(day, lastDay)-1)
+}
+
+func truncate(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
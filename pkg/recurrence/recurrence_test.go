@@ -0,0 +1,244 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func date(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+
+	parsed, err := time.Parse(DateLayout, value)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return parsed
+}
+
+func TestParseFrequency(t *testing.T) {
+	for _, name := range frequencyNames {
+		frequency, err := ParseFrequency(name)
+
+		if err != nil || frequency.String() != name {
+			t.Errorf("ParseFrequency(%q) = %v, %v", name, frequency, err)
+		}
+	}
+
+	if _, err := ParseFrequency("hourly"); err != ErrorInvalidFrequency {
+		t.Errorf("ParseFrequency(%q) = %v, want %v", "hourly", err, ErrorInvalidFrequency)
+	}
+
+	if name := Frequency(7).String(); name != "" {
+		t.Errorf("Frequency(7).String() = %q, want empty", name)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Rule{Frequency: Monthly, Interval: 1, Weekday: -1, Start: date("2026-01-01")}
+
+	tests := []struct {
+		name   string
+		change func(r *Rule)
+		want   error
+	}{
+		{"valid", func(r *Rule) {}, nil},
+		{"frequency", func(r *Rule) { r.Frequency = Yearly + 1 }, ErrorInvalidFrequency},
+		{"interval", func(r *Rule) { r.Interval = 0 }, ErrorInvalidInterval},
+		{"weekday", func(r *Rule) { r.Weekday = time.Saturday + 1 }, ErrorInvalidWeekday},
+		{"weekday before -1", func(r *Rule) { r.Weekday = -2 }, ErrorInvalidWeekday},
+		{"day of month", func(r *Rule) { r.DayOfMonth = 32 }, ErrorInvalidDay},
+		{"last day of month", func(r *Rule) { r.DayOfMonth = 31 }, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule := valid
+			test.change(&rule)
+
+			if err := rule.Validate(); err != test.want {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestOccurrence(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		n    int
+		want string
+	}{
+		{"daily", Rule{Frequency: Daily, Interval: 1, Start: date("2026-01-01")}, 0, "2026-01-01"},
+		{"daily interval", Rule{Frequency: Daily, Interval: 3, Start: date("2026-01-30")}, 2, "2026-02-05"},
+		{"weekly", Rule{Frequency: Weekly, Interval: 2, Weekday: -1, Start: date("2026-01-01")}, 1, "2026-01-15"},
+		{"weekly on a later weekday", Rule{Frequency: Weekly, Interval: 1, Weekday: time.Monday,
+			Start: date("2026-10-14")}, 0, "2026-10-19"},
+		{"weekly on the start weekday", Rule{Frequency: Weekly, Interval: 1, Weekday: time.Monday,
+			Start: date("2026-10-12")}, 1, "2026-10-19"},
+		{"monthly", Rule{Frequency: Monthly, Interval: 1, Start: date("2026-01-15")}, 13, "2027-02-15"},
+		{"monthly past the end of February", Rule{Frequency: Monthly, Interval: 1, Start: date("2026-01-31")},
+			1, "2026-02-28"},
+		{"monthly back on the 31st", Rule{Frequency: Monthly, Interval: 1, Start: date("2026-01-31")},
+			2, "2026-03-31"},
+		{"monthly pinned after the start", Rule{Frequency: Monthly, Interval: 1, DayOfMonth: 20,
+			Start: date("2026-01-10")}, 0, "2026-01-20"},
+		{"monthly pinned before the start", Rule{Frequency: Monthly, Interval: 1, DayOfMonth: 5,
+			Start: date("2026-01-10")}, 0, "2026-02-05"},
+		{"quarterly", Rule{Frequency: Monthly, Interval: 3, Start: date("2026-11-30")}, 1, "2027-02-28"},
+		{"yearly", Rule{Frequency: Yearly, Interval: 1, Start: date("2026-03-01")}, 2, "2028-03-01"},
+		{"yearly from a leap day", Rule{Frequency: Yearly, Interval: 1, Start: date("2024-02-29")},
+			1, "2025-02-28"},
+		{"yearly on the next leap day", Rule{Frequency: Yearly, Interval: 1, Start: date("2024-02-29")},
+			4, "2028-02-29"},
+		{"time of day is ignored", Rule{Frequency: Daily, Interval: 1,
+			Start: date("2026-01-01").Add(15 * time.Hour)}, 1, "2026-01-02"},
+		{"within count", Rule{Frequency: Daily, Interval: 1, Count: 2, Start: date("2026-01-01")}, 1, "2026-01-02"},
+		{"past count", Rule{Frequency: Daily, Interval: 1, Count: 2, Start: date("2026-01-01")}, 2, ""},
+		{"on the end date", Rule{Frequency: Daily, Interval: 1, Start: date("2026-01-01"),
+			End: date("2026-01-03")}, 2, "2026-01-03"},
+		{"past the end date", Rule{Frequency: Daily, Interval: 1, Start: date("2026-01-01"),
+			End: date("2026-01-03")}, 3, ""},
+		{"negative", Rule{Frequency: Daily, Interval: 1, Start: date("2026-01-01")}, -1, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := test.rule.Occurrence(test.n)
+
+			if test.want == "" {
+				if ok {
+					t.Errorf("got %s, want no occurrence", got.Format(DateLayout))
+				}
+
+				return
+			}
+
+			if !ok || got.Format(DateLayout) != test.want {
+				t.Errorf("got %s, %t, want %s", got.Format(DateLayout), ok, test.want)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	rule := Rule{Frequency: Weekly, Interval: 1, Weekday: -1, Start: date("2026-01-01"), Count: 6}
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		indexes []int
+		dates   []string
+	}{
+		{"inclusive", "2026-01-08", "2026-01-22", []int{1, 2, 3}, []string{"2026-01-08", "2026-01-15", "2026-01-22"}},
+		{"between occurrences", "2026-01-09", "2026-01-14", nil, nil},
+		{"before the start", "2025-12-01", "2026-01-01", []int{0}, []string{"2026-01-01"}},
+		{"up to the count", "2026-01-29", "2026-12-31", []int{4, 5}, []string{"2026-01-29", "2026-02-05"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			indexes, dates := rule.Between(date(test.from), date(test.to))
+
+			if len(indexes) != len(test.indexes) || len(dates) != len(test.dates) {
+				t.Fatalf("got %v %v, want %v %v", indexes, dates, test.indexes, test.dates)
+			}
+
+			for i := range indexes {
+				if indexes[i] != test.indexes[i] || dates[i].Format(DateLayout) != test.dates[i] {
+					t.Errorf("occurrence %d is %d on %s, want %d on %s", i, indexes[i],
+						dates[i].Format(DateLayout), test.indexes[i], test.dates[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSameDates(t *testing.T) {
+	rule := Rule{Frequency: Monthly, Interval: 1, Weekday: -1, Start: date("2026-01-15")}
+
+	tests := []struct {
+		name   string
+		change func(r *Rule)
+		want   bool
+	}{
+		{"unchanged", func(r *Rule) {}, true},
+		{"end", func(r *Rule) { r.End = date("2026-12-31") }, true},
+		{"count", func(r *Rule) { r.Count = 3 }, true},
+		{"time of day", func(r *Rule) { r.Start = r.Start.Add(time.Hour) }, true},
+		{"frequency", func(r *Rule) { r.Frequency = Daily }, false},
+		{"interval", func(r *Rule) { r.Interval = 2 }, false},
+		{"weekday", func(r *Rule) { r.Weekday = time.Monday }, false},
+		{"day of month", func(r *Rule) { r.DayOfMonth = 1 }, false},
+		{"start", func(r *Rule) { r.Start = date("2026-02-15") }, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			other := rule
+			test.change(&other)
+
+			if got := other.SameDates(rule); got != test.want {
+				t.Errorf("got %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestContinue(t *testing.T) {
+	monthly := Rule{Frequency: Monthly, Interval: 1, Weekday: -1, Start: date("2025-01-15")}
+	daily := Rule{Frequency: Daily, Interval: 1, Weekday: -1, Start: date("2025-01-15")}
+
+	tests := []struct {
+		name     string
+		rule     Rule
+		previous Rule
+		done     int
+		start    string
+		count    int
+		err      error
+	}{
+		{"nothing done", daily, monthly, 0, "2025-01-15", 0, nil},
+		{"starts on the next due date", daily, monthly, 20, "2026-09-15", 0, nil},
+		{"a later start is kept", Rule{Frequency: Daily, Interval: 1, Start: date("2027-01-01")},
+			monthly, 20, "2027-01-01", 0, nil},
+		{"count leaves out the done occurrences", Rule{Frequency: Daily, Interval: 1, Count: 24,
+			Start: date("2025-01-15")}, monthly, 20, "2026-09-15", 4, nil},
+		{"count reached", Rule{Frequency: Daily, Interval: 1, Count: 20, Start: date("2025-01-15")},
+			monthly, 20, "", 0, ErrorCountReached},
+		{"after an ended rule", daily, Rule{Frequency: Weekly, Interval: 1, Weekday: -1, Count: 3,
+			Start: date("2026-01-01")}, 3, "2026-01-16", 0, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.rule.Continue(test.previous, test.done)
+
+			if err != test.err {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got.Start.Format(DateLayout) != test.start || got.Count != test.count {
+				t.Errorf("got start %s and count %d, want %s and %d", got.Start.Format(DateLayout), got.Count,
+					test.start, test.count)
+			}
+
+			// nothing falls before the next due date of the previous rule
+			if first, ok := got.Occurrence(0); ok && test.done > 0 {
+				if last, ok := test.previous.Occurrence(test.done - 1); ok && !first.After(last) {
+					t.Errorf("first occurrence %s is not after the last done one %s",
+						first.Format(DateLayout), last.Format(DateLayout))
+				}
+			}
+		})
+	}
+}
@@ -14,12 +14,12 @@ const API = {
             console.log("Accounts.edit", data);
             return await requester._post(`api/accounts/edit`, data)
         },
-        all: async (id) => {
-            console.log("Accounts.get", id);
+        all: async (limit, offset) => {
+            console.log("Accounts.all", limit, offset);
             return await requester._post(`api/accounts/all`,
                 {
-                    offset: 0,
-                    limit: 10,
+                    offset: offset,
+                    limit: limit,
                     order: 'asc',
                     orderBy: 'id'
                 })